@@ -12,6 +12,7 @@ import (
 
 	"backend-go/internal/config"
 	"backend-go/internal/handlers"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
@@ -38,20 +39,48 @@ func main() {
 
 	// Initialize storage
 	store := storage.NewJSONStore(dataPath)
+
+	sessionSecret, err := store.GetSessionSecret()
+	if err != nil {
+		log.Fatal("Failed to load session secret:", err)
+	}
+	handlers.SetSessionSecret(sessionSecret)
+
+	// Record the scheduled price list on aggregator washes saved without one
+	if err := handlers.StoreWashPriceLists(store); err != nil {
+		log.Printf("Failed to store wash price lists: %v", err)
+	}
 	cache := storage.NewCache()
+	broker := services.NewEventBroker()
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(store, cache)
 	employeeHandler := handlers.NewEmployeeHandler(store, cache)
+	loanHandler := handlers.NewLoanHandler(store, cache)
 	counterAgentHandler := handlers.NewCounterAgentHandler(store, cache)
 	aggregatorHandler := handlers.NewAggregatorHandler(store, cache)
-	expenseHandler := handlers.NewExpenseHandler(store, cache)
-	washEventHandler := handlers.NewWashEventHandler(store, cache)
+	invoiceHandler := handlers.NewInvoiceHandler(store, cache)
+	reconciliationHandler := handlers.NewReconciliationHandler(store, cache)
+	clientStatementHandler := handlers.NewClientStatementHandler(store, cache)
+	creditHandler := handlers.NewCreditHandler(store, cache)
+	bankImportHandler := handlers.NewBankImportHandler(store, cache, broker)
+	exportHandler := handlers.NewExportHandler(store, cache)
+	aggregatorRegisterHandler := handlers.NewAggregatorRegisterHandler(store, cache, broker)
+	priceListVersionHandler := handlers.NewPriceListVersionHandler(store, cache)
+	priceListImportHandler := handlers.NewPriceListImportHandler(store, cache)
+	expenseHandler := handlers.NewExpenseHandler(store, cache, broker)
+	washEventHandler := handlers.NewWashEventHandler(store, cache, broker)
 	salarySchemeHandler := handlers.NewSalarySchemeHandler(store, cache)
-	transactionHandler := handlers.NewTransactionHandler(store, cache)
+	transactionHandler := handlers.NewTransactionHandler(store, cache, broker)
 	priceListHandler := handlers.NewPriceListHandler(store, cache)
 	inventoryHandler := handlers.NewInventoryHandler(store, cache)
 	salaryReportHandler := handlers.NewSalaryReportHandler(store, cache)
+	eventsHandler := handlers.NewEventsHandler(broker)
+	shiftHandler := handlers.NewShiftHandler(store, cache, broker)
+	payrollPeriodHandler := handlers.NewPayrollPeriodHandler(store, cache)
+	auditLogHandler := handlers.NewAuditLogHandler(store, cache)
+	payslipHandler := handlers.NewPayslipHandler(store, cache)
+	chemicalConsumptionHandler := handlers.NewChemicalConsumptionHandler(store, cache)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -93,14 +122,26 @@ func main() {
 	employees.Delete("/:id", employeeHandler.Delete)
 	employees.Post("/:id/transactions", employeeHandler.AddTransaction)
 	employees.Delete("/:id/transactions", employeeHandler.DeleteTransaction)
+	employees.Get("/:id/payslip", payslipHandler.Get)
+	employees.Get("/:id/loans", loanHandler.GetEmployeeLoans)
+
+	// Loan settings routes
+	api.Get("/loan-settings", loanHandler.GetSettings)
+	api.Put("/loan-settings", loanHandler.UpdateSettings)
 
 	// Counter Agents routes
 	counterAgents := api.Group("/counter-agents")
 	counterAgents.Get("/", counterAgentHandler.GetAll)
 	counterAgents.Post("/", counterAgentHandler.Create)
+	counterAgents.Get("/credit", creditHandler.GetAll)
+	counterAgents.Get("/requisites", counterAgentHandler.GetRequisitesIssues)
 	counterAgents.Get("/:id", counterAgentHandler.GetByID)
 	counterAgents.Put("/:id", counterAgentHandler.Update)
 	counterAgents.Delete("/:id", counterAgentHandler.Delete)
+	counterAgents.Get("/:id/reconciliation", reconciliationHandler.GetForCounterAgent)
+	counterAgents.Get("/:id/credit", creditHandler.GetByID)
+	counterAgents.Get("/:id/price-list-versions", priceListVersionHandler.GetCounterAgentVersions)
+	counterAgents.Get("/:id/price-list/csv", priceListImportHandler.ExportCounterAgent)
 
 	// Aggregators routes
 	aggregators := api.Group("/aggregators")
@@ -109,6 +150,28 @@ func main() {
 	aggregators.Get("/:id", aggregatorHandler.GetByID)
 	aggregators.Put("/:id", aggregatorHandler.Update)
 	aggregators.Delete("/:id", aggregatorHandler.Delete)
+	aggregators.Get("/:id/reconciliation", reconciliationHandler.GetForAggregator)
+	aggregators.Get("/:id/price-list-schedule", aggregatorHandler.GetPriceListSchedule)
+	aggregators.Get("/:id/price-list-versions", priceListVersionHandler.GetAggregatorVersions)
+	aggregators.Get("/:id/price-lists/csv", priceListImportHandler.ExportAggregator)
+
+	// Invoices routes
+	invoices := api.Group("/invoices")
+	invoices.Get("/", invoiceHandler.GetAll)
+	invoices.Post("/", invoiceHandler.Create)
+	invoices.Get("/:id", invoiceHandler.GetByID)
+	invoices.Put("/:id", invoiceHandler.Update)
+	invoices.Delete("/:id", invoiceHandler.Delete)
+	invoices.Post("/:id/status", invoiceHandler.SetStatus)
+	invoices.Get("/:id/document", invoiceHandler.GetDocument)
+	invoices.Get("/:id/act", invoiceHandler.GetAct)
+
+	// Client statement routes
+	api.Get("/clients/:id/statement", clientStatementHandler.Get)
+
+	// Company details routes
+	api.Get("/company-details", invoiceHandler.GetCompanyDetails)
+	api.Put("/company-details", invoiceHandler.UpdateCompanyDetails)
 
 	// Expenses routes
 	expenses := api.Group("/expenses")
@@ -130,7 +193,10 @@ func main() {
 	salarySchemes := api.Group("/salary-schemes")
 	salarySchemes.Get("/", salarySchemeHandler.GetAll)
 	salarySchemes.Post("/", salarySchemeHandler.Create)
+	salarySchemes.Post("/simulate", salarySchemeHandler.Simulate)
 	salarySchemes.Get("/:id", salarySchemeHandler.GetByID)
+	salarySchemes.Get("/:id/gaps", salarySchemeHandler.GetGaps)
+	salarySchemes.Post("/:id/gaps/rates", salarySchemeHandler.FillGaps)
 	salarySchemes.Put("/:id", salarySchemeHandler.Update)
 	salarySchemes.Delete("/:id", salarySchemeHandler.Delete)
 
@@ -139,15 +205,67 @@ func main() {
 	clientTransactions.Post("/:clientId", transactionHandler.AddClientTransaction)
 	clientTransactions.Delete("/:clientId", transactionHandler.DeleteClientTransaction)
 
+	// Bank statement import routes
+	bankImports := api.Group("/bank-imports")
+	bankImports.Get("/", bankImportHandler.GetAll)
+	bankImports.Post("/", bankImportHandler.Create)
+	bankImports.Get("/:id", bankImportHandler.GetByID)
+	bankImports.Put("/:id/payments/:paymentId", bankImportHandler.UpdatePayment)
+	bankImports.Post("/:id/confirm", bankImportHandler.Confirm)
+
+	// Aggregator register reconciliation routes
+	aggregatorRegisters := api.Group("/aggregator-registers")
+	aggregatorRegisters.Get("/", aggregatorRegisterHandler.GetAll)
+	aggregatorRegisters.Post("/", aggregatorRegisterHandler.Create)
+	aggregatorRegisters.Get("/:id", aggregatorRegisterHandler.GetByID)
+	aggregatorRegisters.Post("/:id/items/:itemId/resolve", aggregatorRegisterHandler.ResolveItem)
+
+	// Price list CSV import routes
+	priceListImports := api.Group("/price-list-imports")
+	priceListImports.Get("/", priceListImportHandler.GetAll)
+	priceListImports.Post("/", priceListImportHandler.Create)
+	priceListImports.Get("/:id", priceListImportHandler.GetByID)
+	priceListImports.Post("/:id/confirm", priceListImportHandler.Confirm)
+
+	// Exports to accounting
+	exports := api.Group("/exports")
+	exports.Get("/1c", exportHandler.EnterpriseData)
+
+	// Shifts routes
+	shifts := api.Group("/shifts")
+	shifts.Get("/", shiftHandler.GetAll)
+	shifts.Get("/current", shiftHandler.GetCurrent)
+	shifts.Post("/open", shiftHandler.Open)
+	shifts.Post("/close", shiftHandler.Close)
+	shifts.Get("/:id", shiftHandler.GetByID)
+
 	// Retail Price List routes
 	api.Get("/retail-price-list", priceListHandler.Get)
 	api.Post("/retail-price-list", priceListHandler.Update)
+	api.Get("/retail-price-list/versions", priceListVersionHandler.GetRetailVersions)
+	api.Get("/retail-price-list/csv", priceListImportHandler.ExportRetail)
 
 	// Inventory route (bonus - useful for frontend)
 	api.Get("/inventory", inventoryHandler.Get)
 
 	// Salary Report route
 	api.Get("/salary-report", salaryReportHandler.GenerateReport)
+	api.Get("/chemical-consumption", chemicalConsumptionHandler.GetReport)
+
+	// Payroll Periods routes
+	payrollPeriods := api.Group("/payroll-periods")
+	payrollPeriods.Get("/", payrollPeriodHandler.GetAll)
+	payrollPeriods.Post("/", payrollPeriodHandler.Create)
+	payrollPeriods.Get("/:id", payrollPeriodHandler.GetByID)
+	payrollPeriods.Delete("/:id", payrollPeriodHandler.Delete)
+	payrollPeriods.Post("/:id/close", payrollPeriodHandler.Close)
+	payrollPeriods.Post("/:id/reopen", payrollPeriodHandler.Reopen)
+
+	// Audit Log route
+	api.Get("/audit-log", auditLogHandler.GetAll)
+
+	// Live events route (Server-Sent Events)
+	api.Get("/events", eventsHandler.Stream)
 
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
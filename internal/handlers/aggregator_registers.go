@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type AggregatorRegisterHandler struct {
+	store  *storage.JSONStore
+	cache  *storage.Cache
+	broker *services.EventBroker
+	// mu serializes resolutions so a missing wash is never created twice
+	mu sync.Mutex
+}
+
+func NewAggregatorRegisterHandler(store *storage.JSONStore, cache *storage.Cache, broker *services.EventBroker) *AggregatorRegisterHandler {
+	return &AggregatorRegisterHandler{
+		store:  store,
+		cache:  cache,
+		broker: broker,
+	}
+}
+
+// ResolveRegisterItemRequest is the body of
+// POST /api/aggregator-registers/:id/items/:itemId/resolve
+type ResolveRegisterItemRequest struct {
+	Action string `json:"action"` // "create", "adjust" or "ignore"
+	Reason string `json:"reason"`
+}
+
+// GetAll handles GET /api/aggregator-registers?aggregatorId=
+func (h *AggregatorRegisterHandler) GetAll(c *fiber.Ctx) error {
+	registers, err := h.store.GetAllAggregatorRegisters()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get aggregator registers",
+		})
+	}
+
+	aggregatorID := c.Query("aggregatorId")
+	result := []models.AggregatorRegister{}
+	for _, register := range registers {
+		if aggregatorID == "" || register.AggregatorID == aggregatorID {
+			result = append(result, register)
+		}
+	}
+
+	return c.JSON(result)
+}
+
+// GetByID handles GET /api/aggregator-registers/:id
+func (h *AggregatorRegisterHandler) GetByID(c *fiber.Ctx) error {
+	register, err := h.store.GetAggregatorRegisterByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Aggregator register not found",
+		})
+	}
+
+	return c.JSON(register)
+}
+
+// Create handles POST /api/aggregator-registers?aggregatorId=&from=&to=
+// The CSV register is uploaded as the multipart field "file" or as the raw
+// body and matched against the aggregator's washes. The washes compared are
+// those from the first to the last date of the register unless from and to
+// are given.
+func (h *AggregatorRegisterHandler) Create(c *fiber.Ctx) error {
+	aggregatorID := c.Query("aggregatorId", c.FormValue("aggregatorId"))
+	agg, err := h.store.GetAggregatorByID(aggregatorID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Aggregator not found",
+		})
+	}
+
+	data, fileName, err := uploadedFile(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+
+	rows, err := services.ParseAggregatorRegister(data)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	dateFrom, dateTo := services.RegisterDates(rows)
+	if c.Query("from") != "" || c.Query("to") != "" {
+		for _, date := range []string{c.Query("from"), c.Query("to")} {
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "from and to must both be dates in YYYY-MM-DD format",
+				})
+			}
+		}
+		dateFrom, dateTo = c.Query("from"), c.Query("to")
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	register := models.AggregatorRegister{
+		ID:             fmt.Sprintf("areg_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		AggregatorID:   agg.ID,
+		AggregatorName: agg.Name,
+		FileName:       fileName,
+		ImportedAt:     time.Now().Format(time.RFC3339),
+		DateFrom:       dateFrom,
+		DateTo:         dateTo,
+		Items:          services.ReconcileAggregatorRegister(rows, washEvents, agg.ID, dateFrom, dateTo),
+	}
+
+	if err := h.store.SaveAggregatorRegister(&register); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save aggregator register",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(register)
+}
+
+// ResolveItem handles POST /api/aggregator-registers/:id/items/:itemId/resolve
+// Settles a discrepancy: "create" adds the wash missing on our side, "adjust"
+// sets our wash to the register price, "ignore" leaves both sides as they are
+// and needs a reason.
+func (h *AggregatorRegisterHandler) ResolveItem(c *fiber.Ctx) error {
+	var req ResolveRegisterItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	register, err := h.store.GetAggregatorRegisterByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Aggregator register not found",
+		})
+	}
+
+	item := findRegisterItem(register, c.Params("itemId"))
+	if item == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Register item not found",
+		})
+	}
+	if item.Status == models.AggregatorRegisterMatched {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Item matches, there is nothing to resolve",
+		})
+	}
+	if item.Resolution != "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Item is already resolved",
+		})
+	}
+
+	previous := *item
+	var resolution string
+	var event *models.WashEvent
+	eventType := services.EventWashUpdated
+	switch req.Action {
+	case "create":
+		if item.Status != models.AggregatorRegisterMissingOurs {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Only washes missing on our side can be created",
+			})
+		}
+		var status int
+		var body fiber.Map
+		if event, status, body = h.registerWash(register, item); status != 0 {
+			return c.Status(status).JSON(body)
+		}
+		resolution, eventType = "created", services.EventWashCreated
+	case "adjust":
+		if item.Status != models.AggregatorRegisterPriceMismatch {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Only price mismatches can be adjusted",
+			})
+		}
+		var status int
+		var body fiber.Map
+		if event, status, body = h.adjustedWash(c, item, req.Reason); status != 0 {
+			return c.Status(status).JSON(body)
+		}
+		resolution = "adjusted"
+	case "ignore":
+		if req.Reason == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "reason is required to ignore a discrepancy",
+			})
+		}
+		resolution = "ignored"
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "action must be create, adjust or ignore",
+		})
+	}
+
+	item.Resolution = resolution
+	item.ResolvedAt = time.Now().Format(time.RFC3339)
+	item.ResolvedReason = req.Reason
+
+	// The register is saved before the wash: a wash saved for an item left
+	// unresolved would be created again by a retry, while an item resolved
+	// for a wash that failed to save is reopened below
+	if err := h.store.SaveAggregatorRegister(register); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save aggregator register",
+		})
+	}
+
+	if event != nil {
+		if err := h.store.SaveWashEvent(event); err != nil {
+			*item = previous
+			if err := h.store.SaveAggregatorRegister(register); err != nil {
+				log.Printf("Failed to reopen item %s of aggregator register %s: %v", item.ID, register.ID, err)
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to save wash event",
+			})
+		}
+		h.cache.InvalidateWashEvents()
+		h.broker.Publish(eventType, event)
+	}
+
+	recordAudit(h.store, c, "aggregatorRegister.resolve", "aggregatorRegister", register.ID, req.Reason, map[string]interface{}{
+		"itemId":      item.ID,
+		"resolution":  resolution,
+		"washEventId": item.WashEventID,
+	})
+
+	return c.JSON(item)
+}
+
+// registerWash builds the wash of a register row missing from our log, at
+// noon of the register date and priced as the aggregator pays for it, and
+// points the item at it
+func (h *AggregatorRegisterHandler) registerWash(register *models.AggregatorRegister, item *models.AggregatorRegisterItem) (*models.WashEvent, int, fiber.Map) {
+	date, err := time.ParseInLocation("2006-01-02", item.Date, time.Local)
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, fiber.Map{
+			"error": "Invalid register date",
+		}
+	}
+	timestamp := date.Add(12 * time.Hour).UTC().Format(time.RFC3339Nano)
+
+	if locked, err := findLockingPayrollPeriod(h.store, timestamp); err != nil {
+		return nil, fiber.StatusInternalServerError, fiber.Map{
+			"error": "Failed to check payroll periods",
+		}
+	} else if locked != nil {
+		return nil, fiber.StatusConflict, payrollLockedBody(locked)
+	}
+
+	serviceName := item.ServiceName
+	if serviceName == "" {
+		serviceName = "Мойка по реестру агрегатора"
+	}
+
+	event := models.WashEvent{
+		ID:            fmt.Sprintf("we_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		Timestamp:     timestamp,
+		VehicleNumber: item.VehicleNumber,
+		EmployeeIDs:   []string{},
+		PaymentMethod: models.WashPaymentAggregator,
+		SourceID:      register.AggregatorID,
+		SourceName:    register.AggregatorName,
+		TotalAmount:   item.Amount,
+		Services: models.WashServices{
+			Main:       models.PriceListItem{ServiceName: serviceName, Price: item.Amount},
+			Additional: []models.PriceListItem{},
+		},
+	}
+	stampWashServiceIDs(h.store, &event)
+
+	item.WashEventID = event.ID
+	item.WashServiceName = serviceName
+	item.WashAmount = event.TotalAmount
+	return &event, 0, nil
+}
+
+// adjustedWash returns our wash set to the register amount, taking the
+// difference on the main service, with the edit recorded in its history
+func (h *AggregatorRegisterHandler) adjustedWash(c *fiber.Ctx, item *models.AggregatorRegisterItem, reason string) (*models.WashEvent, int, fiber.Map) {
+	event, err := h.store.GetWashEventByID(item.WashEventID)
+	if err != nil {
+		return nil, fiber.StatusNotFound, fiber.Map{
+			"error": "Wash event not found",
+		}
+	}
+
+	if locked, err := findLockingPayrollPeriod(h.store, event.Timestamp); err != nil {
+		return nil, fiber.StatusInternalServerError, fiber.Map{
+			"error": "Failed to check payroll periods",
+		}
+	} else if locked != nil {
+		return nil, fiber.StatusConflict, payrollLockedBody(locked)
+	}
+
+	if reason == "" {
+		reason = "Сверка с реестром агрегатора"
+	}
+	event.EditHistory = append(event.EditHistory, models.WashEventEditHistory{
+		EditedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		EditedBy: currentEmployeeID(c),
+		PreviousState: map[string]interface{}{
+			"totalAmount": event.TotalAmount,
+			"netAmount":   event.NetAmount,
+			"services":    event.Services,
+		},
+		Reason: reason,
+	})
+
+	diff := item.Amount - event.TotalAmount
+	event.TotalAmount = item.Amount
+	event.Services.Main.Price += diff
+	if event.NetAmount != 0 {
+		event.NetAmount += diff
+	}
+
+	item.WashAmount = event.TotalAmount
+	return event, 0, nil
+}
+
+func findRegisterItem(register *models.AggregatorRegister, id string) *models.AggregatorRegisterItem {
+	for i := range register.Items {
+		if register.Items[i].ID == id {
+			return &register.Items[i]
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"backend-go/internal/models"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
@@ -70,6 +71,18 @@ func (h *AggregatorHandler) Create(c *fiber.Ctx) error {
 		agg.PriceLists = []models.NamedPriceList{}
 	}
 
+	if fields := companiesRequisiteErrors(agg.Companies, nil); fields != nil {
+		return requisitesErrorResponse(c, fields)
+	}
+	if err := services.ValidatePriceListSchedule(&agg); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	assignAggregatorServiceIDs(nil, &agg)
+	versionAggregatorPriceLists(nil, &agg, "")
+
 	if err := h.store.SaveAggregator(&agg); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save aggregator",
@@ -78,15 +91,38 @@ func (h *AggregatorHandler) Create(c *fiber.Ctx) error {
 
 	h.cache.InvalidateAggregators()
 
-	return c.Status(fiber.StatusCreated).JSON(agg)
+	return c.Status(fiber.StatusCreated).JSON(aggregatorResponse{
+		Aggregator:     &agg,
+		ScheduleIssues: scheduleIssuesOf(&agg),
+	})
 }
 
-// Update handles PUT /api/aggregators/:id
+// aggregatorResponse is the saved aggregator with the rate schemes its price
+// lists drifted from and the gaps and overlaps in their schedule
+type aggregatorResponse struct {
+	*models.Aggregator
+	SchemeDrift    []models.SalarySchemeDrift      `json:"schemeDrift,omitempty"`
+	ScheduleIssues []models.PriceListScheduleIssue `json:"scheduleIssues,omitempty"`
+}
+
+// scheduleIssuesOf reports the schedule issues of a saved aggregator
+func scheduleIssuesOf(agg *models.Aggregator) []models.PriceListScheduleIssue {
+	issues := services.PriceListScheduleIssues(agg, time.Now())
+	if len(issues) == 0 {
+		return nil
+	}
+	return issues
+}
+
+// Update handles PUT /api/aggregators/:id?propagateRenames=true&effectiveFrom=YYYY-MM-DD
+// With propagateRenames renamed services are renamed in the rate schemes
+// using the aggregator's price lists. Price changes take effect from
+// effectiveFrom, today by default.
 func (h *AggregatorHandler) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	// Get existing aggregator
-	_, err := h.store.GetAggregatorByID(id)
+	existing, err := h.store.GetAggregatorByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Aggregator not found",
@@ -103,6 +139,23 @@ func (h *AggregatorHandler) Update(c *fiber.Ctx) error {
 	// Ensure ID is preserved
 	updates.ID = id
 
+	if fields := companiesRequisiteErrors(updates.Companies, existing.Companies); fields != nil {
+		return requisitesErrorResponse(c, fields)
+	}
+	if err := services.ValidatePriceListSchedule(&updates); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	effectiveFrom, ok := priceListEffectiveFrom(c)
+	if !ok {
+		return invalidEffectiveFromResponse(c)
+	}
+
+	assignAggregatorServiceIDs(existing, &updates)
+	versionAggregatorPriceLists(existing, &updates, effectiveFrom)
+
 	if err := h.store.SaveAggregator(&updates); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update aggregator",
@@ -111,7 +164,51 @@ func (h *AggregatorHandler) Update(c *fiber.Ctx) error {
 
 	h.cache.InvalidateAggregators()
 
-	return c.JSON(updates)
+	drift, err := syncRateSchemes(h.store, h.cache,
+		priceListState{aggregators: []models.Aggregator{*existing}},
+		priceListState{aggregators: []models.Aggregator{updates}},
+		c.QueryBool("propagateRenames"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sync salary schemes",
+		})
+	}
+
+	return c.JSON(aggregatorResponse{
+		Aggregator:     &updates,
+		SchemeDrift:    drift,
+		ScheduleIssues: scheduleIssuesOf(&updates),
+	})
+}
+
+// GetPriceListSchedule handles GET /api/aggregators/:id/price-list-schedule?at=
+// Returns the price list in effect at the given time (now by default), to
+// price washes with, and the gaps and overlaps in the schedule.
+func (h *AggregatorHandler) GetPriceListSchedule(c *fiber.Ctx) error {
+	agg, err := h.store.GetAggregatorByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Aggregator not found",
+		})
+	}
+
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		t, ok := services.ParseTimestamp(value)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "at must be a date or an ISO timestamp",
+			})
+		}
+		at = t
+	}
+
+	name := services.AggregatorPriceListName(agg, at)
+	return c.JSON(fiber.Map{
+		"priceListName": name,
+		"services":      services.AggregatorPriceList(agg, name),
+		"issues":        services.PriceListScheduleIssues(agg, time.Now()),
+	})
 }
 
 // Delete handles DELETE /api/aggregators/:id
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/storage"
+)
+
+type AuditLogHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewAuditLogHandler(store *storage.JSONStore, cache *storage.Cache) *AuditLogHandler {
+	return &AuditLogHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// GetAll handles GET /api/audit-log?entityType=xxx&entityId=xxx
+func (h *AuditLogHandler) GetAll(c *fiber.Ctx) error {
+	entries, err := h.store.GetAuditLog()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get audit log",
+		})
+	}
+
+	entityType := c.Query("entityType")
+	entityID := c.Query("entityId")
+
+	// Newest entries first
+	result := []models.AuditLogEntry{}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entityType != "" && entry.EntityType != entityType {
+			continue
+		}
+		if entityID != "" && entry.EntityID != entityID {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	return c.JSON(result)
+}
+
+// recordAudit appends an entry to the audit log on behalf of the current user.
+// Failures are logged but do not fail the request that triggered them.
+func recordAudit(store *storage.JSONStore, c *fiber.Ctx, action, entityType, entityID, reason string, details map[string]interface{}) {
+	entry := models.AuditLogEntry{
+		ID:         fmt.Sprintf("audit_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
+		ActorID:    currentEmployeeID(c),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Reason:     reason,
+		Details:    details,
+	}
+
+	if err := store.AppendAuditLogEntry(entry); err != nil {
+		log.Printf("Failed to write audit log entry %s for %s: %v", action, entityID, err)
+	}
+}
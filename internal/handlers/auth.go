@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"time"
 
@@ -10,6 +13,29 @@ import (
 	"backend-go/internal/storage"
 )
 
+// Session cookies. The employee cookie stays plain JSON for the frontend;
+// the signature cookie proves the server issued it.
+const (
+	sessionCookie          = "employee_auth_sim"
+	sessionSignatureCookie = "employee_auth_sig"
+)
+
+// sessionSecret signs session cookies, see SetSessionSecret
+var sessionSecret []byte
+
+// SetSessionSecret sets the key session cookies are signed with. Until it is
+// called no session is accepted.
+func SetSessionSecret(secret []byte) {
+	sessionSecret = secret
+}
+
+// signSession returns the signature of a session cookie value
+func signSession(value string) string {
+	mac := hmac.New(sha256.New, sessionSecret)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 type AuthHandler struct {
 	store *storage.JSONStore
 	cache *storage.Cache
@@ -73,6 +99,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		PaymentDetails: foundEmployee.PaymentDetails,
 		HasCar:         foundEmployee.HasCar,
 		Username:       foundEmployee.Username,
+		Role:           foundEmployee.Role,
 		SalarySchemeID: foundEmployee.SalarySchemeID,
 	}
 
@@ -84,29 +111,36 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Set cookie
-	c.Cookie(&fiber.Cookie{
-		Name:     "employee_auth_sim",
-		Value:    string(cookieData),
-		Path:     "/",
-		MaxAge:   60 * 60 * 24 * 7, // 7 days
-		Secure:   false,
-		HTTPOnly: true,
-		SameSite: "Lax",
-	})
+	// Set cookies
+	for name, value := range map[string]string{
+		sessionCookie:          string(cookieData),
+		sessionSignatureCookie: signSession(string(cookieData)),
+	} {
+		c.Cookie(&fiber.Cookie{
+			Name:     name,
+			Value:    value,
+			Path:     "/",
+			MaxAge:   60 * 60 * 24 * 7, // 7 days
+			Secure:   false,
+			HTTPOnly: true,
+			SameSite: "Lax",
+		})
+	}
 
 	return c.JSON(empWithoutPassword)
 }
 
 // Logout handles POST /api/auth/logout
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
-	// Clear cookie by setting it to expire
-	c.Cookie(&fiber.Cookie{
-		Name:    "employee_auth_sim",
-		Value:   "",
-		Path:    "/",
-		Expires: time.Now().Add(-time.Hour),
-	})
+	// Clear cookies by setting them to expire
+	for _, name := range []string{sessionCookie, sessionSignatureCookie} {
+		c.Cookie(&fiber.Cookie{
+			Name:    name,
+			Value:   "",
+			Path:    "/",
+			Expires: time.Now().Add(-time.Hour),
+		})
+	}
 
 	return c.JSON(fiber.Map{
 		"message": "Logged out successfully",
@@ -115,15 +149,14 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 
 // Me handles GET /api/auth/me
 func (h *AuthHandler) Me(c *fiber.Ctx) error {
-	cookieValue := c.Cookies("employee_auth_sim")
-	if cookieValue == "" {
+	if c.Cookies(sessionCookie) == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Not authenticated",
 		})
 	}
 
-	var employee models.EmployeeWithoutPassword
-	if err := json.Unmarshal([]byte(cookieValue), &employee); err != nil {
+	employee, ok := currentEmployee(c)
+	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid session",
 		})
@@ -132,6 +165,45 @@ func (h *AuthHandler) Me(c *fiber.Ctx) error {
 	return c.JSON(employee)
 }
 
+// currentEmployee returns the employee from the session cookie, if any and
+// signed by this server
+func currentEmployee(c *fiber.Ctx) (*models.EmployeeWithoutPassword, bool) {
+	cookieValue := c.Cookies(sessionCookie)
+	if cookieValue == "" || len(sessionSecret) == 0 {
+		return nil, false
+	}
+	signature := c.Cookies(sessionSignatureCookie)
+	if !hmac.Equal([]byte(signature), []byte(signSession(cookieValue))) {
+		return nil, false
+	}
+
+	var employee models.EmployeeWithoutPassword
+	if err := json.Unmarshal([]byte(cookieValue), &employee); err != nil {
+		return nil, false
+	}
+	return &employee, true
+}
+
+// currentEmployeeID returns the ID of the logged in employee or an empty string
+func currentEmployeeID(c *fiber.Ctx) string {
+	if employee, ok := currentEmployee(c); ok {
+		return employee.ID
+	}
+	return ""
+}
+
+// isAdmin reports whether the request comes from a manager. The role is
+// read from the stored employee, not the cookie, so a demoted or deleted
+// employee loses the rights with the next request.
+func isAdmin(store *storage.JSONStore, c *fiber.Ctx) bool {
+	employee, ok := currentEmployee(c)
+	if !ok {
+		return false
+	}
+	stored, err := store.GetEmployeeByID(employee.ID)
+	return err == nil && stored.Role == models.EmployeeRoleAdmin
+}
+
 func (h *AuthHandler) getEmployees() ([]models.Employee, error) {
 	if cached, ok := h.cache.GetEmployees(); ok {
 		return cached, nil
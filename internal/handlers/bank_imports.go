@@ -0,0 +1,428 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type BankImportHandler struct {
+	store  *storage.JSONStore
+	cache  *storage.Cache
+	broker *services.EventBroker
+	// mu serializes confirmations so a payment is never posted twice
+	mu sync.Mutex
+}
+
+func NewBankImportHandler(store *storage.JSONStore, cache *storage.Cache, broker *services.EventBroker) *BankImportHandler {
+	return &BankImportHandler{
+		store:  store,
+		cache:  cache,
+		broker: broker,
+	}
+}
+
+// UpdateBankPaymentRequest is the body of PUT /api/bank-imports/:id/payments/:paymentId
+type UpdateBankPaymentRequest struct {
+	ClientID string `json:"clientId"`
+	Ignored  bool   `json:"ignored"`
+}
+
+// ConfirmBankImportRequest is the body of POST /api/bank-imports/:id/confirm
+type ConfirmBankImportRequest struct {
+	PaymentIDs []string `json:"paymentIds"` // all matched payments when empty
+}
+
+// GetAll handles GET /api/bank-imports
+func (h *BankImportHandler) GetAll(c *fiber.Ctx) error {
+	imports, err := h.store.GetAllBankStatementImports()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get bank statement imports",
+		})
+	}
+	if imports == nil {
+		imports = []models.BankStatementImport{}
+	}
+
+	return c.JSON(imports)
+}
+
+// GetByID handles GET /api/bank-imports/:id
+func (h *BankImportHandler) GetByID(c *fiber.Ctx) error {
+	imp, err := h.store.GetBankStatementImportByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bank statement import not found",
+		})
+	}
+
+	return c.JSON(imp)
+}
+
+// Create handles POST /api/bank-imports
+// The statement is uploaded as the multipart field "file" or as the raw body.
+// Incoming payments are matched to clients and proposed for posting; nothing
+// is posted until the import is confirmed.
+func (h *BankImportHandler) Create(c *fiber.Ctx) error {
+	data, fileName := c.Body(), c.Query("fileName")
+	if header, err := c.FormFile("file"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read uploaded file",
+			})
+		}
+		defer file.Close()
+		if data, err = io.ReadAll(file); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to read uploaded file",
+			})
+		}
+		fileName = header.Filename
+	}
+
+	statement, err := services.ParseBankStatement(data)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	company, err := h.store.GetCompanyDetails()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get company details",
+		})
+	}
+
+	payments, err := services.IncomingBankPayments(statement, company.INN)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	clients, err := bankClients(h.store)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get clients",
+		})
+	}
+
+	posted, err := postedBankDocuments(h.store, clients)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get client transactions",
+		})
+	}
+
+	for i := range payments {
+		p := &payments[i]
+		p.ID = fmt.Sprintf("bpay_%d", i+1)
+		if transactionID, ok := posted[services.BankDocumentKey(p)]; ok {
+			p.Status = models.BankPaymentDuplicate
+			p.TransactionID = transactionID
+			continue
+		}
+		if client, matchedBy := services.MatchBankPayment(p, clients); client != nil {
+			p.Status = models.BankPaymentMatched
+			p.ClientType, p.ClientID, p.ClientName = client.Type, client.ID, client.Name
+			p.MatchedBy = matchedBy
+			continue
+		}
+		p.Status = models.BankPaymentUnmatched
+	}
+	if payments == nil {
+		payments = []models.BankPayment{}
+	}
+
+	imp := models.BankStatementImport{
+		ID:         fmt.Sprintf("bimp_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		FileName:   fileName,
+		ImportedAt: time.Now().Format(time.RFC3339),
+		Account:    statement.Header["РасчСчет"],
+		DateFrom:   services.BankDate(statement.Header["ДатаНачала"]),
+		DateTo:     services.BankDate(statement.Header["ДатаКонца"]),
+		Payments:   payments,
+	}
+
+	if err := h.store.SaveBankStatementImport(&imp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save bank statement import",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(imp)
+}
+
+// UpdatePayment handles PUT /api/bank-imports/:id/payments/:paymentId
+// Assigns a payment to a client by hand, ignores it, or with neither clears
+// the match.
+func (h *BankImportHandler) UpdatePayment(c *fiber.Ctx) error {
+	var req UpdateBankPaymentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	imp, err := h.store.GetBankStatementImportByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bank statement import not found",
+		})
+	}
+
+	p := findBankPayment(imp, c.Params("paymentId"))
+	if p == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payment not found",
+		})
+	}
+	if p.Status == models.BankPaymentPosted || p.Status == models.BankPaymentDuplicate {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Payment is already posted",
+		})
+	}
+
+	switch {
+	case req.Ignored:
+		p.Status = models.BankPaymentIgnored
+		p.ClientType, p.ClientID, p.ClientName, p.MatchedBy = "", "", "", ""
+	case req.ClientID != "":
+		clientType, clientName, ok := findClient(h.store, req.ClientID)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Client not found",
+			})
+		}
+		p.Status = models.BankPaymentMatched
+		p.ClientType, p.ClientID, p.ClientName, p.MatchedBy = clientType, req.ClientID, clientName, "manual"
+	default:
+		p.Status = models.BankPaymentUnmatched
+		p.ClientType, p.ClientID, p.ClientName, p.MatchedBy = "", "", "", ""
+	}
+
+	if err := h.store.SaveBankStatementImport(imp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save bank statement import",
+		})
+	}
+
+	return c.JSON(p)
+}
+
+// Confirm handles POST /api/bank-imports/:id/confirm
+// Posts the matched payments as client transactions in one go. Unmatched and
+// ignored payments stay in the import.
+func (h *BankImportHandler) Confirm(c *fiber.Ctx) error {
+	var req ConfirmBankImportRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	imp, err := h.store.GetBankStatementImportByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Bank statement import not found",
+		})
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range req.PaymentIDs {
+		p := findBankPayment(imp, id)
+		if p == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error":     "Payment not found",
+				"paymentId": id,
+			})
+		}
+		if p.Status != models.BankPaymentMatched {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":     "Only matched payments can be posted",
+				"paymentId": id,
+			})
+		}
+		selected[id] = true
+	}
+
+	// Group the new transactions by client so each file is written once
+	byClient := make(map[string][]*models.BankPayment)
+	var order []string
+	for i := range imp.Payments {
+		p := &imp.Payments[i]
+		if p.Status != models.BankPaymentMatched || (len(selected) > 0 && !selected[p.ID]) {
+			continue
+		}
+		if _, ok := byClient[p.ClientID]; !ok {
+			order = append(order, p.ClientID)
+		}
+		byClient[p.ClientID] = append(byClient[p.ClientID], p)
+	}
+
+	clients, err := bankClients(h.store)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get clients",
+		})
+	}
+	companies := make(map[string][]models.CounterAgentCompany, len(clients))
+	for _, client := range clients {
+		companies[client.ID] = client.Companies
+	}
+
+	posted := 0
+	for _, clientID := range order {
+		transactions, err := h.store.GetClientTransactions(clientID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get client transactions",
+			})
+		}
+
+		// A payment posted from another import of the same statement is
+		// only marked as a duplicate
+		existing := make(map[string]string)
+		for _, trans := range transactions {
+			if trans.BankDocument != "" {
+				existing[trans.BankDocument] = trans.ID
+			}
+		}
+
+		var added []models.ClientTransaction
+		var amount models.Money
+		for _, p := range byClient[clientID] {
+			key := services.BankDocumentKey(p)
+			if transactionID, ok := existing[key]; ok {
+				p.Status, p.TransactionID = models.BankPaymentDuplicate, transactionID
+				continue
+			}
+
+			trans := models.ClientTransaction{
+				ID:           fmt.Sprintf("ctrans_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+				ClientID:     clientID,
+				Date:         bankPaymentTimestamp(p.Date),
+				Type:         "payment",
+				Amount:       p.Amount,
+				Description:  services.BankPaymentDescription(p),
+				BankDocument: key,
+			}
+			// The payment is exported to accounting against the company that paid
+			if company, ok := services.PayerCompany(p, companies[clientID]); ok {
+				trans.CompanyName = company.CompanyName
+			}
+			added = append(added, trans)
+			amount += trans.Amount
+			existing[key] = trans.ID
+			p.Status, p.TransactionID = models.BankPaymentPosted, trans.ID
+		}
+		if len(added) == 0 {
+			continue
+		}
+
+		if err := h.store.SaveClientTransactions(clientID, append(transactions, added...)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to save transactions",
+			})
+		}
+		adjustClientBalance(h.store, h.cache, clientID, amount)
+		h.cache.InvalidateClientTransactions(clientID)
+		for _, trans := range added {
+			h.broker.Publish(services.EventClientPaymentCreated, trans)
+		}
+		posted += len(added)
+	}
+
+	if err := h.store.SaveBankStatementImport(imp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save bank statement import",
+		})
+	}
+
+	if posted > 0 {
+		recordAudit(h.store, c, "bankImport.confirm", "bankImport", imp.ID, "", map[string]interface{}{
+			"posted": posted,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"import": imp,
+		"posted": posted,
+	})
+}
+
+func findBankPayment(imp *models.BankStatementImport, id string) *models.BankPayment {
+	for i := range imp.Payments {
+		if imp.Payments[i].ID == id {
+			return &imp.Payments[i]
+		}
+	}
+	return nil
+}
+
+// bankClients lists the counter agents and aggregators with their requisites
+func bankClients(store *storage.JSONStore) ([]services.BankClient, error) {
+	agents, err := store.GetAllCounterAgents()
+	if err != nil {
+		return nil, err
+	}
+	aggregators, err := store.GetAllAggregators()
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]services.BankClient, 0, len(agents)+len(aggregators))
+	for _, agent := range agents {
+		clients = append(clients, services.BankClient{Type: models.InvoiceClientCounterAgent, ID: agent.ID, Name: agent.Name, Companies: agent.Companies})
+	}
+	for _, agg := range aggregators {
+		clients = append(clients, services.BankClient{Type: models.InvoiceClientAggregator, ID: agg.ID, Name: agg.Name, Companies: agg.Companies})
+	}
+	return clients, nil
+}
+
+// postedBankDocuments maps the bank payments already posted to the IDs of
+// the client transactions they were posted as
+func postedBankDocuments(store *storage.JSONStore, clients []services.BankClient) (map[string]string, error) {
+	posted := make(map[string]string)
+	for _, client := range clients {
+		transactions, err := store.GetClientTransactions(client.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, trans := range transactions {
+			if trans.BankDocument != "" {
+				posted[trans.BankDocument] = trans.ID
+			}
+		}
+	}
+	return posted, nil
+}
+
+// bankPaymentTimestamp dates a client transaction at the start of the day the
+// payment arrived
+func bankPaymentTimestamp(date string) string {
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Now().UTC().Format(time.RFC3339)
+	}
+	return t.UTC().Format(time.RFC3339)
+}
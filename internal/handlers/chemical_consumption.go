@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type ChemicalConsumptionHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewChemicalConsumptionHandler(store *storage.JSONStore, cache *storage.Cache) *ChemicalConsumptionHandler {
+	return &ChemicalConsumptionHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// GetReport handles GET /api/chemical-consumption?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Recorded consumption of each employee is compared with the norms of the
+// services performed; the variance is priced at the average purchase price.
+func (h *ChemicalConsumptionHandler) GetReport(c *fiber.Ctx) error {
+	period, err := parsePeriodQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	employees, err := h.store.GetAllEmployees()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get employees",
+		})
+	}
+
+	costPerKg, err := chemicalCostPerKg(h.store, period.To)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"costPerKg": costPerKg,
+		"employees": services.ChemicalConsumptionReport(washEvents, employees, period, costPerKg),
+	})
+}
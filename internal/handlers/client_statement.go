@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type ClientStatementHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewClientStatementHandler(store *storage.JSONStore, cache *storage.Cache) *ClientStatementHandler {
+	return &ClientStatementHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// Get handles GET /api/clients/:id/statement?from=YYYY-MM-DD&to=YYYY-MM-DD&format=json|csv
+// The client is a counter agent or an aggregator. Without from and to the
+// statement covers the whole history.
+func (h *ClientStatementHandler) Get(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be json or csv",
+		})
+	}
+
+	period, err := parsePeriodQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	clientID := c.Params("id")
+	clientType, clientName, ok := findClient(h.store, clientID)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Client not found",
+		})
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	transactions, err := h.store.GetClientTransactions(clientID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get client transactions",
+		})
+	}
+
+	statement := models.ClientStatement{
+		ClientType: clientType,
+		ClientID:   clientID,
+		ClientName: clientName,
+		From:       c.Query("from"),
+		To:         c.Query("to"),
+	}
+	services.BuildClientStatement(&statement, washEvents, transactions, period)
+
+	if format == "csv" {
+		data, err := services.ClientStatementCSV(&statement)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to render statement",
+			})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, statementFileName(clientID, statement.From, statement.To)))
+		return c.Send(data)
+	}
+
+	return c.JSON(statement)
+}
+
+// findClient resolves a client ID to an aggregator or a counter agent
+func findClient(store *storage.JSONStore, clientID string) (models.InvoiceClientType, string, bool) {
+	if agg, err := store.GetAggregatorByID(clientID); err == nil {
+		return models.InvoiceClientAggregator, agg.Name, true
+	}
+	if agent, err := store.GetCounterAgentByID(clientID); err == nil {
+		return models.InvoiceClientCounterAgent, agent.Name, true
+	}
+	return "", "", false
+}
+
+func statementFileName(clientID, from, to string) string {
+	name := "statement_" + clientID
+	if from != "" {
+		name += "_" + from
+	}
+	if to != "" {
+		name += "_" + to
+	}
+	return name + ".csv"
+}
@@ -73,6 +73,14 @@ func (h *CounterAgentHandler) Create(c *fiber.Ctx) error {
 		agent.PriceList = []models.PriceListItem{}
 	}
 
+	if fields := companiesRequisiteErrors(agent.Companies, nil); fields != nil {
+		return requisitesErrorResponse(c, fields)
+	}
+
+	assignServiceIDs(nil, agent.PriceList, agent.AdditionalPriceList)
+	agent.PriceListVersions, agent.PriceList, agent.AdditionalPriceList = versionPriceList(nil,
+		nil, nil, agent.PriceList, agent.AdditionalPriceList, "")
+
 	if err := h.store.SaveCounterAgent(&agent); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save counter agent",
@@ -84,12 +92,22 @@ func (h *CounterAgentHandler) Create(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusCreated).JSON(agent)
 }
 
-// Update handles PUT /api/counter-agents/:id
+// counterAgentResponse is the saved counter agent with the rate schemes its
+// price lists drifted from
+type counterAgentResponse struct {
+	*models.CounterAgent
+	SchemeDrift []models.SalarySchemeDrift `json:"schemeDrift,omitempty"`
+}
+
+// Update handles PUT /api/counter-agents/:id?propagateRenames=true&effectiveFrom=YYYY-MM-DD
+// With propagateRenames renamed services are renamed in the rate schemes
+// using the counter agent's price list. Price changes take effect from
+// effectiveFrom, today by default.
 func (h *CounterAgentHandler) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	// Get existing agent
-	_, err := h.store.GetCounterAgentByID(id)
+	existing, err := h.store.GetCounterAgentByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Counter agent not found",
@@ -106,6 +124,21 @@ func (h *CounterAgentHandler) Update(c *fiber.Ctx) error {
 	// Ensure ID is preserved
 	updates.ID = id
 
+	if fields := companiesRequisiteErrors(updates.Companies, existing.Companies); fields != nil {
+		return requisitesErrorResponse(c, fields)
+	}
+
+	effectiveFrom, ok := priceListEffectiveFrom(c)
+	if !ok {
+		return invalidEffectiveFromResponse(c)
+	}
+
+	before := append(append([]models.PriceListItem{}, existing.PriceList...), existing.AdditionalPriceList...)
+	assignServiceIDs(before, updates.PriceList, updates.AdditionalPriceList)
+
+	updates.PriceListVersions, updates.PriceList, updates.AdditionalPriceList = versionPriceList(existing.PriceListVersions,
+		existing.PriceList, existing.AdditionalPriceList, updates.PriceList, updates.AdditionalPriceList, effectiveFrom)
+
 	if err := h.store.SaveCounterAgent(&updates); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update counter agent",
@@ -114,7 +147,17 @@ func (h *CounterAgentHandler) Update(c *fiber.Ctx) error {
 
 	h.cache.InvalidateCounterAgents()
 
-	return c.JSON(updates)
+	drift, err := syncRateSchemes(h.store, h.cache,
+		priceListState{counterAgents: []models.CounterAgent{*existing}},
+		priceListState{counterAgents: []models.CounterAgent{updates}},
+		c.QueryBool("propagateRenames"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sync salary schemes",
+		})
+	}
+
+	return c.JSON(counterAgentResponse{CounterAgent: &updates, SchemeDrift: drift})
 }
 
 // Delete handles DELETE /api/counter-agents/:id
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type CreditHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewCreditHandler(store *storage.JSONStore, cache *storage.Cache) *CreditHandler {
+	return &CreditHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// GetAll handles GET /api/counter-agents/credit
+// Credit status of every counter agent, to spot debtors at a glance.
+func (h *CreditHandler) GetAll(c *fiber.Ctx) error {
+	agents, err := h.store.GetAllCounterAgents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get counter agents",
+		})
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	result := make([]models.CreditStatus, 0, len(agents))
+	for i := range agents {
+		transactions, err := h.store.GetClientTransactions(agents[i].ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get client transactions",
+			})
+		}
+		result = append(result, services.CounterAgentCreditStatus(&agents[i], washEvents, transactions, time.Now()))
+	}
+
+	return c.JSON(result)
+}
+
+// GetByID handles GET /api/counter-agents/:id/credit
+func (h *CreditHandler) GetByID(c *fiber.Ctx) error {
+	agent, err := h.store.GetCounterAgentByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Counter agent not found",
+		})
+	}
+
+	status, err := counterAgentCreditStatus(h.store, agent)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(status)
+}
+
+func counterAgentCreditStatus(store *storage.JSONStore, agent *models.CounterAgent) (models.CreditStatus, error) {
+	washEvents, err := store.GetAllWashEvents()
+	if err != nil {
+		return models.CreditStatus{}, fmt.Errorf("Failed to get wash events")
+	}
+
+	transactions, err := store.GetClientTransactions(agent.ID)
+	if err != nil {
+		return models.CreditStatus{}, fmt.Errorf("Failed to get client transactions")
+	}
+
+	return services.CounterAgentCreditStatus(agent, washEvents, transactions, time.Now()), nil
+}
+
+// checkWashCredit checks a contract wash being created, or an existing wash
+// being updated, against the counter agent's credit terms. An update is
+// checked for what it adds to the debt: the whole amount when the wash moves
+// to the counter agent's contract, the increase otherwise. It returns the
+// credit status when the wash breaks the terms, and a refusal as a response
+// status and body when the counter agent is blocked and the manager has not
+// overridden it with ?creditOverride=true and a reason. status is 0 when the
+// wash may be saved.
+func checkWashCredit(store *storage.JSONStore, c *fiber.Ctx, event, existing *models.WashEvent) (*models.CreditStatus, int, fiber.Map) {
+	if event.PaymentMethod != models.WashPaymentCounterAgentContract || event.SourceID == "" {
+		return nil, 0, nil
+	}
+
+	amount := event.TotalAmount
+	if existing != nil && existing.PaymentMethod == event.PaymentMethod && existing.SourceID == event.SourceID {
+		amount -= existing.TotalAmount
+	}
+	if amount <= 0 {
+		return nil, 0, nil
+	}
+
+	agent, err := store.GetCounterAgentByID(event.SourceID)
+	if err != nil {
+		return nil, 0, nil
+	}
+
+	status, err := counterAgentCreditStatus(store, agent)
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, fiber.Map{
+			"error": err.Error(),
+		}
+	}
+	if services.CreditAllows(&status, amount) {
+		return nil, 0, nil
+	}
+	if !agent.CreditBlock {
+		return &status, 0, nil
+	}
+	// This wash is what takes the counter agent over its terms
+	status.Blocked = true
+
+	if c.Query("creditOverride") != "true" {
+		return &status, fiber.StatusConflict, fiber.Map{
+			"error":        "Counter agent is over its credit limit or has overdue debt",
+			"creditStatus": status,
+		}
+	}
+	if !isAdmin(store, c) {
+		return &status, fiber.StatusForbidden, fiber.Map{
+			"error": "Only an administrator can override a credit block",
+		}
+	}
+	if c.Query("reason") == "" {
+		return &status, fiber.StatusBadRequest, fiber.Map{
+			"error": "reason is required to override a credit block",
+		}
+	}
+	return &status, 0, nil
+}
+
+// washCreditResponse answers a saved wash, with the credit status when it
+// broke the counter agent's credit terms; a block the manager overrode is
+// audited
+func washCreditResponse(store *storage.JSONStore, c *fiber.Ctx, status int, event *models.WashEvent, creditStatus *models.CreditStatus) error {
+	if creditStatus == nil {
+		return c.Status(status).JSON(event)
+	}
+
+	// The wash breaks the counter agent's credit terms: either it was let
+	// through with a warning or the manager overrode the block
+	if creditStatus.Blocked {
+		recordAudit(store, c, "washEvent.creditOverride", "washEvent", event.ID, c.Query("reason"), map[string]interface{}{
+			"counterAgentId": creditStatus.CounterAgentID,
+			"amount":         event.TotalAmount,
+			"debt":           creditStatus.Debt,
+			"creditLimit":    creditStatus.CreditLimit,
+			"overdueAmount":  creditStatus.OverdueAmount,
+		})
+	}
+
+	return c.Status(status).JSON(struct {
+		*models.WashEvent
+		CreditWarning *models.CreditStatus `json:"creditWarning"`
+	}{event, creditStatus})
+}
@@ -8,21 +8,36 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"backend-go/internal/models"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
 type EmployeeHandler struct {
-	store *storage.JSONStore
-	cache *storage.Cache
+	store      *storage.JSONStore
+	cache      *storage.Cache
+	calculator *services.SalaryCalculator
 }
 
 func NewEmployeeHandler(store *storage.JSONStore, cache *storage.Cache) *EmployeeHandler {
 	return &EmployeeHandler{
-		store: store,
-		cache: cache,
+		store:      store,
+		cache:      cache,
+		calculator: services.NewSalaryCalculator(),
 	}
 }
 
+// EmployeeSchemeChange carries the date a new salary scheme applies from on
+// PUT /api/employees/:id; it defaults to today
+type EmployeeSchemeChange struct {
+	SalarySchemeEffectiveFrom string `json:"salarySchemeEffectiveFrom"`
+}
+
+// employeeRoleChange tells a role left out of PUT /api/employees/:id, which
+// keeps the role, from an empty one, which revokes it
+type employeeRoleChange struct {
+	Role *string `json:"role"`
+}
+
 // GetAll handles GET /api/employees
 func (h *EmployeeHandler) GetAll(c *fiber.Ctx) error {
 	employees, err := h.getEmployees()
@@ -36,13 +51,15 @@ func (h *EmployeeHandler) GetAll(c *fiber.Ctx) error {
 	result := make([]models.EmployeeWithoutPassword, len(employees))
 	for i, emp := range employees {
 		result[i] = models.EmployeeWithoutPassword{
-			ID:             emp.ID,
-			FullName:       emp.FullName,
-			Phone:          emp.Phone,
-			PaymentDetails: emp.PaymentDetails,
-			HasCar:         emp.HasCar,
-			Username:       emp.Username,
-			SalarySchemeID: emp.SalarySchemeID,
+			ID:                      emp.ID,
+			FullName:                emp.FullName,
+			Phone:                   emp.Phone,
+			PaymentDetails:          emp.PaymentDetails,
+			HasCar:                  emp.HasCar,
+			Username:                emp.Username,
+			Role:                    emp.Role,
+			SalarySchemeID:          emp.SalarySchemeID,
+			SalarySchemeAssignments: emp.SalarySchemeAssignments,
 		}
 	}
 
@@ -58,6 +75,18 @@ func (h *EmployeeHandler) Create(c *fiber.Ctx) error {
 		})
 	}
 
+	if archivedScheme(h.store, emp.SalarySchemeID) {
+		return archivedSchemeResponse(c)
+	}
+	if emp.Role != "" && emp.Role != models.EmployeeRoleAdmin {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown role",
+		})
+	}
+	if emp.Role != "" && !isAdmin(h.store, c) {
+		return roleForbiddenResponse(c)
+	}
+
 	// Generate ID if not provided
 	if emp.ID == "" {
 		emp.ID = fmt.Sprintf("emp_%d_%s", time.Now().UnixMilli(), generateRandomString(7))
@@ -73,13 +102,15 @@ func (h *EmployeeHandler) Create(c *fiber.Ctx) error {
 
 	// Return without password
 	return c.Status(fiber.StatusCreated).JSON(models.EmployeeWithoutPassword{
-		ID:             emp.ID,
-		FullName:       emp.FullName,
-		Phone:          emp.Phone,
-		PaymentDetails: emp.PaymentDetails,
-		HasCar:         emp.HasCar,
-		Username:       emp.Username,
-		SalarySchemeID: emp.SalarySchemeID,
+		ID:                      emp.ID,
+		FullName:                emp.FullName,
+		Phone:                   emp.Phone,
+		PaymentDetails:          emp.PaymentDetails,
+		HasCar:                  emp.HasCar,
+		Username:                emp.Username,
+		Role:                    emp.Role,
+		SalarySchemeID:          emp.SalarySchemeID,
+		SalarySchemeAssignments: emp.SalarySchemeAssignments,
 	})
 }
 
@@ -95,13 +126,15 @@ func (h *EmployeeHandler) GetByID(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(models.EmployeeWithoutPassword{
-		ID:             emp.ID,
-		FullName:       emp.FullName,
-		Phone:          emp.Phone,
-		PaymentDetails: emp.PaymentDetails,
-		HasCar:         emp.HasCar,
-		Username:       emp.Username,
-		SalarySchemeID: emp.SalarySchemeID,
+		ID:                      emp.ID,
+		FullName:                emp.FullName,
+		Phone:                   emp.Phone,
+		PaymentDetails:          emp.PaymentDetails,
+		HasCar:                  emp.HasCar,
+		Username:                emp.Username,
+		Role:                    emp.Role,
+		SalarySchemeID:          emp.SalarySchemeID,
+		SalarySchemeAssignments: emp.SalarySchemeAssignments,
 	})
 }
 
@@ -124,12 +157,74 @@ func (h *EmployeeHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
+	var schemeChange EmployeeSchemeChange
+	if err := c.BodyParser(&schemeChange); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var roleChange employeeRoleChange
+	if err := c.BodyParser(&roleChange); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if roleChange.Role != nil && *roleChange.Role != "" && *roleChange.Role != models.EmployeeRoleAdmin {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown role",
+		})
+	}
+
+	before := *existing
+
+	// Scheme history is either replaced as a whole or extended when the
+	// current scheme changes, so past washes keep their scheme
+	if updates.SalarySchemeAssignments != nil {
+		if err := services.ValidateAssignments(updates.SalarySchemeAssignments); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		existing.SalarySchemeAssignments = updates.SalarySchemeAssignments
+		existing.SalarySchemeID = updates.SalarySchemeID
+		// The current scheme is the one the history assigns today
+		if len(existing.SalarySchemeAssignments) > 0 {
+			existing.SalarySchemeID = services.SchemeIDAt(existing, services.LocalDate(time.Now()))
+		}
+		if existing.SalarySchemeID != before.SalarySchemeID && archivedScheme(h.store, existing.SalarySchemeID) {
+			return archivedSchemeResponse(c)
+		}
+	} else if updates.SalarySchemeID != existing.SalarySchemeID {
+		if archivedScheme(h.store, updates.SalarySchemeID) {
+			return archivedSchemeResponse(c)
+		}
+		effectiveFrom := schemeChange.SalarySchemeEffectiveFrom
+		if effectiveFrom == "" {
+			effectiveFrom = services.LocalDate(time.Now())
+		}
+		if err := services.ReassignScheme(existing, updates.SalarySchemeID, effectiveFrom); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	periods, err := h.store.GetAllPayrollPeriods()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	}
+	if locked := services.FindClosedPayrollPeriodForAssignmentChange(periods, &before, existing); locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
 	// Update fields
 	existing.FullName = updates.FullName
 	existing.Phone = updates.Phone
 	existing.PaymentDetails = updates.PaymentDetails
 	existing.HasCar = updates.HasCar
-	existing.SalarySchemeID = updates.SalarySchemeID
 
 	if updates.Username != "" {
 		existing.Username = updates.Username
@@ -137,6 +232,12 @@ func (h *EmployeeHandler) Update(c *fiber.Ctx) error {
 	if updates.Password != "" {
 		existing.Password = updates.Password
 	}
+	if roleChange.Role != nil && *roleChange.Role != existing.Role {
+		if !isAdmin(h.store, c) {
+			return roleForbiddenResponse(c)
+		}
+		existing.Role = *roleChange.Role
+	}
 
 	if err := h.store.SaveEmployee(existing); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -147,13 +248,22 @@ func (h *EmployeeHandler) Update(c *fiber.Ctx) error {
 	h.cache.InvalidateEmployees()
 
 	return c.JSON(models.EmployeeWithoutPassword{
-		ID:             existing.ID,
-		FullName:       existing.FullName,
-		Phone:          existing.Phone,
-		PaymentDetails: existing.PaymentDetails,
-		HasCar:         existing.HasCar,
-		Username:       existing.Username,
-		SalarySchemeID: existing.SalarySchemeID,
+		ID:                      existing.ID,
+		FullName:                existing.FullName,
+		Phone:                   existing.Phone,
+		PaymentDetails:          existing.PaymentDetails,
+		HasCar:                  existing.HasCar,
+		Username:                existing.Username,
+		Role:                    existing.Role,
+		SalarySchemeID:          existing.SalarySchemeID,
+		SalarySchemeAssignments: existing.SalarySchemeAssignments,
+	})
+}
+
+// roleForbiddenResponse refuses to let a non-administrator grant or revoke roles
+func roleForbiddenResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": "Only an administrator can change employee roles",
 	})
 }
 
@@ -185,6 +295,14 @@ func (h *EmployeeHandler) AddTransaction(c *fiber.Ctx) error {
 		})
 	}
 
+	if locked, err := findLockingPayrollPeriod(h.store, trans.Date); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	} else if locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
 	// Generate ID if not provided
 	if trans.ID == "" {
 		trans.ID = fmt.Sprintf("trans_%d_%s", time.Now().UnixMilli(), generateRandomString(7))
@@ -197,6 +315,14 @@ func (h *EmployeeHandler) AddTransaction(c *fiber.Ctx) error {
 		transactions = []models.EmployeeTransaction{}
 	}
 
+	// Loans are limited by expected earnings; repayments by what is owed
+	if status, body := checkNewLoan(h.store, h.calculator, &trans, transactions); status != 0 {
+		return c.Status(status).JSON(body)
+	}
+	if trans.Type != models.EmpTransLoan {
+		trans.Repayment = nil
+	}
+
 	// Add new transaction
 	transactions = append(transactions, trans)
 
@@ -232,10 +358,12 @@ func (h *EmployeeHandler) DeleteTransaction(c *fiber.Ctx) error {
 
 	// Find and remove transaction
 	var found bool
+	var deleted models.EmployeeTransaction
 	var newTransactions []models.EmployeeTransaction
 	for _, t := range transactions {
 		if t.ID == transactionID {
 			found = true
+			deleted = t
 		} else {
 			newTransactions = append(newTransactions, t)
 		}
@@ -247,6 +375,23 @@ func (h *EmployeeHandler) DeleteTransaction(c *fiber.Ctx) error {
 		})
 	}
 
+	// Repayments would be left without their loan
+	for _, loan := range services.EmployeeLoans(transactions) {
+		if loan.Loan.ID == deleted.ID && len(loan.Repayments) > 0 {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Loan has repayments and cannot be deleted",
+			})
+		}
+	}
+
+	if locked, err := findLockingPayrollPeriod(h.store, deleted.Date); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	} else if locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
 	if err := h.store.SaveEmployeeTransactions(employeeID, newTransactions); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete transaction",
@@ -282,3 +427,19 @@ func generateRandomString(length int) string {
 	}
 	return string(b)
 }
+
+// archivedScheme reports whether the scheme was archived and so can no
+// longer be assigned
+func archivedScheme(store *storage.JSONStore, schemeID string) bool {
+	if schemeID == "" {
+		return false
+	}
+	scheme, err := store.GetSalarySchemeByID(schemeID)
+	return err == nil && scheme.Archived
+}
+
+func archivedSchemeResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "Salary scheme is archived and cannot be assigned",
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+
+	"backend-go/internal/services"
+)
+
+const sseHeartbeatInterval = 20 * time.Second
+
+type EventsHandler struct {
+	broker *services.EventBroker
+}
+
+func NewEventsHandler(broker *services.EventBroker) *EventsHandler {
+	return &EventsHandler{
+		broker: broker,
+	}
+}
+
+// Stream handles GET /api/events
+func (h *EventsHandler) Stream(c *fiber.Ctx) error {
+	// Browsers send Last-Event-ID on automatic reconnect; the query parameter
+	// allows clients that open a fresh EventSource to resume as well
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+
+	var lastID uint64
+	if lastEventID != "" {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid Last-Event-ID",
+			})
+		}
+		lastID = parsed
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	ch, replay, complete := h.broker.Subscribe(lastID)
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer h.broker.Unsubscribe(ch)
+
+		fmt.Fprintf(w, "retry: 3000\n\n")
+
+		if !complete {
+			writeSSEEvent(w, services.Event{
+				ID:        h.broker.LastID(),
+				Type:      services.EventStreamReset,
+				Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+				Data:      []byte("{}"),
+			})
+		}
+		for _, event := range replay {
+			writeSSEEvent(w, event)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-ch:
+				if !ok {
+					return
+				}
+				writeSSEEvent(w, event)
+			case <-heartbeat.C:
+				fmt.Fprintf(w, ": ping\n\n")
+			}
+
+			// A failed flush means the client has gone away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+
+	return nil
+}
+
+func writeSSEEvent(w *bufio.Writer, event services.Event) {
+	fmt.Fprintf(w, "id: %d\n", event.ID)
+	fmt.Fprintf(w, "event: %s\n", event.Type)
+	fmt.Fprintf(w, "data: %s\n\n", event.Data)
+}
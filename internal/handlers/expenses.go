@@ -2,24 +2,26 @@ package handlers
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"backend-go/internal/models"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
 type ExpenseHandler struct {
-	store *storage.JSONStore
-	cache *storage.Cache
+	store  *storage.JSONStore
+	cache  *storage.Cache
+	broker *services.EventBroker
 }
 
-func NewExpenseHandler(store *storage.JSONStore, cache *storage.Cache) *ExpenseHandler {
+func NewExpenseHandler(store *storage.JSONStore, cache *storage.Cache, broker *services.EventBroker) *ExpenseHandler {
 	return &ExpenseHandler{
-		store: store,
-		cache: cache,
+		store:  store,
+		cache:  cache,
+		broker: broker,
 	}
 }
 
@@ -57,7 +59,7 @@ func (h *ExpenseHandler) Create(c *fiber.Ctx) error {
 	}
 
 	// Update inventory if this is a chemical purchase
-	if expense.Category == "Закупка химии" && expense.Quantity > 0 && strings.HasPrefix(strings.ToLower(expense.Unit), "кг") {
+	if services.IsChemicalPurchase(&expense) {
 		inv, err := h.store.GetInventory()
 		if err != nil {
 			inv = &models.Inventory{ChemicalStockGrams: 0}
@@ -65,6 +67,7 @@ func (h *ExpenseHandler) Create(c *fiber.Ctx) error {
 		inv.ChemicalStockGrams += expense.Quantity * 1000 // Convert kg to grams
 		h.store.SaveInventory(inv)
 		h.cache.InvalidateInventory()
+		h.broker.Publish(services.EventInventoryUpdated, inv)
 	}
 
 	h.cache.InvalidateExpenses()
@@ -109,10 +112,10 @@ func (h *ExpenseHandler) Update(c *fiber.Ctx) error {
 	oldChemicalQty := float64(0)
 	newChemicalQty := float64(0)
 
-	if existing.Category == "Закупка химии" && existing.Quantity > 0 && strings.HasPrefix(strings.ToLower(existing.Unit), "кг") {
+	if services.IsChemicalPurchase(existing) {
 		oldChemicalQty = existing.Quantity * 1000
 	}
-	if updates.Category == "Закупка химии" && updates.Quantity > 0 && strings.HasPrefix(strings.ToLower(updates.Unit), "кг") {
+	if services.IsChemicalPurchase(&updates) {
 		newChemicalQty = updates.Quantity * 1000
 	}
 
@@ -134,6 +137,7 @@ func (h *ExpenseHandler) Update(c *fiber.Ctx) error {
 		inv.ChemicalStockGrams = inv.ChemicalStockGrams - oldChemicalQty + newChemicalQty
 		h.store.SaveInventory(inv)
 		h.cache.InvalidateInventory()
+		h.broker.Publish(services.EventInventoryUpdated, inv)
 	}
 
 	h.cache.InvalidateExpenses()
@@ -161,7 +165,7 @@ func (h *ExpenseHandler) Delete(c *fiber.Ctx) error {
 	}
 
 	// Return chemical to inventory if this was a chemical purchase
-	if existing.Category == "Закупка химии" && existing.Quantity > 0 && strings.HasPrefix(strings.ToLower(existing.Unit), "кг") {
+	if services.IsChemicalPurchase(existing) {
 		inv, err := h.store.GetInventory()
 		if err != nil {
 			inv = &models.Inventory{ChemicalStockGrams: 0}
@@ -172,6 +176,7 @@ func (h *ExpenseHandler) Delete(c *fiber.Ctx) error {
 		}
 		h.store.SaveInventory(inv)
 		h.cache.InvalidateInventory()
+		h.broker.Publish(services.EventInventoryUpdated, inv)
 	}
 
 	h.cache.InvalidateExpenses()
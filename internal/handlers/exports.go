@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type ExportHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewExportHandler(store *storage.JSONStore, cache *storage.Cache) *ExportHandler {
+	return &ExportHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// EnterpriseData handles GET /api/exports/1c?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Exports issued invoices with their acts, client payments and expenses of
+// the period to 1C in the EnterpriseData XML format.
+func (h *ExportHandler) EnterpriseData(c *fiber.Ctx) error {
+	if c.Query("from") == "" || c.Query("to") == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from and to are required",
+		})
+	}
+	period, err := parsePeriodQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	organization, err := h.store.GetCompanyDetails()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get company details",
+		})
+	}
+
+	invoices, err := h.store.GetAllInvoices()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get invoices",
+		})
+	}
+
+	clients, err := bankClients(h.store)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get clients",
+		})
+	}
+
+	imports, err := h.store.GetAllBankStatementImports()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get bank statement imports",
+		})
+	}
+	bankPayments := make(map[string]*models.BankPayment)
+	for i := range imports {
+		for j := range imports[i].Payments {
+			p := &imports[i].Payments[j]
+			bankPayments[services.BankDocumentKey(p)] = p
+		}
+	}
+
+	expenses, err := h.store.GetAllExpenses()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get expenses",
+		})
+	}
+
+	input := services.EnterpriseDataInput{
+		Organization: *organization,
+		CreatedAt:    time.Now(),
+	}
+	for _, inv := range invoices {
+		if period.ContainsTimestamp(inv.Date) {
+			input.Invoices = append(input.Invoices, inv)
+		}
+	}
+	for _, client := range clients {
+		transactions, err := h.store.GetClientTransactions(client.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to get client transactions",
+			})
+		}
+		for _, trans := range transactions {
+			if !period.ContainsTimestamp(trans.Date) {
+				continue
+			}
+			input.Payments = append(input.Payments, services.EnterpriseDataPayment{
+				Transaction: trans,
+				ClientName:  client.Name,
+				Company:     paymentCompany(&trans, client.Companies, bankPayments),
+			})
+		}
+	}
+	for _, exp := range expenses {
+		if period.ContainsTimestamp(exp.Date) {
+			input.Expenses = append(input.Expenses, exp)
+		}
+	}
+
+	data, err := services.BuildEnterpriseData(&input)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build export",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/xml; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="1c-export-%s-%s.xml"`, c.Query("from"), c.Query("to")))
+	return c.Send(data)
+}
+
+// paymentCompany returns the client's company a payment is exported against:
+// the one recorded on the transaction, else the payer of the bank document it
+// was posted from, else the client's first company
+func paymentCompany(trans *models.ClientTransaction, companies []models.CounterAgentCompany, bankPayments map[string]*models.BankPayment) models.CounterAgentCompany {
+	if trans.CompanyName != "" {
+		if company, ok := services.CustomerCompany(companies, trans.CompanyName); ok {
+			return company
+		}
+	}
+	if p, ok := bankPayments[trans.BankDocument]; ok && trans.BankDocument != "" {
+		if company, ok := services.PayerCompany(p, companies); ok {
+			return company
+		}
+	}
+	company, _ := services.CustomerCompany(companies, "")
+	return company
+}
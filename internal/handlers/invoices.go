@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type InvoiceHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+	// mu serializes invoice writes so issued numbers stay unique and gapless
+	mu sync.Mutex
+}
+
+func NewInvoiceHandler(store *storage.JSONStore, cache *storage.Cache) *InvoiceHandler {
+	return &InvoiceHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// CreateInvoiceRequest is the body of POST /api/invoices
+type CreateInvoiceRequest struct {
+	ClientType  models.InvoiceClientType `json:"clientType"`
+	ClientID    string                   `json:"clientId"`
+	From        string                   `json:"from"` // YYYY-MM-DD
+	To          string                   `json:"to"`   // YYYY-MM-DD, inclusive
+	Date        string                   `json:"date,omitempty"`
+	CompanyName string                   `json:"companyName,omitempty"`
+	Lines       []models.InvoiceLine     `json:"lines,omitempty"`
+}
+
+// UpdateInvoiceRequest is the body of PUT /api/invoices/:id
+type UpdateInvoiceRequest struct {
+	Date     string                      `json:"date,omitempty"`
+	Customer *models.CounterAgentCompany `json:"customer,omitempty"`
+	Lines    []models.InvoiceLine        `json:"lines,omitempty"`
+}
+
+// InvoiceStatusRequest is the body of POST /api/invoices/:id/status
+type InvoiceStatusRequest struct {
+	Status models.InvoiceStatus `json:"status"`
+	Reason string               `json:"reason,omitempty"`
+}
+
+// GetAll handles GET /api/invoices?clientId=&status=&year=
+func (h *InvoiceHandler) GetAll(c *fiber.Ctx) error {
+	invoices, err := h.store.GetAllInvoices()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get invoices",
+		})
+	}
+
+	clientID := c.Query("clientId")
+	status := models.InvoiceStatus(c.Query("status"))
+	year, _ := strconv.Atoi(c.Query("year"))
+
+	result := []models.Invoice{}
+	for _, inv := range invoices {
+		if clientID != "" && inv.ClientID != clientID {
+			continue
+		}
+		if status != "" && inv.Status != status {
+			continue
+		}
+		if year != 0 && services.InvoiceYear(inv.Date) != year {
+			continue
+		}
+		result = append(result, inv)
+	}
+
+	return c.JSON(result)
+}
+
+// GetByID handles GET /api/invoices/:id
+func (h *InvoiceHandler) GetByID(c *fiber.Ctx) error {
+	inv, err := h.store.GetInvoiceByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invoice not found",
+		})
+	}
+
+	return c.JSON(inv)
+}
+
+// Create handles POST /api/invoices
+// A draft is built from the client's washes in the period unless lines are
+// given. Washes already on an invoice that is not cancelled are refused.
+func (h *InvoiceHandler) Create(c *fiber.Ctx) error {
+	var req CreateInvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.From == "" || req.To == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from and to are required",
+		})
+	}
+	period, err := parsePeriod(req.From, req.To)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Date == "" {
+		req.Date = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "date must be YYYY-MM-DD",
+		})
+	}
+
+	clientName, customer, status, body := invoiceClient(h.store, req.ClientType, req.ClientID, req.CompanyName)
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
+	seller, err := h.store.GetCompanyDetails()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get company details",
+		})
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+	washes := services.InvoiceWashes(washEvents, req.ClientType, req.ClientID, period)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	invoices, err := h.store.GetAllInvoices()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get invoices",
+		})
+	}
+
+	// Lines entered by hand bill nothing in particular, so the invoice
+	// claims no washes and leaves them free for other invoices
+	var lines []models.InvoiceLine
+	washIDs := []string{}
+	if len(req.Lines) > 0 {
+		if err := services.ValidateInvoiceLines(req.Lines); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		lines = services.NormalizeInvoiceLines(req.Lines)
+	} else {
+		invoiced := make(map[string]string)
+		for _, inv := range invoices {
+			if inv.Status == models.InvoiceCancelled {
+				continue
+			}
+			for _, id := range inv.WashEventIDs {
+				invoiced[id] = inv.ID
+			}
+		}
+		for _, event := range washes {
+			if invoiceID, ok := invoiced[event.ID]; ok {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error":       "Wash event is already invoiced",
+					"washEventId": event.ID,
+					"invoiceId":   invoiceID,
+				})
+			}
+			washIDs = append(washIDs, event.ID)
+		}
+		lines = services.BuildInvoiceLines(washes)
+	}
+	if len(lines) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No services to invoice in the period",
+		})
+	}
+
+	inv := models.Invoice{
+		ID:           fmt.Sprintf("inv_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		Date:         req.Date,
+		Status:       models.InvoiceDraft,
+		ClientType:   req.ClientType,
+		ClientID:     req.ClientID,
+		ClientName:   clientName,
+		From:         req.From,
+		To:           req.To,
+		Seller:       *seller,
+		Customer:     customer,
+		Lines:        lines,
+		Total:        services.InvoiceTotal(lines),
+		WashEventIDs: washIDs,
+		CreatedAt:    time.Now().Format(time.RFC3339),
+	}
+
+	if err := h.store.SaveInvoice(&inv); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save invoice",
+		})
+	}
+
+	recordAudit(h.store, c, "invoice.create", "invoice", inv.ID, "", map[string]interface{}{
+		"clientId": inv.ClientID,
+		"from":     inv.From,
+		"to":       inv.To,
+		"total":    inv.Total,
+	})
+
+	return c.Status(fiber.StatusCreated).JSON(inv)
+}
+
+// Update handles PUT /api/invoices/:id
+// Only drafts can be edited; issued invoices keep what was sent to the client.
+func (h *InvoiceHandler) Update(c *fiber.Ctx) error {
+	var req UpdateInvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	inv, err := h.store.GetInvoiceByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invoice not found",
+		})
+	}
+	if inv.Status != models.InvoiceDraft {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Only draft invoices can be edited",
+		})
+	}
+
+	if req.Date != "" {
+		if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "date must be YYYY-MM-DD",
+			})
+		}
+		inv.Date = req.Date
+	}
+	if req.Customer != nil {
+		inv.Customer = *req.Customer
+	}
+	if req.Lines != nil {
+		if err := services.ValidateInvoiceLines(req.Lines); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		lines := services.NormalizeInvoiceLines(req.Lines)
+		// Edited lines no longer bill the washes of the period
+		if !slices.Equal(lines, inv.Lines) {
+			inv.WashEventIDs = []string{}
+		}
+		inv.Lines = lines
+		inv.Total = services.InvoiceTotal(inv.Lines)
+	}
+
+	if err := h.store.SaveInvoice(inv); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save invoice",
+		})
+	}
+
+	return c.JSON(inv)
+}
+
+// Delete handles DELETE /api/invoices/:id
+// Only drafts can be deleted; issued invoices are cancelled instead so their
+// numbers are kept.
+func (h *InvoiceHandler) Delete(c *fiber.Ctx) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	inv, err := h.store.GetInvoiceByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invoice not found",
+		})
+	}
+	if inv.Status != models.InvoiceDraft {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Only draft invoices can be deleted; cancel issued invoices instead",
+		})
+	}
+
+	if err := h.store.DeleteInvoice(inv.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete invoice",
+		})
+	}
+
+	recordAudit(h.store, c, "invoice.delete", "invoice", inv.ID, "", nil)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+	})
+}
+
+// SetStatus handles POST /api/invoices/:id/status
+// Issuing assigns the next number of the invoice year.
+func (h *InvoiceHandler) SetStatus(c *fiber.Ctx) error {
+	var req InvoiceStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	inv, err := h.store.GetInvoiceByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invoice not found",
+		})
+	}
+
+	if !services.CanTransitionInvoice(inv.Status, req.Status) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invoice cannot move from %s to %s", inv.Status, req.Status),
+		})
+	}
+	if req.Status == models.InvoiceCancelled && req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason is required to cancel an invoice",
+		})
+	}
+	if req.Status == models.InvoiceIssued && inv.Total <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invoice total must be positive to issue it",
+		})
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	previous := inv.Status
+	switch req.Status {
+	case models.InvoiceIssued:
+		if previous == models.InvoiceDraft {
+			invoices, err := h.store.GetAllInvoices()
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to get invoices",
+				})
+			}
+			inv.Year = services.InvoiceYear(inv.Date)
+			inv.Number = services.NextInvoiceNumber(invoices, inv.Year)
+			inv.IssuedAt = now
+		}
+		inv.PaidAt = ""
+	case models.InvoicePaid:
+		inv.PaidAt = now
+	case models.InvoiceCancelled:
+		inv.CancelledAt = now
+		inv.CancelReason = req.Reason
+	}
+	inv.Status = req.Status
+
+	if err := h.store.SaveInvoice(inv); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save invoice",
+		})
+	}
+
+	recordAudit(h.store, c, "invoice.status", "invoice", inv.ID, req.Reason, map[string]interface{}{
+		"from":   previous,
+		"to":     inv.Status,
+		"number": inv.Number,
+	})
+
+	return c.JSON(inv)
+}
+
+// GetDocument handles GET /api/invoices/:id/document?format=html|pdf
+// The invoice is followed by its act of services rendered.
+func (h *InvoiceHandler) GetDocument(c *fiber.Ctx) error {
+	format := c.Query("format", "html")
+	if format != "html" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be html or pdf",
+		})
+	}
+
+	inv, err := h.store.GetInvoiceByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invoice not found",
+		})
+	}
+
+	if format == "pdf" {
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, invoiceFileName("invoice", inv)))
+		return c.Send(services.RenderInvoicePDF(inv))
+	}
+
+	html, err := services.RenderInvoiceHTML(inv)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to render invoice",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(html)
+}
+
+// GetAct handles GET /api/invoices/:id/act?format=html|pdf
+// The act of services rendered on its own, for clients who sign it separately.
+func (h *InvoiceHandler) GetAct(c *fiber.Ctx) error {
+	format := c.Query("format", "html")
+	if format != "html" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be html or pdf",
+		})
+	}
+
+	inv, err := h.store.GetInvoiceByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Invoice not found",
+		})
+	}
+
+	if format == "pdf" {
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, invoiceFileName("act", inv)))
+		return c.Send(services.RenderActPDF(inv))
+	}
+
+	html, err := services.RenderActHTML(inv)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to render act",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(html)
+}
+
+// GetCompanyDetails handles GET /api/company-details
+// The seller requisites printed on invoices.
+func (h *InvoiceHandler) GetCompanyDetails(c *fiber.Ctx) error {
+	details, err := h.store.GetCompanyDetails()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get company details",
+		})
+	}
+
+	return c.JSON(details)
+}
+
+// UpdateCompanyDetails handles PUT /api/company-details
+// Invoices already created keep the requisites they were made with.
+func (h *InvoiceHandler) UpdateCompanyDetails(c *fiber.Ctx) error {
+	var details models.CounterAgentCompany
+	if err := c.BodyParser(&details); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if errs := services.CompanyRequisiteErrors(&details); errs != nil {
+		return requisitesErrorResponse(c, errs)
+	}
+
+	if err := h.store.SaveCompanyDetails(&details); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save company details",
+		})
+	}
+
+	recordAudit(h.store, c, "companyDetails.update", "companyDetails", "", "", nil)
+
+	return c.JSON(details)
+}
+
+// invoiceClient looks up the counter agent or aggregator a document is made
+// out to and picks its requisites. A failure is returned as a response status
+// and body; status is 0 when the client is found.
+func invoiceClient(store *storage.JSONStore, clientType models.InvoiceClientType, clientID, companyName string) (string, models.CounterAgentCompany, int, fiber.Map) {
+	var name string
+	var companies []models.CounterAgentCompany
+	switch clientType {
+	case models.InvoiceClientCounterAgent:
+		agent, err := store.GetCounterAgentByID(clientID)
+		if err != nil {
+			return "", models.CounterAgentCompany{}, fiber.StatusNotFound, fiber.Map{
+				"error": "Counter agent not found",
+			}
+		}
+		name, companies = agent.Name, agent.Companies
+	case models.InvoiceClientAggregator:
+		agg, err := store.GetAggregatorByID(clientID)
+		if err != nil {
+			return "", models.CounterAgentCompany{}, fiber.StatusNotFound, fiber.Map{
+				"error": "Aggregator not found",
+			}
+		}
+		name, companies = agg.Name, agg.Companies
+	default:
+		return "", models.CounterAgentCompany{}, fiber.StatusBadRequest, fiber.Map{
+			"error": "clientType must be counterAgent or aggregator",
+		}
+	}
+
+	customer, ok := services.CustomerCompany(companies, companyName)
+	if !ok && companyName != "" {
+		return "", models.CounterAgentCompany{}, fiber.StatusBadRequest, fiber.Map{
+			"error": "Company not found for the client",
+		}
+	}
+	if !ok {
+		customer = models.CounterAgentCompany{CompanyName: name}
+	}
+	return name, customer, 0, nil
+}
+
+func invoiceFileName(prefix string, inv *models.Invoice) string {
+	if inv.Number == 0 {
+		return prefix + "_" + inv.ID + ".pdf"
+	}
+	return fmt.Sprintf("%s_%d_%d.pdf", prefix, inv.Year, inv.Number)
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type LoanHandler struct {
+	store      *storage.JSONStore
+	cache      *storage.Cache
+	calculator *services.SalaryCalculator
+}
+
+func NewLoanHandler(store *storage.JSONStore, cache *storage.Cache) *LoanHandler {
+	return &LoanHandler{
+		store:      store,
+		cache:      cache,
+		calculator: services.NewSalaryCalculator(),
+	}
+}
+
+// GetEmployeeLoans handles GET /api/employees/:id/loans
+// Loans with a repayment plan, what is left to repay and the loan limit.
+func (h *LoanHandler) GetEmployeeLoans(c *fiber.Ctx) error {
+	employee, err := h.store.GetEmployeeByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Employee not found",
+		})
+	}
+
+	transactions, err := h.store.GetEmployeeTransactions(employee.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get employee transactions",
+		})
+	}
+
+	loans := services.EmployeeLoans(transactions)
+	data := models.EmployeeLoansData{
+		EmployeeID:   employee.ID,
+		EmployeeName: employee.FullName,
+		Outstanding:  services.OutstandingLoans(loans),
+		Loans:        loans,
+	}
+
+	data.ExpectedEarnings, data.Limit, data.Limited, err = loanLimit(h.store, h.calculator, employee.ID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(data)
+}
+
+// GetSettings handles GET /api/loan-settings
+func (h *LoanHandler) GetSettings(c *fiber.Ctx) error {
+	settings, err := h.store.GetLoanSettings()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get loan settings",
+		})
+	}
+
+	return c.JSON(settings)
+}
+
+// UpdateSettings handles PUT /api/loan-settings
+func (h *LoanHandler) UpdateSettings(c *fiber.Ctx) error {
+	var settings models.LoanSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if settings.LimitPercent < 0 || settings.ExpectedEarningsMonths < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "limitPercent and expectedEarningsMonths must not be negative",
+		})
+	}
+
+	if err := h.store.SaveLoanSettings(&settings); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save loan settings",
+		})
+	}
+
+	recordAudit(h.store, c, "loanSettings.update", "loanSettings", "", "", map[string]interface{}{
+		"limitPercent":           settings.LimitPercent,
+		"expectedEarningsMonths": settings.ExpectedEarningsMonths,
+	})
+
+	return c.JSON(settings)
+}
+
+// loanLimit returns the employee's expected monthly earnings and, when the
+// loan settings limit loans, the most they may owe
+func loanLimit(store *storage.JSONStore, calculator *services.SalaryCalculator, employeeID string, at time.Time) (expected, limit models.Money, limited bool, err error) {
+	settings, err := store.GetLoanSettings()
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("Failed to get loan settings")
+	}
+
+	washEvents, err := store.GetAllWashEvents()
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("Failed to get wash events")
+	}
+
+	employees, err := store.GetAllEmployees()
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("Failed to get employees")
+	}
+
+	schemes, err := loadSalarySchemesForReport(store)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	expected = services.ExpectedMonthlyEarnings(calculator, washEvents, employees, schemes, employeeID, at, settings.ExpectedEarningsMonths)
+	if settings.LimitPercent <= 0 {
+		return expected, 0, false, nil
+	}
+	limit = expected.MulPercent(settings.LimitPercent, models.RoundDown)
+	if limit < 0 {
+		limit = 0
+	}
+	return expected, limit, true, nil
+}
+
+// checkNewLoan validates a loan or repayment being added against the
+// employee's existing transactions. A refusal is returned as a response
+// status and body; status is 0 when the transaction is accepted.
+func checkNewLoan(store *storage.JSONStore, calculator *services.SalaryCalculator, trans *models.EmployeeTransaction, existing []models.EmployeeTransaction) (int, fiber.Map) {
+	switch trans.Type {
+	case models.EmpTransLoanRepayment:
+		for _, loan := range services.EmployeeLoans(existing) {
+			if loan.Loan.ID != trans.LoanID {
+				continue
+			}
+			if trans.Amount <= 0 || trans.Amount > loan.Outstanding {
+				return fiber.StatusBadRequest, fiber.Map{
+					"error":       "Repayment must be positive and not exceed the outstanding amount",
+					"outstanding": loan.Outstanding,
+				}
+			}
+			return 0, nil
+		}
+		return fiber.StatusBadRequest, fiber.Map{
+			"error": "loanId must refer to a loan with a repayment plan",
+		}
+
+	case models.EmpTransLoan:
+		if trans.Amount <= 0 {
+			return fiber.StatusBadRequest, fiber.Map{
+				"error": "Loan amount must be positive",
+			}
+		}
+		if trans.Repayment != nil {
+			if err := services.ValidateLoanRepaymentPlan(trans.Repayment); err != nil {
+				return fiber.StatusBadRequest, fiber.Map{
+					"error": err.Error(),
+				}
+			}
+		}
+
+		at := time.Now()
+		if t, ok := services.ParseTimestamp(trans.Date); ok {
+			at = t
+		}
+		expected, limit, limited, err := loanLimit(store, calculator, trans.EmployeeID, at)
+		if err != nil {
+			return fiber.StatusInternalServerError, fiber.Map{
+				"error": err.Error(),
+			}
+		}
+		if !limited {
+			return 0, nil
+		}
+
+		outstanding := services.OutstandingLoans(services.EmployeeLoans(existing))
+		if outstanding+trans.Amount > limit {
+			return fiber.StatusConflict, fiber.Map{
+				"error":            "Loan exceeds the limit for the employee's expected earnings",
+				"outstanding":      outstanding,
+				"expectedEarnings": expected,
+				"limit":            limit,
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+// settleLoanRepayments withholds loan repayments from the earnings of each
+// employee in the report of a payroll period being closed. It returns the
+// number of repayments recorded.
+func settleLoanRepayments(store *storage.JSONStore, cache *storage.Cache, period *models.PayrollPeriod, r services.Period, report []models.SalaryReportData) (int, error) {
+	count := 0
+	for _, data := range report {
+		transactions, err := store.GetEmployeeTransactions(data.EmployeeID)
+		if err != nil {
+			return count, err
+		}
+
+		repayments := services.ScheduleLoanRepayments(transactions, data.TotalEarnings+data.Bonuses-data.Fines, period, r)
+		if len(repayments) == 0 {
+			continue
+		}
+		for i := range repayments {
+			repayments[i].ID = fmt.Sprintf("trans_%d_%s", time.Now().UnixMilli(), generateRandomString(7))
+		}
+
+		if err := store.SaveEmployeeTransactions(data.EmployeeID, append(transactions, repayments...)); err != nil {
+			return count, err
+		}
+		cache.InvalidateEmployeeTransactions(data.EmployeeID)
+		count += len(repayments)
+	}
+	return count, nil
+}
+
+// removeLoanRepayments deletes the repayments recorded when the payroll period
+// was closed, so closing it again withholds them afresh
+func removeLoanRepayments(store *storage.JSONStore, cache *storage.Cache, periodID string) error {
+	employees, err := store.GetAllEmployees()
+	if err != nil {
+		return err
+	}
+
+	for _, emp := range employees {
+		transactions, err := store.GetEmployeeTransactions(emp.ID)
+		if err != nil {
+			return err
+		}
+
+		kept := make([]models.EmployeeTransaction, 0, len(transactions))
+		for _, trans := range transactions {
+			if trans.Type == models.EmpTransLoanRepayment && trans.PayrollPeriodID == periodID {
+				continue
+			}
+			kept = append(kept, trans)
+		}
+		if len(kept) == len(transactions) {
+			continue
+		}
+
+		if err := store.SaveEmployeeTransactions(emp.ID, kept); err != nil {
+			return err
+		}
+		cache.InvalidateEmployeeTransactions(emp.ID)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type PayrollPeriodHandler struct {
+	store      *storage.JSONStore
+	cache      *storage.Cache
+	calculator *services.SalaryCalculator
+}
+
+func NewPayrollPeriodHandler(store *storage.JSONStore, cache *storage.Cache) *PayrollPeriodHandler {
+	return &PayrollPeriodHandler{
+		store:      store,
+		cache:      cache,
+		calculator: services.NewSalaryCalculator(),
+	}
+}
+
+// ReopenPayrollPeriodRequest is the body of POST /api/payroll-periods/:id/reopen
+type ReopenPayrollPeriodRequest struct {
+	Reason string `json:"reason"`
+}
+
+// GetAll handles GET /api/payroll-periods
+// Snapshots are omitted from the list to keep it small.
+func (h *PayrollPeriodHandler) GetAll(c *fiber.Ctx) error {
+	periods, err := h.store.GetAllPayrollPeriods()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get payroll periods",
+		})
+	}
+
+	result := make([]models.PayrollPeriod, len(periods))
+	for i, period := range periods {
+		period.Snapshot = nil
+		result[i] = period
+	}
+
+	return c.JSON(result)
+}
+
+// GetByID handles GET /api/payroll-periods/:id
+func (h *PayrollPeriodHandler) GetByID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	period, err := h.store.GetPayrollPeriodByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payroll period not found",
+		})
+	}
+
+	return c.JSON(period)
+}
+
+// Create handles POST /api/payroll-periods
+func (h *PayrollPeriodHandler) Create(c *fiber.Ctx) error {
+	var period models.PayrollPeriod
+	if err := c.BodyParser(&period); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if _, err := services.PayrollPeriodRange(&period); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	existing, err := h.store.GetAllPayrollPeriods()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get payroll periods",
+		})
+	}
+
+	for i := range existing {
+		if services.PayrollPeriodsOverlap(&period, &existing[i]) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":           "Payroll period overlaps an existing period",
+				"payrollPeriodId": existing[i].ID,
+			})
+		}
+	}
+
+	period.ID = fmt.Sprintf("payroll_%d_%s", time.Now().UnixMilli(), generateRandomString(7))
+	period.Status = models.PayrollPeriodOpen
+	period.CreatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	period.ClosedAt = ""
+	period.ClosedBy = ""
+	period.SchemeIDs = nil
+	period.Snapshot = nil
+
+	if err := h.store.SavePayrollPeriod(&period); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save payroll period",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(period)
+}
+
+// Delete handles DELETE /api/payroll-periods/:id
+// Only open periods can be deleted.
+func (h *PayrollPeriodHandler) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	period, err := h.store.GetPayrollPeriodByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payroll period not found",
+		})
+	}
+
+	if period.Status == models.PayrollPeriodClosed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Closed payroll period must be reopened before deletion",
+		})
+	}
+
+	if err := h.store.DeletePayrollPeriod(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete payroll period",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Payroll period deleted successfully",
+	})
+}
+
+// Close handles POST /api/payroll-periods/:id/close
+func (h *PayrollPeriodHandler) Close(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	period, err := h.store.GetPayrollPeriodByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payroll period not found",
+		})
+	}
+
+	if period.Status == models.PayrollPeriodClosed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Payroll period is already closed",
+		})
+	}
+
+	r, err := services.PayrollPeriodRange(period)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	report, err := buildSalaryReport(h.store, h.calculator, r)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Loan repayments are withheld from the period earnings and frozen with
+	// them; if the period cannot be closed they are taken back, so closing it
+	// again does not withhold them twice
+	repayments, err := settleLoanRepayments(h.store, h.cache, period, r, report)
+	if err != nil {
+		h.undoLoanRepayments(period.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record loan repayments",
+		})
+	}
+	if repayments > 0 {
+		report, err = buildSalaryReport(h.store, h.calculator, r)
+		if err != nil {
+			h.undoLoanRepayments(period.ID)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	schemeIDs, err := h.usedSchemeIDs(period, report)
+	if err != nil {
+		h.undoLoanRepayments(period.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get employees",
+		})
+	}
+
+	period.Status = models.PayrollPeriodClosed
+	period.ClosedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	period.ClosedBy = currentEmployeeID(c)
+	period.SchemeIDs = schemeIDs
+	period.Snapshot = report
+
+	if err := h.store.SavePayrollPeriod(period); err != nil {
+		h.undoLoanRepayments(period.ID)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save payroll period",
+		})
+	}
+
+	recordAudit(h.store, c, "payrollPeriod.close", "payrollPeriod", period.ID, "", map[string]interface{}{
+		"from":           period.From,
+		"to":             period.To,
+		"loanRepayments": repayments,
+	})
+
+	return c.JSON(period)
+}
+
+// undoLoanRepayments takes back the repayments recorded by a close that failed
+func (h *PayrollPeriodHandler) undoLoanRepayments(periodID string) {
+	if err := removeLoanRepayments(h.store, h.cache, periodID); err != nil {
+		log.Printf("Failed to undo loan repayments of payroll period %s: %v", periodID, err)
+	}
+}
+
+// Reopen handles POST /api/payroll-periods/:id/reopen
+// Reopening is restricted to the manager account and always audited.
+func (h *PayrollPeriodHandler) Reopen(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if !isAdmin(h.store, c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only an administrator can reopen a payroll period",
+		})
+	}
+
+	var req ReopenPayrollPeriodRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason is required",
+		})
+	}
+
+	period, err := h.store.GetPayrollPeriodByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payroll period not found",
+		})
+	}
+
+	if period.Status != models.PayrollPeriodClosed {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Payroll period is not closed",
+		})
+	}
+
+	previousClosedAt := period.ClosedAt
+	previousClosedBy := period.ClosedBy
+
+	if err := removeLoanRepayments(h.store, h.cache, period.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove loan repayments",
+		})
+	}
+
+	// The snapshot and loan repayments are discarded; closing again
+	// freezes a fresh report
+	period.Status = models.PayrollPeriodOpen
+	period.ReopenedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	period.ReopenedBy = currentEmployeeID(c)
+	period.ClosedAt = ""
+	period.ClosedBy = ""
+	period.SchemeIDs = nil
+	period.Snapshot = nil
+
+	if err := h.store.SavePayrollPeriod(period); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save payroll period",
+		})
+	}
+
+	recordAudit(h.store, c, "payrollPeriod.reopen", "payrollPeriod", period.ID, req.Reason, map[string]interface{}{
+		"from":             period.From,
+		"to":               period.To,
+		"previousClosedAt": previousClosedAt,
+		"previousClosedBy": previousClosedBy,
+	})
+
+	return c.JSON(period)
+}
+
+// usedSchemeIDs returns the schemes assigned during the period to employees
+// who have earnings in the report
+func (h *PayrollPeriodHandler) usedSchemeIDs(period *models.PayrollPeriod, report []models.SalaryReportData) ([]string, error) {
+	employees, err := h.store.GetAllEmployees()
+	if err != nil {
+		return nil, err
+	}
+
+	employeeMap := make(map[string]*models.Employee, len(employees))
+	for i := range employees {
+		employeeMap[employees[i].ID] = &employees[i]
+	}
+
+	seen := make(map[string]bool)
+	var schemeIDs []string
+	for _, data := range report {
+		emp, ok := employeeMap[data.EmployeeID]
+		if !ok || (len(data.Breakdown) == 0 && len(data.Adjustments) == 0) {
+			continue
+		}
+		for _, schemeID := range services.SchemeIDsInPeriod(emp, period.From, period.To) {
+			if schemeID != "" && !seen[schemeID] {
+				seen[schemeID] = true
+				schemeIDs = append(schemeIDs, schemeID)
+			}
+		}
+	}
+
+	sort.Strings(schemeIDs)
+	return schemeIDs, nil
+}
+
+// findLockingPayrollPeriod returns the closed payroll period covering any of
+// the given timestamps
+func findLockingPayrollPeriod(store *storage.JSONStore, timestamps ...string) (*models.PayrollPeriod, error) {
+	periods, err := store.GetAllPayrollPeriods()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ts := range timestamps {
+		if period := services.FindClosedPayrollPeriod(periods, ts); period != nil {
+			return period, nil
+		}
+	}
+	return nil, nil
+}
+
+// payrollLockedResponse rejects a change that falls into a closed payroll period
+func payrollLockedResponse(c *fiber.Ctx, period *models.PayrollPeriod) error {
+	return c.Status(fiber.StatusConflict).JSON(payrollLockedBody(period))
+}
+
+func payrollLockedBody(period *models.PayrollPeriod) fiber.Map {
+	return fiber.Map{
+		"error":           fmt.Sprintf("Payroll period %s - %s is closed", period.From, period.To),
+		"payrollPeriodId": period.ID,
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type PayslipHandler struct {
+	store      *storage.JSONStore
+	cache      *storage.Cache
+	calculator *services.SalaryCalculator
+}
+
+func NewPayslipHandler(store *storage.JSONStore, cache *storage.Cache) *PayslipHandler {
+	return &PayslipHandler{
+		store:      store,
+		cache:      cache,
+		calculator: services.NewSalaryCalculator(),
+	}
+}
+
+// Get handles GET /api/employees/:id/payslip?from=YYYY-MM-DD&to=YYYY-MM-DD&format=html|pdf
+// The payslip is an HTML page by default. With periodId the frozen report of
+// a closed payroll period is used.
+func (h *PayslipHandler) Get(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	employee, err := h.store.GetEmployeeByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Employee not found",
+		})
+	}
+
+	format := c.Query("format", "html")
+	if format != "html" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be html or pdf",
+		})
+	}
+
+	payslip := services.Payslip{
+		EmployeeName: employee.FullName,
+		GeneratedAt:  time.Now(),
+	}
+
+	var report []models.SalaryReportData
+	if periodID := c.Query("periodId"); periodID != "" {
+		period, err := h.store.GetPayrollPeriodByID(periodID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Payroll period not found",
+			})
+		}
+		payslip.From, payslip.To = period.From, period.To
+
+		if period.Status == models.PayrollPeriodClosed {
+			report = period.Snapshot
+		} else {
+			r, err := services.PayrollPeriodRange(period)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			report, err = buildSalaryReport(h.store, h.calculator, r)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+	} else {
+		period, err := parsePeriodQuery(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		payslip.From, payslip.To = c.Query("from"), c.Query("to")
+
+		report, err = buildSalaryReport(h.store, h.calculator, period)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	payslip.Data = models.SalaryReportData{EmployeeID: employee.ID, EmployeeName: employee.FullName}
+	for _, data := range report {
+		if data.EmployeeID == employee.ID {
+			payslip.Data = data
+			break
+		}
+	}
+
+	if format == "pdf" {
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, payslipFileName(employee.ID, payslip.From, payslip.To)))
+		return c.Send(services.RenderPayslipPDF(&payslip))
+	}
+
+	html, err := services.RenderPayslipHTML(&payslip)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to render payslip",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(html)
+}
+
+func payslipFileName(employeeID, from, to string) string {
+	name := "payslip_" + employeeID
+	if from != "" {
+		name += "_" + from
+	}
+	if to != "" {
+		name += "_" + to
+	}
+	return name + ".pdf"
+}
@@ -31,7 +31,18 @@ func (h *PriceListHandler) Get(c *fiber.Ctx) error {
 	return c.JSON(config)
 }
 
-// Update handles POST /api/retail-price-list
+// retailPriceListResponse is the saved retail price list with the rate
+// schemes it drifted from
+type retailPriceListResponse struct {
+	*models.RetailPriceConfig
+	SchemeDrift []models.SalarySchemeDrift `json:"schemeDrift,omitempty"`
+}
+
+// Update handles POST /api/retail-price-list?propagateRenames=true&effectiveFrom=YYYY-MM-DD
+// Services get stable IDs; with propagateRenames renamed services are renamed
+// in the rate schemes using the retail price list. The prices are saved as a
+// version effective from effectiveFrom, today by default; a later date
+// schedules them without changing the prices in effect.
 func (h *PriceListHandler) Update(c *fiber.Ctx) error {
 	var config models.RetailPriceConfig
 	if err := c.BodyParser(&config); err != nil {
@@ -48,6 +59,23 @@ func (h *PriceListHandler) Update(c *fiber.Ctx) error {
 		config.AdditionalPriceList = []models.PriceListItem{}
 	}
 
+	existing, err := h.store.GetRetailPriceConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get retail price list",
+		})
+	}
+	effectiveFrom, ok := priceListEffectiveFrom(c)
+	if !ok {
+		return invalidEffectiveFromResponse(c)
+	}
+
+	before := append(append([]models.PriceListItem{}, existing.MainPriceList...), existing.AdditionalPriceList...)
+	assignServiceIDs(before, config.MainPriceList, config.AdditionalPriceList)
+
+	config.Versions, config.MainPriceList, config.AdditionalPriceList = versionPriceList(existing.Versions,
+		existing.MainPriceList, existing.AdditionalPriceList, config.MainPriceList, config.AdditionalPriceList, effectiveFrom)
+
 	if err := h.store.SaveRetailPriceConfig(&config); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save retail price list",
@@ -56,7 +84,15 @@ func (h *PriceListHandler) Update(c *fiber.Ctx) error {
 
 	h.cache.InvalidateRetailPriceConfig()
 
-	return c.JSON(config)
+	drift, err := syncRateSchemes(h.store, h.cache,
+		priceListState{retail: existing}, priceListState{retail: &config}, c.QueryBool("propagateRenames"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sync salary schemes",
+		})
+	}
+
+	return c.JSON(retailPriceListResponse{RetailPriceConfig: &config, SchemeDrift: drift})
 }
 
 func (h *PriceListHandler) getRetailPriceConfig() (*models.RetailPriceConfig, error) {
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type PriceListImportHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+	// mu serializes confirmations so an import is never applied twice
+	mu sync.Mutex
+}
+
+func NewPriceListImportHandler(store *storage.JSONStore, cache *storage.Cache) *PriceListImportHandler {
+	return &PriceListImportHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// priceListContents is a price list CSV files are exported from and
+// imported into, as it is now
+type priceListContents struct {
+	source         models.RateSource
+	ownerName      string
+	main           []models.PriceListItem
+	additional     []models.PriceListItem
+	withAdditional bool
+}
+
+// loadPriceList reads the retail list, a counter agent's lists or an
+// aggregator's named list, the list chosen by hand when no name is given
+func loadPriceList(store *storage.JSONStore, source models.RateSource) (*priceListContents, int, fiber.Map) {
+	switch source.Type {
+	case models.RateSourceRetail:
+		config, err := store.GetRetailPriceConfig()
+		if err != nil {
+			return nil, fiber.StatusInternalServerError, fiber.Map{
+				"error": "Failed to get retail price list",
+			}
+		}
+		return &priceListContents{
+			source:         models.RateSource{Type: models.RateSourceRetail, ID: "retail"},
+			ownerName:      "Розница",
+			main:           config.MainPriceList,
+			additional:     config.AdditionalPriceList,
+			withAdditional: true,
+		}, 0, nil
+
+	case models.RateSourceCounterAgent:
+		agent, err := store.GetCounterAgentByID(source.ID)
+		if err != nil {
+			return nil, fiber.StatusNotFound, fiber.Map{
+				"error": "Counter agent not found",
+			}
+		}
+		return &priceListContents{
+			source:         models.RateSource{Type: models.RateSourceCounterAgent, ID: agent.ID},
+			ownerName:      agent.Name,
+			main:           agent.PriceList,
+			additional:     agent.AdditionalPriceList,
+			withAdditional: true,
+		}, 0, nil
+
+	case models.RateSourceAggregator:
+		agg, err := store.GetAggregatorByID(source.ID)
+		if err != nil {
+			return nil, fiber.StatusNotFound, fiber.Map{
+				"error": "Aggregator not found",
+			}
+		}
+		name := source.PriceListName
+		if name == "" {
+			name = agg.ActivePriceListName
+		}
+		for _, list := range agg.PriceLists {
+			if list.Name == name {
+				return &priceListContents{
+					source:    models.RateSource{Type: models.RateSourceAggregator, ID: agg.ID, PriceListName: name},
+					ownerName: agg.Name,
+					main:      list.Services,
+				}, 0, nil
+			}
+		}
+		return nil, fiber.StatusNotFound, fiber.Map{
+			"error": "Price list not found",
+		}
+	}
+
+	return nil, fiber.StatusBadRequest, fiber.Map{
+		"error": "type must be retail, counterAgent or aggregator",
+	}
+}
+
+// sendPriceListCSV answers with the price list as a CSV attachment
+func sendPriceListCSV(c *fiber.Ctx, store *storage.JSONStore, source models.RateSource, fileName string) error {
+	list, status, body := loadPriceList(store, source)
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
+	data, err := services.PriceListCSV(list.main, list.additional)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to render price list",
+		})
+	}
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	return c.Send(data)
+}
+
+// ExportRetail handles GET /api/retail-price-list/csv
+func (h *PriceListImportHandler) ExportRetail(c *fiber.Ctx) error {
+	return sendPriceListCSV(c, h.store, models.RateSource{Type: models.RateSourceRetail}, "price_list_retail.csv")
+}
+
+// ExportCounterAgent handles GET /api/counter-agents/:id/price-list/csv
+func (h *PriceListImportHandler) ExportCounterAgent(c *fiber.Ctx) error {
+	id := c.Params("id")
+	return sendPriceListCSV(c, h.store, models.RateSource{Type: models.RateSourceCounterAgent, ID: id},
+		"price_list_"+id+".csv")
+}
+
+// ExportAggregator handles GET /api/aggregators/:id/price-lists/csv?name=
+// name picks the price list; the list chosen by hand by default.
+func (h *PriceListImportHandler) ExportAggregator(c *fiber.Ctx) error {
+	id := c.Params("id")
+	return sendPriceListCSV(c, h.store, models.RateSource{Type: models.RateSourceAggregator, ID: id, PriceListName: c.Query("name")},
+		"price_list_"+id+".csv")
+}
+
+// GetAll handles GET /api/price-list-imports?type=&id=
+func (h *PriceListImportHandler) GetAll(c *fiber.Ctx) error {
+	imports, err := h.store.GetAllPriceListImports()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get price list imports",
+		})
+	}
+
+	sourceType, sourceID := c.Query("type"), c.Query("id")
+	result := []models.PriceListImport{}
+	for _, imp := range imports {
+		if sourceType != "" && string(imp.Source.Type) != sourceType {
+			continue
+		}
+		if sourceID != "" && imp.Source.ID != sourceID {
+			continue
+		}
+		result = append(result, imp)
+	}
+
+	return c.JSON(result)
+}
+
+// GetByID handles GET /api/price-list-imports/:id
+func (h *PriceListImportHandler) GetByID(c *fiber.Ctx) error {
+	imp, err := h.store.GetPriceListImportByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Price list import not found",
+		})
+	}
+
+	return c.JSON(imp)
+}
+
+// Create handles POST /api/price-list-imports?type=&id=&priceListName=&effectiveFrom=YYYY-MM-DD
+// The CSV price list is uploaded as the multipart field "file" or as the raw
+// body and matched against the services of the list it replaces: the retail
+// list, a counter agent's lists or an aggregator's named list. The import
+// previews the services added, changed and removed; nothing is saved to the
+// price list until it is confirmed.
+func (h *PriceListImportHandler) Create(c *fiber.Ctx) error {
+	list, status, body := loadPriceList(h.store, models.RateSource{
+		Type:          models.RateSourceType(c.Query("type", c.FormValue("type"))),
+		ID:            c.Query("id", c.FormValue("id")),
+		PriceListName: c.Query("priceListName", c.FormValue("priceListName")),
+	})
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
+	// Like the list, the date may come with the multipart form
+	effectiveFrom := c.Query("effectiveFrom", c.FormValue("effectiveFrom"))
+	if _, err := time.Parse("2006-01-02", effectiveFrom); effectiveFrom != "" && err != nil {
+		return invalidEffectiveFromResponse(c)
+	}
+
+	data, fileName, err := uploadedFile(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read uploaded file",
+		})
+	}
+
+	rows, err := services.ParsePriceListCSV(data)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	main, additional, err := services.MergePriceListCSV(rows, list.main, list.additional, list.withAdditional)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	imp := models.PriceListImport{
+		ID:            fmt.Sprintf("plimp_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		Source:        list.source,
+		SourceName:    list.ownerName,
+		FileName:      fileName,
+		ImportedAt:    time.Now().Format(time.RFC3339),
+		EffectiveFrom: effectiveFrom,
+		Status:        models.PriceListImportPending,
+		Main:          main,
+		Additional:    additional,
+		Diff:          importDiff(list, main, additional),
+	}
+
+	if err := h.store.SavePriceListImport(&imp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save price list import",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(imp)
+}
+
+// Confirm handles POST /api/price-list-imports/:id/confirm?propagateRenames=true
+// Saves the imported services as the price list, the same way saving the
+// list by hand does: as a version effective from the import's effectiveFrom,
+// with renames optionally propagated to the rate schemes. An import whose
+// price list changed since the preview is refused and must be uploaded again.
+func (h *PriceListImportHandler) Confirm(c *fiber.Ctx) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	imp, err := h.store.GetPriceListImportByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Price list import not found",
+		})
+	}
+	if imp.Status == models.PriceListImportApplied {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Price list import is already applied",
+		})
+	}
+
+	list, status, body := loadPriceList(h.store, imp.Source)
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+	// Compared as JSON, the form the import was stored in
+	current, _ := json.Marshal(importDiff(list, imp.Main, imp.Additional))
+	previewed, _ := json.Marshal(imp.Diff)
+	if !bytes.Equal(current, previewed) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Price list changed since the import, upload the file again",
+		})
+	}
+
+	effectiveFrom := imp.EffectiveFrom
+	if effectiveFrom == "" {
+		effectiveFrom = services.LocalDate(time.Now())
+	}
+
+	drift, err := h.apply(imp, effectiveFrom, c.QueryBool("propagateRenames"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save price list",
+		})
+	}
+
+	imp.Status = models.PriceListImportApplied
+	imp.AppliedAt = time.Now().Format(time.RFC3339)
+	imp.EffectiveFrom = effectiveFrom
+	if err := h.store.SavePriceListImport(imp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save price list import",
+		})
+	}
+
+	recordAudit(h.store, c, "priceListImport.confirm", "priceListImport", imp.ID, "", map[string]interface{}{
+		"source":        imp.Source,
+		"effectiveFrom": effectiveFrom,
+		"added":         len(imp.Diff.Added),
+		"changed":       len(imp.Diff.Changed),
+		"removed":       len(imp.Diff.Removed),
+	})
+
+	return c.JSON(fiber.Map{
+		"import":      imp,
+		"schemeDrift": drift,
+	})
+}
+
+// apply saves the imported services to their price list and syncs the rate
+// schemes using it
+func (h *PriceListImportHandler) apply(imp *models.PriceListImport, effectiveFrom string, propagate bool) ([]models.SalarySchemeDrift, error) {
+	var before, after priceListState
+
+	switch imp.Source.Type {
+	case models.RateSourceRetail:
+		existing, err := h.store.GetRetailPriceConfig()
+		if err != nil {
+			return nil, err
+		}
+		config := *existing
+		assignServiceIDs(append(append([]models.PriceListItem{}, existing.MainPriceList...), existing.AdditionalPriceList...),
+			imp.Main, imp.Additional)
+		config.Versions, config.MainPriceList, config.AdditionalPriceList = versionPriceList(existing.Versions,
+			existing.MainPriceList, existing.AdditionalPriceList, imp.Main, imp.Additional, effectiveFrom)
+		if err := h.store.SaveRetailPriceConfig(&config); err != nil {
+			return nil, err
+		}
+		h.cache.InvalidateRetailPriceConfig()
+		before, after = priceListState{retail: existing}, priceListState{retail: &config}
+
+	case models.RateSourceCounterAgent:
+		existing, err := h.store.GetCounterAgentByID(imp.Source.ID)
+		if err != nil {
+			return nil, err
+		}
+		agent := *existing
+		assignServiceIDs(append(append([]models.PriceListItem{}, existing.PriceList...), existing.AdditionalPriceList...),
+			imp.Main, imp.Additional)
+		agent.PriceListVersions, agent.PriceList, agent.AdditionalPriceList = versionPriceList(existing.PriceListVersions,
+			existing.PriceList, existing.AdditionalPriceList, imp.Main, imp.Additional, effectiveFrom)
+		if err := h.store.SaveCounterAgent(&agent); err != nil {
+			return nil, err
+		}
+		h.cache.InvalidateCounterAgents()
+		before = priceListState{counterAgents: []models.CounterAgent{*existing}}
+		after = priceListState{counterAgents: []models.CounterAgent{agent}}
+
+	case models.RateSourceAggregator:
+		existing, err := h.store.GetAggregatorByID(imp.Source.ID)
+		if err != nil {
+			return nil, err
+		}
+		agg := *existing
+		agg.PriceLists = append([]models.NamedPriceList{}, existing.PriceLists...)
+		for i := range agg.PriceLists {
+			if agg.PriceLists[i].Name == imp.Source.PriceListName {
+				agg.PriceLists[i].Services = imp.Main
+			}
+		}
+		assignAggregatorServiceIDs(existing, &agg)
+		versionAggregatorPriceLists(existing, &agg, effectiveFrom)
+		if err := h.store.SaveAggregator(&agg); err != nil {
+			return nil, err
+		}
+		h.cache.InvalidateAggregators()
+		before = priceListState{aggregators: []models.Aggregator{*existing}}
+		after = priceListState{aggregators: []models.Aggregator{agg}}
+	}
+
+	return syncRateSchemes(h.store, h.cache, before, after, propagate)
+}
+
+// importDiff compares the imported services with the price list as it is
+func importDiff(list *priceListContents, main, additional []models.PriceListItem) models.PriceListDiff {
+	return services.DiffPriceListVersions(
+		&models.PriceListVersion{Main: list.main, Additional: list.additional},
+		&models.PriceListVersion{Main: main, Additional: additional},
+	)
+}
+
+// uploadedFile returns the multipart field "file", or the raw body with the
+// name given in ?fileName= when no file is attached
+func uploadedFile(c *fiber.Ctx) ([]byte, string, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Body(), c.Query("fileName"), nil
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, header.Filename, nil
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type PriceListVersionHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewPriceListVersionHandler(store *storage.JSONStore, cache *storage.Cache) *PriceListVersionHandler {
+	return &PriceListVersionHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// GetRetailVersions handles GET /api/retail-price-list/versions
+func (h *PriceListVersionHandler) GetRetailVersions(c *fiber.Ctx) error {
+	config, err := h.store.GetRetailPriceConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get retail price list",
+		})
+	}
+
+	return priceListVersionsResponse(c, config.Versions)
+}
+
+// GetCounterAgentVersions handles GET /api/counter-agents/:id/price-list-versions
+func (h *PriceListVersionHandler) GetCounterAgentVersions(c *fiber.Ctx) error {
+	agent, err := h.store.GetCounterAgentByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Counter agent not found",
+		})
+	}
+
+	return priceListVersionsResponse(c, agent.PriceListVersions)
+}
+
+// GetAggregatorVersions handles GET /api/aggregators/:id/price-list-versions?name=
+// name picks the price list; the list chosen by hand by default.
+func (h *PriceListVersionHandler) GetAggregatorVersions(c *fiber.Ctx) error {
+	agg, err := h.store.GetAggregatorByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Aggregator not found",
+		})
+	}
+
+	name := c.Query("name", agg.ActivePriceListName)
+	for _, list := range agg.PriceLists {
+		if list.Name == name {
+			return priceListVersionsResponse(c, list.Versions)
+		}
+	}
+
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": "Price list not found",
+	})
+}
+
+// priceListVersionsResponse answers a versions request: with ?at= the version
+// in effect at that date or timestamp, with ?fromVersion=&toVersion= the diff
+// between two versions, otherwise all versions
+func priceListVersionsResponse(c *fiber.Ctx, versions []models.PriceListVersion) error {
+	if at := c.Query("at"); at != "" {
+		t, ok := services.ParseTimestamp(at)
+		if !ok {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "at must be a date or an ISO timestamp",
+			})
+		}
+		date := services.LocalDate(t)
+		if len(at) == len("2006-01-02") {
+			date = at
+		}
+		v := models.PriceListVersionAt(versions, date)
+		if v == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No version in effect at that time",
+			})
+		}
+		return c.JSON(v)
+	}
+
+	if c.Query("fromVersion") != "" || c.Query("toVersion") != "" {
+		fromNumber, err1 := strconv.Atoi(c.Query("fromVersion"))
+		toNumber, err2 := strconv.Atoi(c.Query("toVersion"))
+		if err1 != nil || err2 != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "fromVersion and toVersion must both be version numbers",
+			})
+		}
+		from := services.FindPriceListVersion(versions, fromNumber)
+		to := services.FindPriceListVersion(versions, toNumber)
+		if from == nil || to == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Version not found",
+			})
+		}
+		return c.JSON(services.DiffPriceListVersions(from, to))
+	}
+
+	if versions == nil {
+		versions = []models.PriceListVersion{}
+	}
+	return c.JSON(versions)
+}
+
+// priceListEffectiveFrom reads ?effectiveFrom=YYYY-MM-DD, the date a saved
+// price list takes effect; today by default
+func priceListEffectiveFrom(c *fiber.Ctx) (string, bool) {
+	effectiveFrom := c.Query("effectiveFrom")
+	if effectiveFrom == "" {
+		return services.LocalDate(time.Now()), true
+	}
+	_, err := time.Parse("2006-01-02", effectiveFrom)
+	return effectiveFrom, err == nil
+}
+
+func invalidEffectiveFromResponse(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "effectiveFrom must be a date in YYYY-MM-DD format",
+	})
+}
+
+// versionPriceList records the saved services as a version effective from
+// the given date and returns the versions and the services in effect today
+func versionPriceList(versions []models.PriceListVersion, liveMain, liveAdditional, main, additional []models.PriceListItem, effectiveFrom string) ([]models.PriceListVersion, []models.PriceListItem, []models.PriceListItem) {
+	now := time.Now()
+	versions, current := services.VersionPriceList(versions,
+		models.PriceListVersion{Main: liveMain, Additional: liveAdditional, SavedAt: now.Format(time.RFC3339)},
+		models.PriceListVersion{Main: main, Additional: additional, SavedAt: now.Format(time.RFC3339)},
+		effectiveFrom, services.LocalDate(now))
+	return versions, current.Main, current.Additional
+}
+
+// versionAggregatorPriceLists versions every price list of a saved
+// aggregator, carrying the versions over from the list of the same name.
+// New lists are in effect since the beginning.
+func versionAggregatorPriceLists(existing, agg *models.Aggregator, effectiveFrom string) {
+	for i := range agg.PriceLists {
+		list := &agg.PriceLists[i]
+		var versions []models.PriceListVersion
+		var live []models.PriceListItem
+		listEffectiveFrom := ""
+		if existing != nil {
+			for _, old := range existing.PriceLists {
+				if old.Name == list.Name {
+					versions, live, listEffectiveFrom = old.Versions, old.Services, effectiveFrom
+				}
+			}
+		}
+		list.Versions, list.Services, _ = versionPriceList(versions, live, nil, list.Services, nil, listEffectiveFrom)
+	}
+}
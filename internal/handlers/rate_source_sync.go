@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+// priceListState holds the price lists rate schemes can point at, as they
+// were before or after a save. Only the lists being saved are set.
+type priceListState struct {
+	retail        *models.RetailPriceConfig
+	aggregators   []models.Aggregator
+	counterAgents []models.CounterAgent
+}
+
+func (s priceListState) items(source *models.RateSource) []models.PriceListItem {
+	return services.RateSourceItems(source, s.retail, s.aggregators, s.counterAgents)
+}
+
+// covers reports whether the state holds the price list of the source
+func (s priceListState) covers(source *models.RateSource) bool {
+	switch source.Type {
+	case models.RateSourceRetail:
+		return s.retail != nil
+	case models.RateSourceAggregator:
+		for _, agg := range s.aggregators {
+			if agg.ID == source.ID {
+				return true
+			}
+		}
+	case models.RateSourceCounterAgent:
+		for _, agent := range s.counterAgents {
+			if agent.ID == source.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// assignServiceIDs gives every service of a saved price list a stable ID.
+// Services keep the ID they were sent with or the ID of the service of the
+// same name in the previous version of the list. The editors do not send the
+// per-employee chemical norm, so a service without one keeps the norm the
+// service of its ID had.
+func assignServiceIDs(before []models.PriceListItem, lists ...[]models.PriceListItem) {
+	ids := make(map[string]string, len(before))
+	norms := make(map[string]float64, len(before))
+	for _, item := range before {
+		if item.ServiceID != "" {
+			ids[item.ServiceName] = item.ServiceID
+			norms[item.ServiceID] = item.EmployeeChemicalNorm
+		}
+	}
+
+	for _, items := range lists {
+		for i := range items {
+			if items[i].ServiceID == "" {
+				if id, ok := ids[items[i].ServiceName]; ok {
+					items[i].ServiceID = id
+				} else {
+					items[i].ServiceID = fmt.Sprintf("svc_%d_%s", time.Now().UnixMilli(), generateRandomString(7))
+					ids[items[i].ServiceName] = items[i].ServiceID
+				}
+			}
+			if items[i].EmployeeChemicalNorm == 0 {
+				items[i].EmployeeChemicalNorm = norms[items[i].ServiceID]
+			}
+		}
+	}
+}
+
+// assignAggregatorServiceIDs assigns stable IDs list by list, carrying them
+// over from the previous list of the same name
+func assignAggregatorServiceIDs(existing *models.Aggregator, agg *models.Aggregator) {
+	for i := range agg.PriceLists {
+		var before []models.PriceListItem
+		if existing != nil {
+			before = services.AggregatorPriceList(existing, agg.PriceLists[i].Name)
+		}
+		assignServiceIDs(before, agg.PriceLists[i].Services)
+	}
+}
+
+// syncRateSchemes reports the rate schemes whose rate source price list
+// drifted from their rates in a save. With propagate, renamed services are
+// renamed in the schemes too, as a new scheme version effective today;
+// schemes locked by a closed payroll period are only reported.
+func syncRateSchemes(store *storage.JSONStore, cache *storage.Cache, before, after priceListState, propagate bool) ([]models.SalarySchemeDrift, error) {
+	schemes, err := store.GetAllSalarySchemes()
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []models.PayrollPeriod
+	if propagate {
+		if periods, err = store.GetAllPayrollPeriods(); err != nil {
+			return nil, err
+		}
+	}
+
+	today := services.LocalDate(time.Now())
+	saved := false
+	var drifts []models.SalarySchemeDrift
+
+	for i := range schemes {
+		scheme := &schemes[i]
+		if scheme.RateSource == nil || !after.covers(scheme.RateSource) {
+			continue
+		}
+
+		afterItems := after.items(scheme.RateSource)
+		drift, ok := services.DetectSchemeDrift(scheme, before.items(scheme.RateSource), afterItems)
+		if !ok {
+			continue
+		}
+
+		if propagate && len(drift.Renamed) > 0 &&
+			services.FindClosedPayrollPeriodForSchemeVersion(periods, scheme.ID, today) == nil {
+			updates := *scheme
+			updates.PreviousVersions = nil
+			updates.Rates = services.LinkRateServiceIDs(services.PropagateRenames(scheme.Rates, drift.Renamed), afterItems)
+
+			updated := services.NewSchemeVersion(scheme, updates, today)
+			if err := store.SaveSalaryScheme(&updated); err != nil {
+				return nil, err
+			}
+			drift.Propagated = true
+			saved = true
+		}
+
+		drifts = append(drifts, drift)
+	}
+
+	if saved {
+		cache.InvalidateSalarySchemes()
+	}
+
+	return drifts, nil
+}
+
+// rateSourceItems loads the price list a rate source points at
+func rateSourceItems(store *storage.JSONStore, source *models.RateSource) ([]models.PriceListItem, error) {
+	if source == nil {
+		return nil, nil
+	}
+
+	var state priceListState
+	switch source.Type {
+	case models.RateSourceRetail:
+		retail, err := store.GetRetailPriceConfig()
+		if err != nil {
+			return nil, err
+		}
+		state.retail = retail
+	case models.RateSourceAggregator:
+		if agg, err := store.GetAggregatorByID(source.ID); err == nil {
+			state.aggregators = []models.Aggregator{*agg}
+		}
+	case models.RateSourceCounterAgent:
+		if agent, err := store.GetCounterAgentByID(source.ID); err == nil {
+			state.counterAgents = []models.CounterAgent{*agent}
+		}
+	}
+
+	return state.items(source), nil
+}
+
+// stampWashServiceIDs links the services of a wash to the stable IDs of the
+// price list it was priced from. An aggregator wash still without a price
+// list, such as one made from a register, is labelled with the list
+// scheduled on its day but keeps its amounts.
+func stampWashServiceIDs(store *storage.JSONStore, event *models.WashEvent) {
+	if event.PaymentMethod == models.WashPaymentAggregator && event.PriceListName == "" {
+		if agg, err := store.GetAggregatorByID(event.SourceID); err == nil {
+			if t, ok := services.ParseTimestamp(event.Timestamp); ok {
+				event.PriceListName = services.AggregatorPriceListName(agg, t)
+			}
+		}
+	}
+
+	items, err := rateSourceItems(store, services.WashRateSource(event))
+	if err != nil {
+		return
+	}
+	services.StampServiceIDs(event, items)
+}
+
+// scheduleWashPrices prices an aggregator wash sent without a price list from
+// the list scheduled on its day. An updated wash keeps following the schedule
+// while the client leaves the scheduled list in place, so moving it into
+// another season reprices it; a list the client chose is kept as sent.
+func scheduleWashPrices(store *storage.JSONStore, event, existing *models.WashEvent) {
+	if event.PaymentMethod != models.WashPaymentAggregator {
+		event.PriceListScheduled = false
+		return
+	}
+
+	scheduled := event.PriceListName == "" ||
+		(existing != nil && existing.PriceListScheduled && event.PriceListName == existing.PriceListName)
+	event.PriceListScheduled = scheduled
+	if !scheduled {
+		return
+	}
+
+	agg, err := store.GetAggregatorByID(event.SourceID)
+	if err != nil {
+		return
+	}
+	t, ok := services.ParseTimestamp(event.Timestamp)
+	if !ok {
+		return
+	}
+	name := services.AggregatorPriceListName(agg, t)
+	if name == "" || name == event.PriceListName {
+		return
+	}
+
+	event.PriceListName = name
+	services.PriceWashFromList(event, services.AggregatorPriceList(agg, name))
+}
+
+// StoreWashPriceLists saves the scheduled price list on aggregator washes
+// saved before the list was recorded on save, so later schedule edits do not
+// change the salaries of past washes
+func StoreWashPriceLists(store *storage.JSONStore) error {
+	washEvents, err := store.GetAllWashEvents()
+	if err != nil {
+		return err
+	}
+
+	aggregators, err := store.GetAllAggregators()
+	if err != nil {
+		return err
+	}
+
+	for _, event := range services.ResolveWashPriceLists(washEvents, aggregators) {
+		if err := store.SaveWashEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
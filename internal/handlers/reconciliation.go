@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type ReconciliationHandler struct {
+	store *storage.JSONStore
+	cache *storage.Cache
+}
+
+func NewReconciliationHandler(store *storage.JSONStore, cache *storage.Cache) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		store: store,
+		cache: cache,
+	}
+}
+
+// GetForCounterAgent handles GET /api/counter-agents/:id/reconciliation
+func (h *ReconciliationHandler) GetForCounterAgent(c *fiber.Ctx) error {
+	return h.get(c, models.InvoiceClientCounterAgent)
+}
+
+// GetForAggregator handles GET /api/aggregators/:id/reconciliation
+func (h *ReconciliationHandler) GetForAggregator(c *fiber.Ctx) error {
+	return h.get(c, models.InvoiceClientAggregator)
+}
+
+// get builds the reconciliation statement of a client for
+// ?from=YYYY-MM-DD&to=YYYY-MM-DD or ?quarter=YYYY-Qn, optionally for one of
+// its companies (?companyName=), as JSON or ?format=html|pdf
+func (h *ReconciliationHandler) get(c *fiber.Ctx, clientType models.InvoiceClientType) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "html" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "format must be json, html or pdf",
+		})
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if quarter := c.Query("quarter"); quarter != "" {
+		var err error
+		if from, to, err = services.QuarterDates(quarter); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+	if from == "" || to == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from and to or quarter are required",
+		})
+	}
+	period, err := parsePeriod(from, to)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	clientID := c.Params("id")
+	clientName, customer, status, body := invoiceClient(h.store, clientType, clientID, c.Query("companyName"))
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
+	seller, err := h.store.GetCompanyDetails()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get company details",
+		})
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	transactions, err := h.store.GetClientTransactions(clientID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get client transactions",
+		})
+	}
+
+	rec := models.Reconciliation{
+		ClientType: clientType,
+		ClientID:   clientID,
+		ClientName: clientName,
+		From:       from,
+		To:         to,
+		Seller:     *seller,
+		Customer:   customer,
+	}
+	services.BuildReconciliation(&rec, washEvents, transactions, period)
+
+	switch format {
+	case "pdf":
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="reconciliation_%s_%s_%s.pdf"`, clientID, from, to))
+		return c.Send(services.RenderReconciliationPDF(&rec))
+	case "html":
+		html, err := services.RenderReconciliationHTML(&rec)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to render reconciliation",
+			})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(html)
+	}
+
+	return c.JSON(rec)
+}
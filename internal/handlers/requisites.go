@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+)
+
+// companiesRequisiteErrors validates the requisites of the companies being
+// saved. Companies saved before unchanged are skipped, so requisites entered
+// before validation existed do not block unrelated edits. Errors are keyed
+// by field path, e.g. "companies[0].inn".
+func companiesRequisiteErrors(companies, previous []models.CounterAgentCompany) map[string]string {
+	unchanged := make(map[models.CounterAgentCompany]bool, len(previous))
+	for _, company := range previous {
+		unchanged[company] = true
+	}
+
+	errs := make(map[string]string)
+	for i := range companies {
+		if unchanged[companies[i]] {
+			continue
+		}
+		for field, message := range services.CompanyRequisiteErrors(&companies[i]) {
+			errs[fmt.Sprintf("companies[%d].%s", i, field)] = message
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// requisitesErrorResponse is the response refusing to save invalid requisites
+func requisitesErrorResponse(c *fiber.Ctx, fields map[string]string) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":  "Invalid company requisites",
+		"fields": fields,
+	})
+}
+
+// GetRequisitesIssues handles GET /api/counter-agents/requisites
+// Lists the companies of counter agents whose requisites are invalid.
+func (h *CounterAgentHandler) GetRequisitesIssues(c *fiber.Ctx) error {
+	agents, err := h.getCounterAgents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get counter agents",
+		})
+	}
+
+	issues := []models.RequisitesIssue{}
+	for _, agent := range agents {
+		for i := range agent.Companies {
+			errs := services.CompanyRequisiteErrors(&agent.Companies[i])
+			if errs == nil {
+				continue
+			}
+			issues = append(issues, models.RequisitesIssue{
+				CounterAgentID:   agent.ID,
+				CounterAgentName: agent.Name,
+				CompanyIndex:     i,
+				CompanyName:      agent.Companies[i].CompanyName,
+				Errors:           errs,
+			})
+		}
+	}
+
+	return c.JSON(issues)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+)
+
+// FillRateGapsRequest is the body of POST /api/salary-schemes/:id/gaps/rates
+// Rates are either the fixed Rate or Percentage of the service price in the
+// scheme's rate source (the average price performed when the source has none).
+type FillRateGapsRequest struct {
+	ServiceNames  []string     `json:"serviceNames"` // all gaps when empty
+	Rate          models.Money `json:"rate"`
+	Percentage    float64      `json:"percentage"`
+	From          string       `json:"from"`
+	To            string       `json:"to"`
+	EffectiveFrom string       `json:"effectiveFrom"`
+}
+
+// GetGaps handles GET /api/salary-schemes/:id/gaps?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Services performed by employees on the scheme that it has no rate for.
+func (h *SalarySchemeHandler) GetGaps(c *fiber.Ctx) error {
+	scheme, err := h.store.GetSalarySchemeByID(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Salary scheme not found",
+		})
+	}
+
+	period, err := parsePeriodQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	gaps, err := h.findRateGaps(scheme, period)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var revenue models.Money
+	for _, gap := range gaps {
+		revenue += gap.Revenue
+	}
+
+	return c.JSON(fiber.Map{
+		"schemeId": scheme.ID,
+		"gaps":     gaps,
+		"revenue":  revenue,
+	})
+}
+
+// FillGaps handles POST /api/salary-schemes/:id/gaps/rates
+// Adds rates for the gaps as a new version of the scheme.
+func (h *SalarySchemeHandler) FillGaps(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	existing, err := h.store.GetSalarySchemeByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Salary scheme not found",
+		})
+	}
+
+	var req FillRateGapsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Rate < 0 || req.Percentage < 0 || req.Percentage > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "rate must not be negative and percentage must be between 0 and 100",
+		})
+	}
+	if req.Rate == 0 && req.Percentage == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "rate or percentage is required",
+		})
+	}
+
+	period, err := parsePeriod(req.From, req.To)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	effectiveFrom := req.EffectiveFrom
+	if effectiveFrom == "" {
+		effectiveFrom = services.LocalDate(time.Now())
+	}
+	if _, err := time.Parse("2006-01-02", effectiveFrom); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "effectiveFrom must be a YYYY-MM-DD date",
+		})
+	}
+
+	gaps, err := h.findRateGaps(existing, period)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	selected := make(map[string]bool, len(req.ServiceNames))
+	for _, name := range req.ServiceNames {
+		selected[name] = true
+	}
+
+	added := []models.SalaryRate{}
+	for i := range gaps {
+		gap := &gaps[i]
+		if len(selected) > 0 && !selected[gap.ServiceName] {
+			continue
+		}
+		rate := req.Rate
+		if rate == 0 {
+			rate = services.RateFromPrice(gap, req.Percentage)
+		}
+		if rate > 0 {
+			added = append(added, models.SalaryRate{ServiceName: gap.ServiceName, Rate: rate})
+		}
+	}
+
+	if len(added) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No rate gaps to fill",
+		})
+	}
+
+	periods, err := h.store.GetAllPayrollPeriods()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	}
+	if locked := services.FindClosedPayrollPeriodForSchemeVersion(periods, id, effectiveFrom); locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
+	updates := *existing
+	updates.PreviousVersions = nil
+	updates.Rates = mergeSalaryRates(existing.Rates, added)
+
+	updated := services.NewSchemeVersion(existing, updates, effectiveFrom)
+
+	if err := h.store.SaveSalaryScheme(&updated); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update salary scheme",
+		})
+	}
+
+	h.cache.InvalidateSalarySchemes()
+
+	return c.JSON(fiber.Map{
+		"scheme": updated,
+		"added":  added,
+	})
+}
+
+// findRateGaps loads the washes, employees and rate source prices for the scheme
+func (h *SalarySchemeHandler) findRateGaps(scheme *models.SalaryScheme, period services.Period) ([]models.SalaryRateGap, error) {
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return nil, errors.New("Failed to get wash events")
+	}
+
+	employees, err := h.store.GetAllEmployees()
+	if err != nil {
+		return nil, errors.New("Failed to get employees")
+	}
+
+	items, err := rateSourceItems(h.store, scheme.RateSource)
+	if err != nil {
+		return nil, errors.New("Failed to get rate source price list")
+	}
+
+	return services.FindRateGaps(scheme, washEvents, employees, period, services.ServicePrices(items)), nil
+}
+
+// mergeSalaryRates replaces zero rates of the same service and appends the rest
+func mergeSalaryRates(rates []models.SalaryRate, added []models.SalaryRate) []models.SalaryRate {
+	result := append([]models.SalaryRate{}, rates...)
+	index := make(map[string]int, len(result))
+	for i, r := range result {
+		index[r.ServiceName] = i
+	}
+	for _, r := range added {
+		if i, ok := index[r.ServiceName]; ok {
+			result[i].Rate = r.Rate
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
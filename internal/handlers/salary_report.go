@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
+	"backend-go/internal/models"
 	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
@@ -21,32 +25,168 @@ func NewSalaryReportHandler(store *storage.JSONStore, cache *storage.Cache) *Sal
 	}
 }
 
-// GenerateReport handles GET /api/salary-report
+// GenerateReport handles GET /api/salary-report?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Both bounds are optional; without them the report covers all history.
+// With periodId the frozen report of a closed payroll period is returned.
 func (h *SalaryReportHandler) GenerateReport(c *fiber.Ctx) error {
-	// Get all required data
-	washEvents, err := h.store.GetAllWashEvents()
+	if periodID := c.Query("periodId"); periodID != "" {
+		return h.payrollPeriodReport(c, periodID)
+	}
+
+	period, err := parsePeriodQuery(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get wash events",
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	employees, err := h.store.GetAllEmployees()
+	report, err := buildSalaryReport(h.store, h.calculator, period)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get employees",
+			"error": err.Error(),
 		})
 	}
 
-	salarySchemes, err := h.store.GetAllSalarySchemes()
+	return c.JSON(report)
+}
+
+// payrollPeriodReport returns the snapshot of a closed payroll period or the
+// live report of an open one
+func (h *SalaryReportHandler) payrollPeriodReport(c *fiber.Ctx, periodID string) error {
+	period, err := h.store.GetPayrollPeriodByID(periodID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get salary schemes",
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Payroll period not found",
 		})
 	}
 
-	// Generate report
-	report := h.calculator.GenerateSalaryReport(washEvents, employees, salarySchemes)
+	if period.Status == models.PayrollPeriodClosed {
+		return c.JSON(period.Snapshot)
+	}
+
+	r, err := services.PayrollPeriodRange(period)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	report, err := buildSalaryReport(h.store, h.calculator, r)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	return c.JSON(report)
 }
+
+// buildSalaryReport loads wash events, employees, schemes and employee
+// transactions and generates the salary report for the period
+func buildSalaryReport(store *storage.JSONStore, calculator *services.SalaryCalculator, period services.Period) ([]models.SalaryReportData, error) {
+	washEvents, err := store.GetAllWashEvents()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get wash events")
+	}
+
+	employees, err := store.GetAllEmployees()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get employees")
+	}
+
+	salarySchemes, err := loadSalarySchemesForReport(store)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make(map[string][]models.EmployeeTransaction, len(employees))
+	for _, emp := range employees {
+		empTransactions, err := store.GetEmployeeTransactions(emp.ID)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get employee transactions")
+		}
+		transactions[emp.ID] = empTransactions
+	}
+
+	return calculator.GeneratePeriodReport(washEvents, employees, salarySchemes, transactions, period), nil
+}
+
+// loadSalarySchemesForReport loads the salary schemes with chemical rules
+// priced at the average price of chemical bought up to each day unless they
+// set their own
+func loadSalarySchemesForReport(store *storage.JSONStore) ([]models.SalaryScheme, error) {
+	salarySchemes, err := store.GetAllSalarySchemes()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get salary schemes")
+	}
+
+	prices, err := chemicalPrices(store)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.WithChemicalCost(salarySchemes, prices), nil
+}
+
+// chemicalPrices returns the average price of chemical after each day with
+// purchases
+func chemicalPrices(store *storage.JSONStore) ([]models.ChemicalPrice, error) {
+	expenses, err := store.GetAllExpenses()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get expenses")
+	}
+	return services.ChemicalPrices(expenses), nil
+}
+
+// chemicalCostPerKg returns the average price of chemical bought before until
+func chemicalCostPerKg(store *storage.JSONStore, until time.Time) (models.Money, error) {
+	expenses, err := store.GetAllExpenses()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get expenses")
+	}
+	return services.AverageChemicalCostPerKg(expenses, until), nil
+}
+
+// parsePeriodQuery reads the from/to query parameters. Dates without a time
+// are taken in server local time and to is inclusive of the whole day.
+func parsePeriodQuery(c *fiber.Ctx) (services.Period, error) {
+	return parsePeriod(c.Query("from"), c.Query("to"))
+}
+
+// parsePeriod parses optional from/to bounds as described for parsePeriodQuery
+func parsePeriod(from, to string) (services.Period, error) {
+	var period services.Period
+
+	if from != "" {
+		t, _, err := parsePeriodBound(from)
+		if err != nil {
+			return period, fmt.Errorf("invalid from date: %s", from)
+		}
+		period.From = t
+	}
+
+	if to != "" {
+		t, dateOnly, err := parsePeriodBound(to)
+		if err != nil {
+			return period, fmt.Errorf("invalid to date: %s", to)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		period.To = t
+	}
+
+	if !period.From.IsZero() && !period.To.IsZero() && !period.From.Before(period.To) {
+		return period, fmt.Errorf("from must be before to")
+	}
+
+	return period, nil
+}
+
+func parsePeriodBound(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
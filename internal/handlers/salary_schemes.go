@@ -1,28 +1,48 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 
 	"backend-go/internal/models"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
 type SalarySchemeHandler struct {
-	store *storage.JSONStore
-	cache *storage.Cache
+	store      *storage.JSONStore
+	cache      *storage.Cache
+	calculator *services.SalaryCalculator
 }
 
 func NewSalarySchemeHandler(store *storage.JSONStore, cache *storage.Cache) *SalarySchemeHandler {
 	return &SalarySchemeHandler{
-		store: store,
-		cache: cache,
+		store:      store,
+		cache:      cache,
+		calculator: services.NewSalaryCalculator(),
 	}
 }
 
-// GetAll handles GET /api/salary-schemes
+// draftSchemeID identifies a simulated scheme that has not been saved
+const draftSchemeID = "draft"
+
+// SimulateSalarySchemeRequest is the body of POST /api/salary-schemes/simulate
+// Either Scheme (a draft or a complete replacement) or SchemeID with optional
+// Changes to the existing scheme is given.
+type SimulateSalarySchemeRequest struct {
+	SchemeID    string               `json:"schemeId"`
+	Scheme      *models.SalaryScheme `json:"scheme"`
+	Changes     json.RawMessage      `json:"changes"`
+	EmployeeIDs []string             `json:"employeeIds"`
+	From        string               `json:"from"`
+	To          string               `json:"to"`
+}
+
+// GetAll handles GET /api/salary-schemes?includeArchived=true
 func (h *SalarySchemeHandler) GetAll(c *fiber.Ctx) error {
 	schemes, err := h.getSalarySchemes()
 	if err != nil {
@@ -31,7 +51,16 @@ func (h *SalarySchemeHandler) GetAll(c *fiber.Ctx) error {
 		})
 	}
 
-	return c.JSON(schemes)
+	if c.QueryBool("includeArchived") {
+		return c.JSON(schemes)
+	}
+	result := []models.SalaryScheme{}
+	for _, scheme := range schemes {
+		if !scheme.Archived {
+			result = append(result, scheme)
+		}
+	}
+	return c.JSON(result)
 }
 
 // Create handles POST /api/salary-schemes
@@ -48,11 +77,27 @@ func (h *SalarySchemeHandler) Create(c *fiber.Ctx) error {
 		scheme.ID = fmt.Sprintf("scheme_%d", time.Now().UnixMilli())
 	}
 
+	if err := validateSalaryScheme(&scheme); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// A new scheme starts without history
+	scheme.Version = 1
+	scheme.PreviousVersions = nil
+
 	// Initialize rates if nil
 	if scheme.Rates == nil {
 		scheme.Rates = []models.SalaryRate{}
 	}
 
+	if err := h.linkRateServiceIDs(&scheme); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rate source price list",
+		})
+	}
+
 	if err := h.store.SaveSalaryScheme(&scheme); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save salary scheme",
@@ -83,7 +128,7 @@ func (h *SalarySchemeHandler) Update(c *fiber.Ctx) error {
 	id := c.Params("id")
 
 	// Get existing scheme
-	_, err := h.store.GetSalarySchemeByID(id)
+	existing, err := h.store.GetSalarySchemeByID(id)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Salary scheme not found",
@@ -97,10 +142,46 @@ func (h *SalarySchemeHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
-	// Ensure ID is preserved
-	updates.ID = id
+	if err := validateSalaryScheme(&updates); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Changes apply from effectiveFrom (today by default); washes before
+	// that date keep being calculated under the previous version
+	effectiveFrom := updates.EffectiveFrom
+	if effectiveFrom == "" {
+		effectiveFrom = services.LocalDate(time.Now())
+	}
+	if _, err := time.Parse("2006-01-02", effectiveFrom); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "effectiveFrom must be a YYYY-MM-DD date",
+		})
+	}
+
+	periods, err := h.store.GetAllPayrollPeriods()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	}
+	if locked := services.FindClosedPayrollPeriodForSchemeVersion(periods, id, effectiveFrom); locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
+	if err := h.linkRateServiceIDs(&updates); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get rate source price list",
+		})
+	}
+
+	// Schemes are archived only by deleting them; the form does not send it
+	updates.Archived = existing.Archived
+
+	updated := services.NewSchemeVersion(existing, updates, effectiveFrom)
 
-	if err := h.store.SaveSalaryScheme(&updates); err != nil {
+	if err := h.store.SaveSalaryScheme(&updated); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update salary scheme",
 		})
@@ -108,13 +189,44 @@ func (h *SalarySchemeHandler) Update(c *fiber.Ctx) error {
 
 	h.cache.InvalidateSalarySchemes()
 
-	return c.JSON(updates)
+	return c.JSON(updated)
 }
 
 // Delete handles DELETE /api/salary-schemes/:id
 func (h *SalarySchemeHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	locked, err := h.findLockingPayrollPeriod(id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	}
+
+	// A scheme closed periods were calculated with is kept for them: deleting
+	// it only archives it, which changes no date of those periods
+	if locked != nil {
+		scheme, err := h.store.GetSalarySchemeByID(id)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Salary scheme not found",
+			})
+		}
+		scheme.Archived = true
+		if err := h.store.SaveSalaryScheme(scheme); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to archive salary scheme",
+			})
+		}
+
+		h.cache.InvalidateSalarySchemes()
+
+		return c.JSON(fiber.Map{
+			"message":         "Salary scheme archived, closed payroll periods were calculated with it",
+			"payrollPeriodId": locked.ID,
+		})
+	}
+
 	if err := h.store.DeleteSalaryScheme(id); err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Salary scheme not found",
@@ -128,6 +240,135 @@ func (h *SalarySchemeHandler) Delete(c *fiber.Ctx) error {
 	})
 }
 
+// Simulate handles POST /api/salary-schemes/simulate
+// Wash history is replayed under the current and the proposed rules and the
+// earnings of each employee in the period are returned side by side.
+// Nothing is saved.
+func (h *SalarySchemeHandler) Simulate(c *fiber.Ctx) error {
+	var req SimulateSalarySchemeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var proposed models.SalaryScheme
+	switch {
+	case req.SchemeID != "":
+		existing, err := h.store.GetSalarySchemeByID(req.SchemeID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Salary scheme not found",
+			})
+		}
+		proposed = *existing
+		if req.Scheme != nil {
+			proposed = *req.Scheme
+		}
+		if len(req.Changes) > 0 {
+			proposed, err = applySchemeChanges(proposed, req.Changes)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid scheme changes",
+				})
+			}
+		}
+		proposed.ID = req.SchemeID
+	case req.Scheme != nil:
+		if len(req.EmployeeIDs) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "employeeIds is required to simulate a draft scheme",
+			})
+		}
+		proposed = *req.Scheme
+		proposed.ID = draftSchemeID
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "scheme or schemeId is required",
+		})
+	}
+
+	if err := validateSalaryScheme(&proposed); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	period, err := parsePeriod(req.From, req.To)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	washEvents, err := h.store.GetAllWashEvents()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	employees, err := h.store.GetAllEmployees()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get employees",
+		})
+	}
+
+	schemes, err := loadSalarySchemesForReport(h.store)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// The proposed scheme is priced like the stored ones
+	prices, err := chemicalPrices(h.store)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	proposed = services.WithChemicalCost([]models.SalaryScheme{proposed}, prices)[0]
+
+	result := h.calculator.SimulateScheme(washEvents, employees, schemes, proposed, req.EmployeeIDs, period)
+
+	return c.JSON(result)
+}
+
+// applySchemeChanges overlays the fields present in changes on the scheme.
+// A null field clears it.
+func applySchemeChanges(scheme models.SalaryScheme, changes json.RawMessage) (models.SalaryScheme, error) {
+	base, err := json.Marshal(scheme)
+	if err != nil {
+		return scheme, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(base, &fields); err != nil {
+		return scheme, err
+	}
+
+	var overrides map[string]json.RawMessage
+	if err := json.Unmarshal(changes, &overrides); err != nil {
+		return scheme, err
+	}
+	for key, value := range overrides {
+		fields[key] = value
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return scheme, err
+	}
+
+	var result models.SalaryScheme
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return scheme, err
+	}
+	return result, nil
+}
+
 func (h *SalarySchemeHandler) getSalarySchemes() ([]models.SalaryScheme, error) {
 	if cached, ok := h.cache.GetSalarySchemes(); ok {
 		return cached, nil
@@ -141,3 +382,77 @@ func (h *SalarySchemeHandler) getSalarySchemes() ([]models.SalaryScheme, error)
 	h.cache.SetSalarySchemes(schemes)
 	return schemes, nil
 }
+
+// linkRateServiceIDs links the rates of the scheme to the stable IDs of the
+// services in its rate source price list
+func (h *SalarySchemeHandler) linkRateServiceIDs(scheme *models.SalaryScheme) error {
+	items, err := rateSourceItems(h.store, scheme.RateSource)
+	if err != nil {
+		return err
+	}
+	scheme.Rates = services.LinkRateServiceIDs(scheme.Rates, items)
+	return nil
+}
+
+// findLockingPayrollPeriod returns a closed payroll period calculated with the scheme
+func (h *SalarySchemeHandler) findLockingPayrollPeriod(schemeID string) (*models.PayrollPeriod, error) {
+	periods, err := h.store.GetAllPayrollPeriods()
+	if err != nil {
+		return nil, err
+	}
+	return services.FindClosedPayrollPeriodForScheme(periods, schemeID), nil
+}
+
+// validateSalaryScheme checks the scheme type and the amounts of its components
+func validateSalaryScheme(scheme *models.SalaryScheme) error {
+	switch scheme.Type {
+	case models.SalarySchemePercentage, models.SalarySchemeRate, models.SalarySchemeHybrid:
+	default:
+		return fmt.Errorf("unknown salary scheme type: %s", scheme.Type)
+	}
+
+	if scheme.Percentage < 0 || scheme.BaseWage < 0 || scheme.GuaranteedDailyMinimum < 0 || scheme.DailyCap < 0 {
+		return errors.New("salary scheme amounts must not be negative")
+	}
+
+	switch scheme.BaseWagePeriod {
+	case "", models.BaseWagePerDay, models.BaseWagePerShift:
+	default:
+		return fmt.Errorf("unknown base wage period: %s", scheme.BaseWagePeriod)
+	}
+
+	if scheme.DailyCap > 0 && scheme.DailyCap < scheme.GuaranteedDailyMinimum {
+		return errors.New("dailyCap must not be lower than guaranteedDailyMinimum")
+	}
+
+	if scheme.Type == models.SalarySchemeHybrid && scheme.BaseWage == 0 && scheme.Percentage == 0 &&
+		len(scheme.Rates) == 0 && len(scheme.Tiers) == 0 {
+		return errors.New("hybrid scheme needs a base wage, a percentage, rates or tiers")
+	}
+
+	switch scheme.TierBasis {
+	case "", models.SalaryTierByRevenue, models.SalaryTierByWashCount:
+	default:
+		return fmt.Errorf("unknown tier basis: %s", scheme.TierBasis)
+	}
+
+	switch scheme.TierPeriod {
+	case "", models.SalaryTierPeriodDay, models.SalaryTierPeriodWeek, models.SalaryTierPeriodMonth:
+	default:
+		return fmt.Errorf("unknown tier period: %s", scheme.TierPeriod)
+	}
+
+	for _, tier := range scheme.Tiers {
+		if tier.From < 0 || tier.Percentage < 0 || tier.BonusPerWash < 0 {
+			return errors.New("salary tier values must not be negative")
+		}
+	}
+
+	if rule := scheme.ChemicalRule; rule != nil {
+		if rule.CostPerKg < 0 || rule.TolerancePercent < 0 || rule.TolerancePercent > 100 {
+			return errors.New("chemicalRule costPerKg must not be negative and tolerancePercent must be within 0-100")
+		}
+	}
+
+	return nil
+}
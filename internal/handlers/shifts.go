@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"backend-go/internal/models"
+	"backend-go/internal/services"
+	"backend-go/internal/storage"
+)
+
+type ShiftHandler struct {
+	store  *storage.JSONStore
+	cache  *storage.Cache
+	broker *services.EventBroker
+}
+
+func NewShiftHandler(store *storage.JSONStore, cache *storage.Cache, broker *services.EventBroker) *ShiftHandler {
+	return &ShiftHandler{
+		store:  store,
+		cache:  cache,
+		broker: broker,
+	}
+}
+
+// OpenShiftRequest is the body of POST /api/shifts/open
+type OpenShiftRequest struct {
+	EmployeeIDs []string     `json:"employeeIds"`
+	OpenedBy    string       `json:"openedBy"`
+	OpeningCash models.Money `json:"openingCash"`
+	Notes       string       `json:"notes"`
+}
+
+// CloseShiftRequest is the body of POST /api/shifts/close
+type CloseShiftRequest struct {
+	CountedCash *models.Money `json:"countedCash"`
+	CountedCard *models.Money `json:"countedCard"`
+	ClosedBy    string        `json:"closedBy"`
+	Notes       string        `json:"notes"`
+}
+
+// GetAll handles GET /api/shifts
+func (h *ShiftHandler) GetAll(c *fiber.Ctx) error {
+	shifts, err := h.store.GetAllShifts()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get shifts",
+		})
+	}
+
+	if shifts == nil {
+		shifts = []models.Shift{}
+	}
+
+	return c.JSON(shifts)
+}
+
+// GetCurrent handles GET /api/shifts/current
+// The expected totals of the open shift are calculated on the fly.
+func (h *ShiftHandler) GetCurrent(c *fiber.Ctx) error {
+	shift, err := h.store.GetOpenShift()
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No open shift",
+		})
+	}
+
+	events, err := h.getShiftWashEvents(shift.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	totals := services.CalculateShiftTotals(events)
+	shift.Totals = &totals
+
+	return c.JSON(shift)
+}
+
+// GetByID handles GET /api/shifts/:id
+func (h *ShiftHandler) GetByID(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	shift, err := h.store.GetShiftByID(id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Shift not found",
+		})
+	}
+
+	return c.JSON(shift)
+}
+
+// Open handles POST /api/shifts/open
+func (h *ShiftHandler) Open(c *fiber.Ctx) error {
+	var req OpenShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.OpeningCash < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "openingCash must not be negative",
+		})
+	}
+
+	if open, err := h.store.GetOpenShift(); err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   "A shift is already open",
+			"shiftId": open.ID,
+		})
+	}
+
+	if req.EmployeeIDs == nil {
+		req.EmployeeIDs = []string{}
+	}
+
+	shift := models.Shift{
+		ID:          fmt.Sprintf("shift_%d_%s", time.Now().UnixMilli(), generateRandomString(7)),
+		Status:      models.ShiftOpen,
+		OpenedAt:    time.Now().UTC().Format(time.RFC3339Nano),
+		OpenedBy:    req.OpenedBy,
+		EmployeeIDs: req.EmployeeIDs,
+		OpeningCash: req.OpeningCash,
+		Notes:       req.Notes,
+	}
+
+	if err := h.store.SaveShift(&shift); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save shift",
+		})
+	}
+
+	h.broker.Publish(services.EventShiftOpened, shift)
+
+	return c.Status(fiber.StatusCreated).JSON(shift)
+}
+
+// Close handles POST /api/shifts/close
+func (h *ShiftHandler) Close(c *fiber.Ctx) error {
+	var req CloseShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.CountedCash == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "countedCash is required",
+		})
+	}
+
+	shift, err := h.store.GetOpenShift()
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "No open shift",
+		})
+	}
+
+	events, err := h.getShiftWashEvents(shift.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to get wash events",
+		})
+	}
+
+	services.ReconcileShift(shift, events, *req.CountedCash, req.CountedCard)
+
+	shift.Status = models.ShiftClosed
+	shift.ClosedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	shift.ClosedBy = req.ClosedBy
+	if req.Notes != "" {
+		shift.Notes = req.Notes
+	}
+
+	if err := h.store.SaveShift(shift); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save shift",
+		})
+	}
+
+	h.broker.Publish(services.EventShiftClosed, shift)
+
+	return c.JSON(shift)
+}
+
+func (h *ShiftHandler) getShiftWashEvents(shiftID string) ([]models.WashEvent, error) {
+	events, ok := h.cache.GetWashEvents()
+	if !ok {
+		var err error
+		events, err = h.store.GetAllWashEvents()
+		if err != nil {
+			return nil, err
+		}
+		h.cache.SetWashEvents(events)
+	}
+
+	var result []models.WashEvent
+	for _, event := range events {
+		if event.ShiftID == shiftID {
+			result = append(result, event)
+		}
+	}
+	return result, nil
+}
+
+// shiftClockSkew is how far ahead of the server clock a wash timestamp set
+// by a workstation may be and still count as made now
+const shiftClockSkew = 5 * time.Minute
+
+// assignWashToOpenShift links a new wash event to the open shift and adds
+// its washers to the employees on duty. Only washes made while the shift is
+// open are linked; backdated ones stay out of its cash reconciliation.
+func assignWashToOpenShift(store *storage.JSONStore, event *models.WashEvent) {
+	shift, err := store.GetOpenShift()
+	if err != nil {
+		return
+	}
+
+	washedAt, ok := services.ParseTimestamp(event.Timestamp)
+	if !ok {
+		return
+	}
+	openedAt, ok := services.ParseTimestamp(shift.OpenedAt)
+	if !ok || washedAt.Before(openedAt) || washedAt.After(time.Now().Add(shiftClockSkew)) {
+		return
+	}
+
+	event.ShiftID = shift.ID
+
+	onDuty := make(map[string]bool, len(shift.EmployeeIDs))
+	for _, id := range shift.EmployeeIDs {
+		onDuty[id] = true
+	}
+
+	changed := false
+	for _, id := range event.EmployeeIDs {
+		if !onDuty[id] {
+			shift.EmployeeIDs = append(shift.EmployeeIDs, id)
+			onDuty[id] = true
+			changed = true
+		}
+	}
+
+	if changed {
+		store.SaveShift(shift)
+	}
+}
@@ -8,18 +8,21 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"backend-go/internal/models"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
 type TransactionHandler struct {
-	store *storage.JSONStore
-	cache *storage.Cache
+	store  *storage.JSONStore
+	cache  *storage.Cache
+	broker *services.EventBroker
 }
 
-func NewTransactionHandler(store *storage.JSONStore, cache *storage.Cache) *TransactionHandler {
+func NewTransactionHandler(store *storage.JSONStore, cache *storage.Cache, broker *services.EventBroker) *TransactionHandler {
 	return &TransactionHandler{
-		store: store,
-		cache: cache,
+		store:  store,
+		cache:  cache,
+		broker: broker,
 	}
 }
 
@@ -60,6 +63,7 @@ func (h *TransactionHandler) AddClientTransaction(c *fiber.Ctx) error {
 	h.updateClientBalance(clientID, trans.Amount)
 
 	h.cache.InvalidateClientTransactions(clientID)
+	h.broker.Publish(services.EventClientPaymentCreated, trans)
 
 	return c.Status(fiber.StatusCreated).JSON(trans)
 }
@@ -85,7 +89,7 @@ func (h *TransactionHandler) DeleteClientTransaction(c *fiber.Ctx) error {
 
 	// Find and remove transaction
 	var found bool
-	var deletedAmount float64
+	var deletedAmount models.Money
 	var newTransactions []models.ClientTransaction
 	for _, t := range transactions {
 		if t.ID == transactionID {
@@ -112,6 +116,11 @@ func (h *TransactionHandler) DeleteClientTransaction(c *fiber.Ctx) error {
 	h.updateClientBalance(clientID, -deletedAmount)
 
 	h.cache.InvalidateClientTransactions(clientID)
+	h.broker.Publish(services.EventClientPaymentDeleted, fiber.Map{
+		"id":       transactionID,
+		"clientId": clientID,
+		"amount":   deletedAmount,
+	})
 
 	return c.JSON(fiber.Map{
 		"message": "Transaction deleted successfully",
@@ -119,42 +128,47 @@ func (h *TransactionHandler) DeleteClientTransaction(c *fiber.Ctx) error {
 }
 
 // updateClientBalance updates the balance of an aggregator or counter agent
-func (h *TransactionHandler) updateClientBalance(clientID string, amount float64) {
+func (h *TransactionHandler) updateClientBalance(clientID string, amount models.Money) {
+	adjustClientBalance(h.store, h.cache, clientID, amount)
+}
+
+// adjustClientBalance adds amount to the balance of an aggregator or counter agent
+func adjustClientBalance(store *storage.JSONStore, cache *storage.Cache, clientID string, amount models.Money) {
 	// Try to update aggregator first
 	if strings.HasPrefix(clientID, "agg_") {
-		agg, err := h.store.GetAggregatorByID(clientID)
+		agg, err := store.GetAggregatorByID(clientID)
 		if err == nil {
 			agg.Balance += amount
-			h.store.SaveAggregator(agg)
-			h.cache.InvalidateAggregators()
+			store.SaveAggregator(agg)
+			cache.InvalidateAggregators()
 			return
 		}
 	}
 
 	// Try to update counter agent
 	if strings.HasPrefix(clientID, "agent_") {
-		agent, err := h.store.GetCounterAgentByID(clientID)
+		agent, err := store.GetCounterAgentByID(clientID)
 		if err == nil {
 			agent.Balance += amount
-			h.store.SaveCounterAgent(agent)
-			h.cache.InvalidateCounterAgents()
+			store.SaveCounterAgent(agent)
+			cache.InvalidateCounterAgents()
 			return
 		}
 	}
 
 	// If not found by prefix, try both
-	agg, err := h.store.GetAggregatorByID(clientID)
+	agg, err := store.GetAggregatorByID(clientID)
 	if err == nil {
 		agg.Balance += amount
-		h.store.SaveAggregator(agg)
-		h.cache.InvalidateAggregators()
+		store.SaveAggregator(agg)
+		cache.InvalidateAggregators()
 		return
 	}
 
-	agent, err := h.store.GetCounterAgentByID(clientID)
+	agent, err := store.GetCounterAgentByID(clientID)
 	if err == nil {
 		agent.Balance += amount
-		h.store.SaveCounterAgent(agent)
-		h.cache.InvalidateCounterAgents()
+		store.SaveCounterAgent(agent)
+		cache.InvalidateCounterAgents()
 	}
 }
@@ -7,18 +7,21 @@ import (
 	"github.com/gofiber/fiber/v2"
 
 	"backend-go/internal/models"
+	"backend-go/internal/services"
 	"backend-go/internal/storage"
 )
 
 type WashEventHandler struct {
-	store *storage.JSONStore
-	cache *storage.Cache
+	store  *storage.JSONStore
+	cache  *storage.Cache
+	broker *services.EventBroker
 }
 
-func NewWashEventHandler(store *storage.JSONStore, cache *storage.Cache) *WashEventHandler {
+func NewWashEventHandler(store *storage.JSONStore, cache *storage.Cache, broker *services.EventBroker) *WashEventHandler {
 	return &WashEventHandler{
-		store: store,
-		cache: cache,
+		store:  store,
+		cache:  cache,
+		broker: broker,
 	}
 }
 
@@ -43,6 +46,21 @@ func (h *WashEventHandler) Create(c *fiber.Ctx) error {
 		})
 	}
 
+	if locked, err := findLockingPayrollPeriod(h.store, event.Timestamp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	} else if locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
+	scheduleWashPrices(h.store, &event, nil)
+
+	creditStatus, status, body := checkWashCredit(h.store, c, &event, nil)
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
 	// Generate ID if not provided
 	if event.ID == "" {
 		event.ID = fmt.Sprintf("we_%d_%s", time.Now().UnixMilli(), generateRandomString(7))
@@ -56,6 +74,13 @@ func (h *WashEventHandler) Create(c *fiber.Ctx) error {
 		event.Services.Additional = []models.PriceListItem{}
 	}
 
+	// Link the wash to the shift in progress, if any; the shift is never
+	// taken from the request
+	event.ShiftID = ""
+	assignWashToOpenShift(h.store, &event)
+
+	stampWashServiceIDs(h.store, &event)
+
 	// Calculate total chemical consumption
 	totalConsumption := calculateChemicalConsumption(&event)
 
@@ -78,11 +103,13 @@ func (h *WashEventHandler) Create(c *fiber.Ctx) error {
 		}
 		h.store.SaveInventory(inv)
 		h.cache.InvalidateInventory()
+		h.broker.Publish(services.EventInventoryUpdated, inv)
 	}
 
 	h.cache.InvalidateWashEvents()
+	h.broker.Publish(services.EventWashCreated, event)
 
-	return c.Status(fiber.StatusCreated).JSON(event)
+	return washCreditResponse(h.store, c, fiber.StatusCreated, &event, creditStatus)
 }
 
 // GetByID handles GET /api/wash-events/:id
@@ -118,12 +145,35 @@ func (h *WashEventHandler) Update(c *fiber.Ctx) error {
 		})
 	}
 
+	if locked, err := findLockingPayrollPeriod(h.store, existing.Timestamp, updates.Timestamp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	} else if locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
+	scheduleWashPrices(h.store, &updates, existing)
+
+	creditStatus, status, body := checkWashCredit(h.store, c, &updates, existing)
+	if status != 0 {
+		return c.Status(status).JSON(body)
+	}
+
 	// Calculate old and new chemical consumption
 	oldConsumption := calculateChemicalConsumption(existing)
 	newConsumption := calculateChemicalConsumption(&updates)
 
-	// Ensure ID is preserved
+	// Ensure ID and shift are preserved; a wash moved in time is linked
+	// again as if it were new
 	updates.ID = id
+	updates.ShiftID = existing.ShiftID
+	if updates.Timestamp != existing.Timestamp {
+		updates.ShiftID = ""
+		assignWashToOpenShift(h.store, &updates)
+	}
+
+	stampWashServiceIDs(h.store, &updates)
 
 	// Save updated event
 	if err := h.store.SaveWashEvent(&updates); err != nil {
@@ -145,11 +195,13 @@ func (h *WashEventHandler) Update(c *fiber.Ctx) error {
 		}
 		h.store.SaveInventory(inv)
 		h.cache.InvalidateInventory()
+		h.broker.Publish(services.EventInventoryUpdated, inv)
 	}
 
 	h.cache.InvalidateWashEvents()
+	h.broker.Publish(services.EventWashUpdated, updates)
 
-	return c.JSON(updates)
+	return washCreditResponse(h.store, c, fiber.StatusOK, &updates, creditStatus)
 }
 
 // Delete handles DELETE /api/wash-events/:id
@@ -164,6 +216,14 @@ func (h *WashEventHandler) Delete(c *fiber.Ctx) error {
 		})
 	}
 
+	if locked, err := findLockingPayrollPeriod(h.store, existing.Timestamp); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check payroll periods",
+		})
+	} else if locked != nil {
+		return payrollLockedResponse(c, locked)
+	}
+
 	// Calculate consumption to return to inventory
 	consumption := calculateChemicalConsumption(existing)
 
@@ -183,9 +243,11 @@ func (h *WashEventHandler) Delete(c *fiber.Ctx) error {
 		inv.ChemicalStockGrams += consumption
 		h.store.SaveInventory(inv)
 		h.cache.InvalidateInventory()
+		h.broker.Publish(services.EventInventoryUpdated, inv)
 	}
 
 	h.cache.InvalidateWashEvents()
+	h.broker.Publish(services.EventWashDeleted, fiber.Map{"id": id})
 
 	return c.JSON(fiber.Map{
 		"message": "Wash event deleted successfully",
@@ -49,11 +49,13 @@ type EmployeeConsumption struct {
 // PriceListItem represents a service in price list
 type PriceListItem struct {
 	ServiceName          string                `json:"serviceName"`
-	Price                float64               `json:"price"`
+	Price                Money                 `json:"price"`
 	IsCustom             bool                  `json:"isCustom,omitempty"`
 	ChemicalConsumption  float64               `json:"chemicalConsumption,omitempty"`
+	EmployeeChemicalNorm float64               `json:"employeeChemicalNorm,omitempty"` // grams one employee may use
 	EmployeeConsumptions []EmployeeConsumption `json:"employeeConsumptions,omitempty"`
 	ID                   string                `json:"id,omitempty"`
+	ServiceID            string                `json:"serviceId,omitempty"` // stable ID of the service in its price list
 }
 
 // RetailPriceConfig represents retail price configuration
@@ -63,31 +65,146 @@ type RetailPriceConfig struct {
 	AllowCustomRetailServices bool            `json:"allowCustomRetailServices,omitempty"`
 	CardAcquiringPercentage   float64         `json:"cardAcquiringPercentage,omitempty"`
 	DismissedCustomServices   []string        `json:"dismissedCustomServices,omitempty"`
+	// Versions of the price lists; the lists above are the version in effect today
+	Versions []PriceListVersion `json:"versions,omitempty"`
+}
+
+// PriceListVersion is a saved version of a price list, in effect from its
+// date until the next version starts
+type PriceListVersion struct {
+	Version       int             `json:"version"`
+	EffectiveFrom string          `json:"effectiveFrom,omitempty"` // YYYY-MM-DD; empty means since the beginning
+	SavedAt       string          `json:"savedAt,omitempty"`
+	Main          []PriceListItem `json:"main"`
+	Additional    []PriceListItem `json:"additional,omitempty"`
+}
+
+// PriceListChange is a service whose name, price or chemical consumption
+// differs between two versions of a price list
+type PriceListChange struct {
+	ServiceID                   string  `json:"serviceId,omitempty"`
+	ServiceName                 string  `json:"serviceName"`
+	PreviousName                string  `json:"previousName,omitempty"` // set when renamed
+	PreviousPrice               Money   `json:"previousPrice"`
+	Price                       Money   `json:"price"`
+	PreviousChemicalConsumption float64 `json:"previousChemicalConsumption,omitempty"`
+	ChemicalConsumption         float64 `json:"chemicalConsumption,omitempty"`
+}
+
+// PriceListDiff is what changed from one version of a price list to another
+type PriceListDiff struct {
+	FromVersion int               `json:"fromVersion"`
+	ToVersion   int               `json:"toVersion"`
+	Added       []PriceListItem   `json:"added"`
+	Removed     []PriceListItem   `json:"removed"`
+	Changed     []PriceListChange `json:"changed"`
+}
+
+// PriceListImportStatus represents the state of a price list CSV import
+type PriceListImportStatus string
+
+const (
+	PriceListImportPending PriceListImportStatus = "pending"
+	PriceListImportApplied PriceListImportStatus = "applied"
+)
+
+// PriceListImport is an uploaded CSV price list matched against the services
+// of the list it replaces. Nothing changes until the import is applied.
+type PriceListImport struct {
+	ID            string                `json:"id"`
+	Source        RateSource            `json:"source"`
+	SourceName    string                `json:"sourceName"`
+	FileName      string                `json:"fileName,omitempty"`
+	ImportedAt    string                `json:"importedAt"`
+	EffectiveFrom string                `json:"effectiveFrom,omitempty"` // YYYY-MM-DD, the day it is applied when empty
+	Status        PriceListImportStatus `json:"status"`
+	AppliedAt     string                `json:"appliedAt,omitempty"`
+	Main          []PriceListItem       `json:"main"`
+	Additional    []PriceListItem       `json:"additional"`
+	Diff          PriceListDiff         `json:"diff"`
 }
 
 // CounterAgent represents a counter agent
 type CounterAgent struct {
 	ID                  string                `json:"id"`
 	Name                string                `json:"name"`
-	Balance             float64               `json:"balance,omitempty"`
+	Balance             Money                 `json:"balance,omitempty"`
 	Companies           []CounterAgentCompany `json:"companies"`
 	Cars                []Car                 `json:"cars"`
 	PriceList           []PriceListItem       `json:"priceList,omitempty"`
 	AdditionalPriceList []PriceListItem       `json:"additionalPriceList,omitempty"`
 	AllowCustomServices bool                  `json:"allowCustomServices,omitempty"`
+	// Versions of the price lists; the lists above are the version in effect today
+	PriceListVersions []PriceListVersion `json:"priceListVersions,omitempty"`
+	// Credit terms; a zero limit or term means none
+	CreditLimit     Money `json:"creditLimit,omitempty"`
+	PaymentTermDays int   `json:"paymentTermDays,omitempty"`
+	// CreditBlock refuses contract washes over the limit or with overdue debt
+	// instead of warning about them
+	CreditBlock bool `json:"creditBlock,omitempty"`
+}
+
+// CreditStatus is where a counter agent stands against its credit terms.
+// Payments settle the oldest washes first; washes left unpaid longer than the
+// payment term are overdue.
+type CreditStatus struct {
+	CounterAgentID   string `json:"counterAgentId"`
+	CounterAgentName string `json:"counterAgentName"`
+	Debt             Money  `json:"debt"`
+	CreditLimit      Money  `json:"creditLimit,omitempty"`
+	Available        Money  `json:"available,omitempty"`
+	OverLimit        bool   `json:"overLimit"`
+	PaymentTermDays  int    `json:"paymentTermDays,omitempty"`
+	OverdueAmount    Money  `json:"overdueAmount,omitempty"`
+	OverdueSince     string `json:"overdueSince,omitempty"` // oldest unpaid wash past the term
+	Overdue          bool   `json:"overdue"`
+	Blocked          bool   `json:"blocked"`
+}
+
+// RequisitesIssue is a company of a counter agent whose requisites fail
+// validation, with an error message per invalid field
+type RequisitesIssue struct {
+	CounterAgentID   string            `json:"counterAgentId"`
+	CounterAgentName string            `json:"counterAgentName"`
+	CompanyIndex     int               `json:"companyIndex"`
+	CompanyName      string            `json:"companyName"`
+	Errors           map[string]string `json:"errors"`
 }
 
 // NamedPriceList represents a named price list for aggregators
 type NamedPriceList struct {
 	Name     string          `json:"name"`
 	Services []PriceListItem `json:"services"`
+	// Validity schedules the list: it is in effect on the dates any of the
+	// rules match. Unscheduled lists are only used when chosen by hand.
+	Validity []PriceListValidity `json:"validity,omitempty"`
+	// Versions of the list; Services is the version in effect today
+	Versions []PriceListVersion `json:"versions,omitempty"`
+}
+
+// PriceListValidity is a rule for when a price list is in effect: a date
+// range, a season repeating every year, or a season within a date range
+type PriceListValidity struct {
+	ValidFrom  string `json:"validFrom,omitempty"`  // YYYY-MM-DD, inclusive
+	ValidTo    string `json:"validTo,omitempty"`    // YYYY-MM-DD, inclusive
+	SeasonFrom string `json:"seasonFrom,omitempty"` // MM-DD, inclusive
+	SeasonTo   string `json:"seasonTo,omitempty"`   // MM-DD, inclusive; before SeasonFrom wraps over the new year
+}
+
+// PriceListScheduleIssue is a stretch of days on which no scheduled price
+// list or several of them are in effect
+type PriceListScheduleIssue struct {
+	Type       string   `json:"type"` // "gap" or "overlap"
+	From       string   `json:"from"` // YYYY-MM-DD, inclusive
+	To         string   `json:"to"`   // YYYY-MM-DD, inclusive
+	PriceLists []string `json:"priceLists,omitempty"`
 }
 
 // Aggregator represents an aggregator
 type Aggregator struct {
 	ID                  string                `json:"id"`
 	Name                string                `json:"name"`
-	Balance             float64               `json:"balance,omitempty"`
+	Balance             Money                 `json:"balance,omitempty"`
 	Companies           []CounterAgentCompany `json:"companies,omitempty"`
 	Cars                []Car                 `json:"cars"`
 	PriceLists          []NamedPriceList      `json:"priceLists"`
@@ -107,40 +224,76 @@ const (
 type Transaction struct {
 	ID          string      `json:"id"`
 	Date        string      `json:"date"`
-	Amount      float64     `json:"amount"`
+	Amount      Money       `json:"amount"`
 	PaymentType PaymentType `json:"paymentType"`
 	ClientName  string      `json:"clientName"`
 	Notes       string      `json:"notes,omitempty"`
 }
 
+// SalarySchemeAssignment represents the scheme an employee was paid by
+// during a date range. Empty bounds are open-ended.
+type SalarySchemeAssignment struct {
+	SchemeID  string `json:"schemeId"`
+	ValidFrom string `json:"validFrom,omitempty"` // YYYY-MM-DD, inclusive
+	ValidTo   string `json:"validTo,omitempty"`   // YYYY-MM-DD, inclusive
+}
+
 // Employee represents an employee
+// SalarySchemeID is the current scheme; SalarySchemeAssignments keeps the
+// history so past washes are calculated under the scheme in effect then.
 type Employee struct {
-	ID             string `json:"id"`
-	FullName       string `json:"fullName"`
-	Phone          string `json:"phone"`
-	PaymentDetails string `json:"paymentDetails"`
-	HasCar         bool   `json:"hasCar"`
-	Username       string `json:"username,omitempty"`
-	Password       string `json:"password,omitempty"`
-	SalarySchemeID string `json:"salarySchemeId,omitempty"`
+	ID                      string                   `json:"id"`
+	FullName                string                   `json:"fullName"`
+	Phone                   string                   `json:"phone"`
+	PaymentDetails          string                   `json:"paymentDetails"`
+	HasCar                  bool                     `json:"hasCar"`
+	Username                string                   `json:"username,omitempty"`
+	Password                string                   `json:"password,omitempty"`
+	Role                    string                   `json:"role,omitempty"` // EmployeeRoleAdmin for managers
+	SalarySchemeID          string                   `json:"salarySchemeId,omitempty"`
+	SalarySchemeAssignments []SalarySchemeAssignment `json:"salarySchemeAssignments,omitempty"`
 }
 
 // EmployeeWithoutPassword is Employee without password field for API responses
 type EmployeeWithoutPassword struct {
-	ID             string `json:"id"`
-	FullName       string `json:"fullName"`
-	Phone          string `json:"phone"`
-	PaymentDetails string `json:"paymentDetails"`
-	HasCar         bool   `json:"hasCar"`
-	Username       string `json:"username,omitempty"`
-	SalarySchemeID string `json:"salarySchemeId,omitempty"`
+	ID                      string                   `json:"id"`
+	FullName                string                   `json:"fullName"`
+	Phone                   string                   `json:"phone"`
+	PaymentDetails          string                   `json:"paymentDetails"`
+	HasCar                  bool                     `json:"hasCar"`
+	Username                string                   `json:"username,omitempty"`
+	Role                    string                   `json:"role,omitempty"`
+	SalarySchemeID          string                   `json:"salarySchemeId,omitempty"`
+	SalarySchemeAssignments []SalarySchemeAssignment `json:"salarySchemeAssignments,omitempty"`
 }
 
+// EmployeeRoleAdmin is the role of managers, who may override locks and limits
+const EmployeeRoleAdmin = "admin"
+
 // SalaryRate represents a rate for a service
 type SalaryRate struct {
-	ServiceName string  `json:"serviceName"`
-	Rate        float64 `json:"rate"`
-	Deduction   float64 `json:"deduction,omitempty"`
+	ServiceName string `json:"serviceName"`
+	ServiceID   string `json:"serviceId,omitempty"` // matched before the name when both sides have it
+	Rate        Money  `json:"rate"`
+	Deduction   Money  `json:"deduction,omitempty"`
+}
+
+// ServiceRename is a service renamed in a price list
+type ServiceRename struct {
+	ServiceID string `json:"serviceId"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// SalarySchemeDrift describes how a saved price list diverged from the rates
+// of a scheme that uses it as its rate source
+type SalarySchemeDrift struct {
+	SchemeID   string          `json:"schemeId"`
+	SchemeName string          `json:"schemeName"`
+	Renamed    []ServiceRename `json:"renamed,omitempty"`
+	Missing    []string        `json:"missing,omitempty"` // rates for services no longer in the price list
+	Added      []string        `json:"added,omitempty"`   // new services without a rate
+	Propagated bool            `json:"propagated"`        // renames were applied to the scheme
 }
 
 // RateSourceType represents rate source types
@@ -165,17 +318,106 @@ type SalarySchemeType string
 const (
 	SalarySchemePercentage SalarySchemeType = "percentage"
 	SalarySchemeRate       SalarySchemeType = "rate"
+	SalarySchemeHybrid     SalarySchemeType = "hybrid" // base wage + percentage + per-service rates
+)
+
+// BaseWagePeriod represents how often the base wage of a hybrid scheme is paid
+type BaseWagePeriod string
+
+const (
+	BaseWagePerDay   BaseWagePeriod = "day"
+	BaseWagePerShift BaseWagePeriod = "shift"
 )
 
+// SalaryTierBasis represents what is accumulated to pick a tier
+type SalaryTierBasis string
+
+const (
+	SalaryTierByRevenue   SalaryTierBasis = "revenue"
+	SalaryTierByWashCount SalaryTierBasis = "washCount"
+)
+
+// SalaryTierPeriod represents the accumulation period after which tiers start over
+type SalaryTierPeriod string
+
+const (
+	SalaryTierPeriodDay   SalaryTierPeriod = "day"
+	SalaryTierPeriodWeek  SalaryTierPeriod = "week"
+	SalaryTierPeriodMonth SalaryTierPeriod = "month"
+)
+
+// SalaryTier represents a progressive tier of a salary scheme. A tier applies
+// once the accumulated revenue or wash count reaches From. A zero Percentage
+// keeps the percentage of the tier below.
+type SalaryTier struct {
+	Name         string  `json:"name,omitempty"`
+	From         float64 `json:"from"`
+	Percentage   float64 `json:"percentage,omitempty"`
+	BonusPerWash Money   `json:"bonusPerWash,omitempty"`
+}
+
+// ChemicalRule settles chemical consumption against the norms of the services
+// performed. Each working day the employee's recorded grams are compared with
+// their share of the norms; overuse is deducted and savings are paid at the
+// chemical's cost price. Differences within TolerancePercent of the norm are
+// ignored.
+type ChemicalRule struct {
+	DeductOveruse    bool    `json:"deductOveruse,omitempty"`
+	RewardSavings    bool    `json:"rewardSavings,omitempty"`
+	TolerancePercent float64 `json:"tolerancePercent,omitempty"`
+	CostPerKg        Money   `json:"costPerKg,omitempty"` // defaults to the average purchase price
+	// PurchasePrices are the average purchase prices by date used when
+	// CostPerKg is not set; reports fill them in, they are not stored
+	PurchasePrices []ChemicalPrice `json:"-"`
+}
+
+// ChemicalPrice is the average price of chemical bought up to and on From
+type ChemicalPrice struct {
+	From      string // YYYY-MM-DD
+	CostPerKg Money
+}
+
 // SalaryScheme represents a salary scheme
+// Tiers raise the percentage (or add a per-wash bonus) as the employee's
+// revenue or wash count grows within TierPeriod; Percentage is the rate
+// below the first tier.
+// GuaranteedDailyMinimum and DailyCap apply to any scheme type and are
+// evaluated per working day on the sum of all components.
 type SalaryScheme struct {
-	ID             string           `json:"id"`
-	Name           string           `json:"name"`
-	Type           SalarySchemeType `json:"type"`
-	Percentage     float64          `json:"percentage,omitempty"`
-	FixedDeduction float64          `json:"fixedDeduction,omitempty"`
-	RateSource     *RateSource      `json:"rateSource,omitempty"`
-	Rates          []SalaryRate     `json:"rates,omitempty"`
+	ID                     string           `json:"id"`
+	Name                   string           `json:"name"`
+	Type                   SalarySchemeType `json:"type"`
+	Percentage             float64          `json:"percentage,omitempty"`
+	FixedDeduction         Money            `json:"fixedDeduction,omitempty"`
+	RateSource             *RateSource      `json:"rateSource,omitempty"`
+	Rates                  []SalaryRate     `json:"rates,omitempty"`
+	BaseWage               Money            `json:"baseWage,omitempty"`
+	BaseWagePeriod         BaseWagePeriod   `json:"baseWagePeriod,omitempty"`
+	GuaranteedDailyMinimum Money            `json:"guaranteedDailyMinimum,omitempty"`
+	DailyCap               Money            `json:"dailyCap,omitempty"`
+	Tiers                  []SalaryTier     `json:"tiers,omitempty"`
+	TierBasis              SalaryTierBasis  `json:"tierBasis,omitempty"`
+	TierPeriod             SalaryTierPeriod `json:"tierPeriod,omitempty"`
+	ChemicalRule           *ChemicalRule    `json:"chemicalRule,omitempty"`
+	Version                int              `json:"version,omitempty"`
+	EffectiveFrom          string           `json:"effectiveFrom,omitempty"` // YYYY-MM-DD; empty means since the beginning
+	PreviousVersions       []SalaryScheme   `json:"previousVersions,omitempty"`
+	// Archived schemes were deleted after closed payroll periods were
+	// calculated with them; they are kept for those periods but no longer
+	// listed or assigned
+	Archived bool `json:"archived,omitempty"`
+}
+
+// SalaryRateGap is a service performed under a rate scheme that has no rate
+// for it, so the washers were not paid for it
+type SalaryRateGap struct {
+	ServiceName  string `json:"serviceName"`
+	Count        int    `json:"count"`
+	Revenue      Money  `json:"revenue"`
+	AveragePrice Money  `json:"averagePrice"`
+	SourcePrice  Money  `json:"sourcePrice,omitempty"` // price in the scheme's rate source price list
+	FirstSeen    string `json:"firstSeen"`
+	LastSeen     string `json:"lastSeen"`
 }
 
 // WashComment represents a comment on a wash event
@@ -197,10 +439,10 @@ type WashEventEditHistory struct {
 type WashPaymentMethod string
 
 const (
-	WashPaymentCash                WashPaymentMethod = "cash"
-	WashPaymentCard                WashPaymentMethod = "card"
-	WashPaymentTransfer            WashPaymentMethod = "transfer"
-	WashPaymentAggregator          WashPaymentMethod = "aggregator"
+	WashPaymentCash                 WashPaymentMethod = "cash"
+	WashPaymentCard                 WashPaymentMethod = "card"
+	WashPaymentTransfer             WashPaymentMethod = "transfer"
+	WashPaymentAggregator           WashPaymentMethod = "aggregator"
 	WashPaymentCounterAgentContract WashPaymentMethod = "counterAgentContract"
 )
 
@@ -212,20 +454,63 @@ type WashServices struct {
 
 // WashEvent represents a wash event
 type WashEvent struct {
-	ID             string                 `json:"id"`
-	Timestamp      string                 `json:"timestamp"`
-	VehicleNumber  string                 `json:"vehicleNumber"`
-	EmployeeIDs    []string               `json:"employeeIds"`
-	PaymentMethod  WashPaymentMethod      `json:"paymentMethod"`
-	SourceID       string                 `json:"sourceId,omitempty"`
-	SourceName     string                 `json:"sourceName,omitempty"`
-	PriceListName  string                 `json:"priceListName,omitempty"`
-	TotalAmount    float64                `json:"totalAmount"`
-	NetAmount      float64                `json:"netAmount,omitempty"`
-	AcquiringFee   float64                `json:"acquiringFee,omitempty"`
-	Services       WashServices           `json:"services"`
-	DriverComments []WashComment          `json:"driverComments,omitempty"`
-	EditHistory    []WashEventEditHistory `json:"editHistory,omitempty"`
+	ID                 string                 `json:"id"`
+	Timestamp          string                 `json:"timestamp"`
+	VehicleNumber      string                 `json:"vehicleNumber"`
+	EmployeeIDs        []string               `json:"employeeIds"`
+	PaymentMethod      WashPaymentMethod      `json:"paymentMethod"`
+	SourceID           string                 `json:"sourceId,omitempty"`
+	SourceName         string                 `json:"sourceName,omitempty"`
+	PriceListName      string                 `json:"priceListName,omitempty"`
+	PriceListScheduled bool                   `json:"priceListScheduled,omitempty"` // list taken from the aggregator's schedule
+	TotalAmount        Money                  `json:"totalAmount"`
+	NetAmount          Money                  `json:"netAmount,omitempty"`
+	AcquiringFee       Money                  `json:"acquiringFee,omitempty"`
+	Services           WashServices           `json:"services"`
+	DriverComments     []WashComment          `json:"driverComments,omitempty"`
+	EditHistory        []WashEventEditHistory `json:"editHistory,omitempty"`
+	ShiftID            string                 `json:"shiftId,omitempty"`
+}
+
+// ShiftStatus represents shift statuses
+type ShiftStatus string
+
+const (
+	ShiftOpen   ShiftStatus = "open"
+	ShiftClosed ShiftStatus = "closed"
+)
+
+// ShiftTotals represents expected takings of a shift calculated from its wash events
+type ShiftTotals struct {
+	WashCount         int   `json:"washCount"`
+	CashWashCount     int   `json:"cashWashCount"`
+	CardWashCount     int   `json:"cardWashCount"`
+	ExpectedCash      Money `json:"expectedCash"`
+	ExpectedCardGross Money `json:"expectedCardGross"`
+	AcquiringFees     Money `json:"acquiringFees"`
+	ExpectedCardNet   Money `json:"expectedCardNet"`
+	TransferTotal     Money `json:"transferTotal"`
+	NonCashTotal      Money `json:"nonCashTotal"` // aggregator and counter agent contract washes
+}
+
+// Shift represents a work shift with cash reconciliation on close
+type Shift struct {
+	ID              string       `json:"id"`
+	Status          ShiftStatus  `json:"status"`
+	OpenedAt        string       `json:"openedAt"`
+	OpenedBy        string       `json:"openedBy,omitempty"`
+	ClosedAt        string       `json:"closedAt,omitempty"`
+	ClosedBy        string       `json:"closedBy,omitempty"`
+	EmployeeIDs     []string     `json:"employeeIds"`
+	OpeningCash     Money        `json:"openingCash"`
+	WashEventIDs    []string     `json:"washEventIds,omitempty"`
+	Totals          *ShiftTotals `json:"totals,omitempty"`
+	CountedCash     Money        `json:"countedCash,omitempty"`
+	CountedCard     *Money       `json:"countedCard,omitempty"`
+	CashDiscrepancy Money        `json:"cashDiscrepancy,omitempty"`
+	CardDiscrepancy Money        `json:"cardDiscrepancy,omitempty"`
+	HasDiscrepancy  bool         `json:"hasDiscrepancy,omitempty"`
+	Notes           string       `json:"notes,omitempty"`
 }
 
 // EmployeeTransactionType represents employee transaction types
@@ -236,6 +521,10 @@ const (
 	EmpTransLoan     EmployeeTransactionType = "loan"
 	EmpTransBonus    EmployeeTransactionType = "bonus"
 	EmpTransPurchase EmployeeTransactionType = "purchase"
+	EmpTransFine     EmployeeTransactionType = "fine"
+
+	// EmpTransLoanRepayment is withheld from salary towards a loan with a repayment plan
+	EmpTransLoanRepayment EmployeeTransactionType = "loanRepayment"
 )
 
 // EmployeeTransaction represents an employee transaction
@@ -244,8 +533,56 @@ type EmployeeTransaction struct {
 	EmployeeID  string                  `json:"employeeId"`
 	Date        string                  `json:"date"`
 	Type        EmployeeTransactionType `json:"type"`
-	Amount      float64                 `json:"amount"`
+	Amount      Money                   `json:"amount"`
 	Description string                  `json:"description"`
+
+	// Repayment makes a loan repaid from salary instead of settled at once
+	Repayment *LoanRepaymentPlan `json:"repayment,omitempty"`
+	// LoanID is the loan a repayment settles
+	LoanID string `json:"loanId,omitempty"`
+	// PayrollPeriodID is the payroll period whose settlement produced the entry
+	PayrollPeriodID string `json:"payrollPeriodId,omitempty"`
+}
+
+// LoanRepaymentType represents how a loan is repaid from salary
+type LoanRepaymentType string
+
+const (
+	LoanRepaymentFixed      LoanRepaymentType = "fixed"
+	LoanRepaymentPercentage LoanRepaymentType = "percentage"
+)
+
+// LoanRepaymentPlan is withheld from the employee's salary every payroll
+// period until the loan is repaid
+type LoanRepaymentPlan struct {
+	Type       LoanRepaymentType `json:"type"`
+	Amount     Money             `json:"amount,omitempty"`     // per payroll period
+	Percentage float64           `json:"percentage,omitempty"` // of the period earnings
+}
+
+// LoanSettings limits new loans relative to the employee's expected earnings
+type LoanSettings struct {
+	LimitPercent           float64 `json:"limitPercent"`           // of expected monthly earnings; 0 disables the limit
+	ExpectedEarningsMonths int     `json:"expectedEarningsMonths"` // full months averaged for expected earnings
+}
+
+// EmployeeLoan is a loan with a repayment plan and its repayments so far
+type EmployeeLoan struct {
+	Loan        EmployeeTransaction   `json:"loan"`
+	Repaid      Money                 `json:"repaid"`
+	Outstanding Money                 `json:"outstanding"`
+	Repayments  []EmployeeTransaction `json:"repayments"`
+}
+
+// EmployeeLoansData summarises an employee's loans against the loan limit
+type EmployeeLoansData struct {
+	EmployeeID       string         `json:"employeeId"`
+	EmployeeName     string         `json:"employeeName"`
+	Outstanding      Money          `json:"outstanding"`
+	ExpectedEarnings Money          `json:"expectedEarnings"`
+	Limited          bool           `json:"limited"`
+	Limit            Money          `json:"limit"` // most the employee may owe when limited
+	Loans            []EmployeeLoan `json:"loans"`
 }
 
 // EmployeeTransactionsFile represents the structure of employee transactions file
@@ -255,12 +592,17 @@ type EmployeeTransactionsFile struct {
 
 // ClientTransaction represents a client transaction
 type ClientTransaction struct {
-	ID          string  `json:"id"`
-	ClientID    string  `json:"clientId"`
-	Date        string  `json:"date"`
-	Type        string  `json:"type"` // always "payment"
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
+	ID          string `json:"id"`
+	ClientID    string `json:"clientId"`
+	Date        string `json:"date"`
+	Type        string `json:"type"` // always "payment"
+	Amount      Money  `json:"amount"`
+	Description string `json:"description"`
+	// BankDocument identifies the bank payment the transaction was posted
+	// from, so a statement imported twice does not post it again
+	BankDocument string `json:"bankDocument,omitempty"`
+	// CompanyName is the client's legal entity that paid, when known
+	CompanyName string `json:"companyName,omitempty"`
 }
 
 // ClientTransactionsFile represents the structure of client transactions file
@@ -274,10 +616,35 @@ type Expense struct {
 	Date         string  `json:"date"`
 	Category     string  `json:"category"`
 	Description  string  `json:"description"`
-	Amount       float64 `json:"amount"`
+	Amount       Money   `json:"amount"`
 	Quantity     float64 `json:"quantity,omitempty"`
 	Unit         string  `json:"unit,omitempty"`
-	PricePerUnit float64 `json:"pricePerUnit,omitempty"`
+	PricePerUnit Money   `json:"pricePerUnit,omitempty"`
+}
+
+// ServiceChemicalConsumption compares recorded and norm consumption of one service
+type ServiceChemicalConsumption struct {
+	ServiceName   string  `json:"serviceName"`
+	Count         int     `json:"count"`
+	NormGrams     float64 `json:"normGrams"`
+	ActualGrams   float64 `json:"actualGrams"`
+	VarianceGrams float64 `json:"varianceGrams"`
+}
+
+// ChemicalConsumptionData compares an employee's recorded chemical consumption
+// with the norms of the services they performed. Positive variance is overuse.
+// UnnormedGrams were recorded on services without a norm and are not compared.
+type ChemicalConsumptionData struct {
+	EmployeeID      string                       `json:"employeeId"`
+	EmployeeName    string                       `json:"employeeName"`
+	WashCount       int                          `json:"washCount"`
+	NormGrams       float64                      `json:"normGrams"`
+	ActualGrams     float64                      `json:"actualGrams"`
+	VarianceGrams   float64                      `json:"varianceGrams"`
+	VariancePercent float64                      `json:"variancePercent"`
+	VarianceCost    Money                        `json:"varianceCost"`
+	UnnormedGrams   float64                      `json:"unnormedGrams"`
+	Services        []ServiceChemicalConsumption `json:"services"`
 }
 
 // Inventory represents chemical inventory
@@ -285,19 +652,334 @@ type Inventory struct {
 	ChemicalStockGrams float64 `json:"chemicalStockGrams"`
 }
 
+// SalaryComponentType represents the parts a salary is made of
+type SalaryComponentType string
+
+const (
+	SalaryComponentPercentage   SalaryComponentType = "percentage"
+	SalaryComponentRate         SalaryComponentType = "rate"
+	SalaryComponentBaseWage     SalaryComponentType = "baseWage"
+	SalaryComponentMinimumTopUp SalaryComponentType = "minimumTopUp"
+	SalaryComponentCap          SalaryComponentType = "cap"
+	SalaryComponentTierBonus    SalaryComponentType = "tierBonus"
+	SalaryComponentChemOveruse  SalaryComponentType = "chemicalOveruse"
+	SalaryComponentChemSavings  SalaryComponentType = "chemicalSavings"
+)
+
+// SalaryComponent represents one component of the earnings for a wash or an adjustment
+type SalaryComponent struct {
+	Type   SalaryComponentType `json:"type"`
+	Amount Money               `json:"amount"`
+	Tier   string              `json:"tier,omitempty"`
+}
+
 // SalaryBreakdownItem represents a breakdown item in salary report
 type SalaryBreakdownItem struct {
-	WashEventID    string   `json:"washEventId"`
-	Timestamp      string   `json:"timestamp"`
-	VehicleNumber  string   `json:"vehicleNumber"`
-	Earnings       float64  `json:"earnings"`
-	UnpaidServices []string `json:"unpaidServices"`
+	WashEventID    string            `json:"washEventId"`
+	Timestamp      string            `json:"timestamp"`
+	VehicleNumber  string            `json:"vehicleNumber"`
+	Earnings       Money             `json:"earnings"`
+	UnpaidServices []string          `json:"unpaidServices"`
+	Components     []SalaryComponent `json:"components,omitempty"`
+	Tier           string            `json:"tier,omitempty"`
+}
+
+// SalaryAdjustment represents earnings not tied to a single wash, such as a
+// base wage or a guaranteed minimum top-up for a working day
+type SalaryAdjustment struct {
+	Type        SalaryComponentType `json:"type"`
+	Timestamp   string              `json:"timestamp"`
+	Date        string              `json:"date"`
+	Amount      Money               `json:"amount"`
+	Description string              `json:"description,omitempty"`
 }
 
 // SalaryReportData represents salary report data
+// Balances follow the finance page convention: earnings and bonuses are owed
+// to the employee, payouts, loans, purchases and fines reduce what is owed.
 type SalaryReportData struct {
-	EmployeeID    string                `json:"employeeId"`
-	EmployeeName  string                `json:"employeeName"`
-	TotalEarnings float64               `json:"totalEarnings"`
-	Breakdown     []SalaryBreakdownItem `json:"breakdown"`
+	EmployeeID     string                `json:"employeeId"`
+	EmployeeName   string                `json:"employeeName"`
+	TotalEarnings  Money                 `json:"totalEarnings"`
+	Breakdown      []SalaryBreakdownItem `json:"breakdown"`
+	Adjustments    []SalaryAdjustment    `json:"adjustments,omitempty"`
+	OpeningBalance Money                 `json:"openingBalance"`
+	Bonuses        Money                 `json:"bonuses"`
+	Payouts        Money                 `json:"payouts"`
+	Loans          Money                 `json:"loans"`
+	Purchases      Money                 `json:"purchases"`
+	Fines          Money                 `json:"fines"`
+	LoanRepayments Money                 `json:"loanRepayments"`
+	ClosingBalance Money                 `json:"closingBalance"`
+	Transactions   []EmployeeTransaction `json:"transactions,omitempty"`
+}
+
+// SalarySimulationItem compares an employee's earnings for a period under
+// the current and the proposed salary rules
+type SalarySimulationItem struct {
+	EmployeeID        string `json:"employeeId"`
+	EmployeeName      string `json:"employeeName"`
+	CurrentEarnings   Money  `json:"currentEarnings"`
+	ProposedEarnings  Money  `json:"proposedEarnings"`
+	Delta             Money  `json:"delta"`
+	CurrentWashCount  int    `json:"currentWashCount"`
+	ProposedWashCount int    `json:"proposedWashCount"`
+}
+
+// SalarySimulationResult is the outcome of replaying wash history through a
+// proposed salary scheme
+type SalarySimulationResult struct {
+	Scheme        SalaryScheme           `json:"scheme"`
+	From          string                 `json:"from,omitempty"`
+	To            string                 `json:"to,omitempty"`
+	Employees     []SalarySimulationItem `json:"employees"`
+	CurrentTotal  Money                  `json:"currentTotal"`
+	ProposedTotal Money                  `json:"proposedTotal"`
+	Delta         Money                  `json:"delta"`
+}
+
+// PayrollPeriodStatus represents payroll period statuses
+type PayrollPeriodStatus string
+
+const (
+	PayrollPeriodOpen   PayrollPeriodStatus = "open"
+	PayrollPeriodClosed PayrollPeriodStatus = "closed"
+)
+
+// PayrollPeriod represents a salary settlement period. Once closed, the
+// calculated report is frozen and data dated within the period is locked.
+type PayrollPeriod struct {
+	ID         string              `json:"id"`
+	Name       string              `json:"name,omitempty"`
+	From       string              `json:"from"` // YYYY-MM-DD, inclusive
+	To         string              `json:"to"`   // YYYY-MM-DD, inclusive
+	Status     PayrollPeriodStatus `json:"status"`
+	CreatedAt  string              `json:"createdAt"`
+	ClosedAt   string              `json:"closedAt,omitempty"`
+	ClosedBy   string              `json:"closedBy,omitempty"`
+	ReopenedAt string              `json:"reopenedAt,omitempty"`
+	ReopenedBy string              `json:"reopenedBy,omitempty"`
+	SchemeIDs  []string            `json:"schemeIds,omitempty"`
+	Snapshot   []SalaryReportData  `json:"snapshot,omitempty"`
+}
+
+// InvoiceStatus represents invoice statuses
+type InvoiceStatus string
+
+const (
+	InvoiceDraft     InvoiceStatus = "draft"
+	InvoiceIssued    InvoiceStatus = "issued"
+	InvoicePaid      InvoiceStatus = "paid"
+	InvoiceCancelled InvoiceStatus = "cancelled"
+)
+
+// InvoiceClientType represents the kind of client an invoice is made out to
+type InvoiceClientType string
+
+const (
+	InvoiceClientCounterAgent InvoiceClientType = "counterAgent"
+	InvoiceClientAggregator   InvoiceClientType = "aggregator"
+)
+
+// InvoiceLine is a service billed on an invoice
+type InvoiceLine struct {
+	ServiceName string `json:"serviceName"`
+	Quantity    int    `json:"quantity"`
+	Unit        string `json:"unit"`
+	Price       Money  `json:"price"`
+	Amount      Money  `json:"amount"`
+}
+
+// Invoice is an invoice with its act of services rendered for the washes of
+// a counter agent or aggregator over a date range. Issued invoices are
+// numbered without gaps within a year and keep the requisites they were
+// issued with.
+type Invoice struct {
+	ID           string              `json:"id"`
+	Number       int                 `json:"number,omitempty"` // assigned on issue
+	Year         int                 `json:"year,omitempty"`
+	Date         string              `json:"date"` // YYYY-MM-DD
+	Status       InvoiceStatus       `json:"status"`
+	ClientType   InvoiceClientType   `json:"clientType"`
+	ClientID     string              `json:"clientId"`
+	ClientName   string              `json:"clientName"`
+	From         string              `json:"from"` // YYYY-MM-DD, inclusive
+	To           string              `json:"to"`   // YYYY-MM-DD, inclusive
+	Seller       CounterAgentCompany `json:"seller"`
+	Customer     CounterAgentCompany `json:"customer"`
+	Lines        []InvoiceLine       `json:"lines"`
+	Total        Money               `json:"total"`
+	WashEventIDs []string            `json:"washEventIds,omitempty"`
+	CreatedAt    string              `json:"createdAt"`
+	IssuedAt     string              `json:"issuedAt,omitempty"`
+	PaidAt       string              `json:"paidAt,omitempty"`
+	CancelledAt  string              `json:"cancelledAt,omitempty"`
+	CancelReason string              `json:"cancelReason,omitempty"`
+}
+
+// ReconciliationEntry is a line of a reconciliation statement: services
+// rendered are debited to the client, payments credited
+type ReconciliationEntry struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	Document string `json:"document"`
+	Debit    Money  `json:"debit,omitempty"`
+	Credit   Money  `json:"credit,omitempty"`
+}
+
+// Reconciliation is a statement of mutual settlements (акт сверки) with a
+// counter agent or aggregator for a period. Balances are the client's
+// account, as in ClientStatement and the client's Balance: payments add to
+// it, washes are taken from it, so a negative balance is a debt and a
+// positive one an advance.
+type Reconciliation struct {
+	ClientType     InvoiceClientType     `json:"clientType"`
+	ClientID       string                `json:"clientId"`
+	ClientName     string                `json:"clientName"`
+	From           string                `json:"from"` // YYYY-MM-DD, inclusive
+	To             string                `json:"to"`   // YYYY-MM-DD, inclusive
+	Seller         CounterAgentCompany   `json:"seller"`
+	Customer       CounterAgentCompany   `json:"customer"`
+	OpeningBalance Money                 `json:"openingBalance"`
+	Entries        []ReconciliationEntry `json:"entries"`
+	DebitTotal     Money                 `json:"debitTotal"`
+	CreditTotal    Money                 `json:"creditTotal"`
+	ClosingBalance Money                 `json:"closingBalance"`
+}
+
+// BankPaymentStatus represents the state of a payment in a bank statement import
+type BankPaymentStatus string
+
+const (
+	BankPaymentMatched   BankPaymentStatus = "matched"   // client found, awaiting confirmation
+	BankPaymentUnmatched BankPaymentStatus = "unmatched" // left for manual assignment
+	BankPaymentPosted    BankPaymentStatus = "posted"
+	BankPaymentIgnored   BankPaymentStatus = "ignored"
+	BankPaymentDuplicate BankPaymentStatus = "duplicate" // already posted from an earlier statement
+)
+
+// BankPayment is an incoming payment read from a bank statement
+type BankPayment struct {
+	ID             string            `json:"id"`
+	DocumentNumber string            `json:"documentNumber"`
+	Date           string            `json:"date"` // YYYY-MM-DD
+	Amount         Money             `json:"amount"`
+	PayerName      string            `json:"payerName"`
+	PayerINN       string            `json:"payerInn,omitempty"`
+	PayerKPP       string            `json:"payerKpp,omitempty"`
+	PayerAccount   string            `json:"payerAccount,omitempty"`
+	Purpose        string            `json:"purpose,omitempty"`
+	Status         BankPaymentStatus `json:"status"`
+	ClientType     InvoiceClientType `json:"clientType,omitempty"`
+	ClientID       string            `json:"clientId,omitempty"`
+	ClientName     string            `json:"clientName,omitempty"`
+	MatchedBy      string            `json:"matchedBy,omitempty"` // "account", "inn", "inn+kpp" or "manual"
+	TransactionID  string            `json:"transactionId,omitempty"`
+}
+
+// BankStatementImport is an uploaded bank statement in the 1C
+// ClientBankExchange format with the incoming payments proposed for posting
+type BankStatementImport struct {
+	ID         string        `json:"id"`
+	FileName   string        `json:"fileName,omitempty"`
+	ImportedAt string        `json:"importedAt"`
+	Account    string        `json:"account,omitempty"`
+	DateFrom   string        `json:"dateFrom,omitempty"` // YYYY-MM-DD
+	DateTo     string        `json:"dateTo,omitempty"`   // YYYY-MM-DD
+	Payments   []BankPayment `json:"payments"`
+}
+
+// AggregatorRegisterItemStatus represents how an aggregator register row
+// compares with our wash log
+type AggregatorRegisterItemStatus string
+
+const (
+	AggregatorRegisterMatched       AggregatorRegisterItemStatus = "matched"
+	AggregatorRegisterMissingOurs   AggregatorRegisterItemStatus = "missingOurs"   // in the register, not in our log
+	AggregatorRegisterMissingTheirs AggregatorRegisterItemStatus = "missingTheirs" // in our log, not in the register
+	AggregatorRegisterPriceMismatch AggregatorRegisterItemStatus = "priceMismatch"
+)
+
+// AggregatorRegisterItem is a register row, one of our washes, or a row
+// paired with a wash
+type AggregatorRegisterItem struct {
+	ID              string                       `json:"id"`
+	Status          AggregatorRegisterItemStatus `json:"status"`
+	Row             int                          `json:"row,omitempty"` // line of the register file
+	VehicleNumber   string                       `json:"vehicleNumber"`
+	Date            string                       `json:"date"` // YYYY-MM-DD
+	ServiceName     string                       `json:"serviceName,omitempty"`
+	Amount          Money                        `json:"amount"` // what the aggregator pays
+	WashEventID     string                       `json:"washEventId,omitempty"`
+	WashServiceName string                       `json:"washServiceName,omitempty"`
+	WashAmount      Money                        `json:"washAmount,omitempty"`
+	ServiceMismatch bool                         `json:"serviceMismatch,omitempty"`
+	Resolution      string                       `json:"resolution,omitempty"` // "created", "adjusted" or "ignored"
+	ResolvedAt      string                       `json:"resolvedAt,omitempty"`
+	ResolvedReason  string                       `json:"resolvedReason,omitempty"`
+}
+
+// AggregatorRegister is an uploaded register of the washes an aggregator
+// pays for, reconciled against our aggregator-paid washes
+type AggregatorRegister struct {
+	ID             string                   `json:"id"`
+	AggregatorID   string                   `json:"aggregatorId"`
+	AggregatorName string                   `json:"aggregatorName"`
+	FileName       string                   `json:"fileName,omitempty"`
+	ImportedAt     string                   `json:"importedAt"`
+	DateFrom       string                   `json:"dateFrom"` // YYYY-MM-DD
+	DateTo         string                   `json:"dateTo"`   // YYYY-MM-DD
+	Items          []AggregatorRegisterItem `json:"items"`
+}
+
+// ClientStatementEntry is a wash debited to or a payment credited to a
+// client's account, with the balance after it
+type ClientStatementEntry struct {
+	Timestamp     string   `json:"timestamp"`
+	Type          string   `json:"type"` // "wash" or "payment"
+	ID            string   `json:"id"`   // wash event or client transaction ID
+	VehicleNumber string   `json:"vehicleNumber,omitempty"`
+	Services      []string `json:"services,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Debit         Money    `json:"debit,omitempty"`
+	Credit        Money    `json:"credit,omitempty"`
+	Balance       Money    `json:"balance"`
+}
+
+// ClientStatement is the ledger of a counter agent's or aggregator's washes
+// and payments. Balances are the client's account, as in Reconciliation and
+// the client's Balance: payments add to it, washes are taken from it, so a
+// negative balance is a debt and a positive one an advance.
+type ClientStatement struct {
+	ClientType     InvoiceClientType      `json:"clientType"`
+	ClientID       string                 `json:"clientId"`
+	ClientName     string                 `json:"clientName"`
+	From           string                 `json:"from,omitempty"`
+	To             string                 `json:"to,omitempty"`
+	OpeningBalance Money                  `json:"openingBalance"`
+	Entries        []ClientStatementEntry `json:"entries"`
+	DebitTotal     Money                  `json:"debitTotal"`
+	CreditTotal    Money                  `json:"creditTotal"`
+	ClosingBalance Money                  `json:"closingBalance"`
+}
+
+// AuditLogEntry represents a recorded administrative action
+type AuditLogEntry struct {
+	ID         string                 `json:"id"`
+	Timestamp  string                 `json:"timestamp"`
+	ActorID    string                 `json:"actorId,omitempty"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entityType"`
+	EntityID   string                 `json:"entityId"`
+	Reason     string                 `json:"reason,omitempty"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// AuditLogFile represents the structure of the audit log file
+type AuditLogFile struct {
+	Entries []AuditLogEntry `json:"entries"`
+}
+
+// SessionSecretFile holds the key session cookies are signed with
+type SessionSecretFile struct {
+	Key []byte `json:"key"`
 }
@@ -0,0 +1,235 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in kopecks. In JSON it is written as a decimal number
+// of rubles (1500, 1234.5), so data files written with float amounts are
+// read without changes.
+type Money int64
+
+// RoundingMode selects how fractions of a kopeck are rounded
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds halves to the even kopeck (banker's rounding)
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds halves away from zero
+	RoundHalfUp
+	// RoundDown drops fractions of a kopeck, rounding toward zero
+	RoundDown
+)
+
+// MaxMoney is the largest amount accepted from input, ten trillion rubles.
+// It leaves room for sums and products of amounts to stay within int64.
+const MaxMoney Money = 10_000_000_000_000_00
+
+// maxExponent limits exponents in parsed amounts such as "1e3"
+const maxExponent = 20
+
+// decimalPattern matches a plain decimal number; the group is the exponent.
+// Fractions, hexadecimal and binary exponents accepted by big.Rat are not.
+var decimalPattern = regexp.MustCompile(`^[+-]?(?:\d+\.?\d*|\.\d+)(?:[eE]([+-]?\d+))?$`)
+
+// Rubles returns a whole number of rubles as Money
+func Rubles(rubles int64) Money {
+	return Money(rubles * 100)
+}
+
+// ParseMoney parses a decimal amount of rubles such as "1234.56" or "1e3".
+// Digits beyond kopecks are rounded half to even. Amounts beyond MaxMoney
+// are rejected.
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
+	match := decimalPattern.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+	if match[1] != "" {
+		if exp, err := strconv.Atoi(match[1]); err != nil || exp > maxExponent || exp < -maxExponent {
+			return 0, fmt.Errorf("invalid amount: %q", s)
+		}
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+	m, err := roundRat(r.Mul(r, big.NewRat(100, 1)), RoundHalfEven)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q: %w", s, err)
+	}
+	return m, nil
+}
+
+// MoneyFromFloat converts a float amount of rubles, rounding half to even.
+// The shortest decimal form of the float is used, so 0.1 is exactly 10 kopecks.
+func MoneyFromFloat(f float64) Money {
+	m, err := ParseMoney(strconv.FormatFloat(f, 'f', -1, 64))
+	if err != nil {
+		return 0
+	}
+	return m
+}
+
+// Kopecks returns the amount in kopecks
+func (m Money) Kopecks() int64 {
+	return int64(m)
+}
+
+// Float64 returns the amount in rubles. Use it only for display or ratios.
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimals, e.g. "-12.05"
+func (m Money) String() string {
+	sign := ""
+	k := int64(m)
+	if k < 0 {
+		sign = "-"
+		k = -k
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, k/100, k%100)
+}
+
+// Abs returns the absolute amount
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Mul multiplies the amount by a factor such as a quantity. Products out of
+// range give 0.
+func (m Money) Mul(factor float64, mode RoundingMode) Money {
+	f, ok := new(big.Rat).SetString(strconv.FormatFloat(factor, 'f', -1, 64))
+	if !ok {
+		return 0
+	}
+	result, err := roundRat(f.Mul(f, new(big.Rat).SetInt64(int64(m))), mode)
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// MulPercent returns the given percentage of the amount. Results out of
+// range give 0.
+func (m Money) MulPercent(percent float64, mode RoundingMode) Money {
+	p, ok := new(big.Rat).SetString(strconv.FormatFloat(percent, 'f', -1, 64))
+	if !ok {
+		return 0
+	}
+	p.Mul(p, new(big.Rat).SetInt64(int64(m)))
+	result, err := roundRat(p.Quo(p, big.NewRat(100, 1)), mode)
+	if err != nil {
+		return 0
+	}
+	return result
+}
+
+// Allocate splits the amount into n shares that add up exactly to it. The
+// kopecks left over after an even split go one each to the first shares.
+func (m Money) Allocate(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	shares := make([]Money, n)
+	base := m / Money(n)
+	remainder := m - base*Money(n)
+
+	step := Money(1)
+	if remainder < 0 {
+		step = -1
+		remainder = -remainder
+	}
+	for i := range shares {
+		shares[i] = base
+		if Money(i) < remainder {
+			shares[i] += step
+		}
+	}
+	return shares
+}
+
+// MarshalJSON writes the amount as a decimal number of rubles
+func (m Money) MarshalJSON() ([]byte, error) {
+	s := m.String()
+	switch {
+	case m%100 == 0:
+		s = s[:len(s)-3]
+	case m%10 == 0:
+		s = s[:len(s)-1]
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalJSON reads a number or a numeric string of rubles
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			*m = 0
+			return nil
+		}
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// roundRat rounds a rational number of kopecks to a whole kopeck. Amounts
+// beyond MaxMoney are an error.
+func roundRat(r *big.Rat, mode RoundingMode) (Money, error) {
+	num := r.Num()
+	den := r.Denom()
+
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() != 0 && mode != RoundDown {
+		roundAway(quo, rem, den, num.Sign(), mode)
+	}
+	if !quo.IsInt64() || quo.Int64() > int64(MaxMoney) || quo.Int64() < -int64(MaxMoney) {
+		return 0, fmt.Errorf("out of range")
+	}
+	return Money(quo.Int64()), nil
+}
+
+// roundAway moves quo one kopeck away from zero when the remainder calls for it
+func roundAway(quo, rem, den *big.Int, sign int, mode RoundingMode) {
+	// Compare twice the remainder with the denominator to find halves
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	cmp := twice.Cmp(den)
+
+	away := cmp > 0
+	if cmp == 0 {
+		away = mode == RoundHalfUp || quo.Bit(0) == 1
+	}
+	if away {
+		if sign < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+}
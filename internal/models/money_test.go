@@ -0,0 +1,127 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "1234.56", want: 123456},
+		{in: "1234,56", want: 123456},
+		{in: " 15 ", want: 1500},
+		{in: "-12.05", want: -1205},
+		{in: "1e3", want: 100000},
+		{in: "1.5E-1", want: 15},
+		{in: ".5", want: 50},
+		{in: "0.125", want: 12},
+		{in: "0.135", want: 14},
+		{in: "10000000000000", want: MaxMoney},
+		{in: "-10000000000000", want: -MaxMoney},
+		{in: "10000000000000.01", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "1e100000", wantErr: true},
+		{in: "1e-100000", wantErr: true},
+		{in: "1e21", wantErr: true},
+		{in: "1/3", wantErr: true},
+		{in: "0x10", wantErr: true},
+		{in: "1p3", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: `1500`, want: 150000},
+		{in: `"1234.5"`, want: 123450},
+		{in: `""`, want: 0},
+		{in: `99999999999999999999`, wantErr: true},
+		{in: `"1/3"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyRounding(t *testing.T) {
+	tests := []struct {
+		amount  Money
+		percent float64
+		mode    RoundingMode
+		want    Money
+	}{
+		{amount: 250, percent: 1, mode: RoundHalfEven, want: 2},
+		{amount: 250, percent: 1, mode: RoundHalfUp, want: 3},
+		{amount: 250, percent: 1, mode: RoundDown, want: 2},
+		{amount: 350, percent: 1, mode: RoundHalfEven, want: 4},
+		{amount: -250, percent: 1, mode: RoundHalfEven, want: -2},
+		{amount: -250, percent: 1, mode: RoundHalfUp, want: -3},
+		{amount: -299, percent: 1, mode: RoundDown, want: -2},
+		{amount: 299, percent: 1, mode: RoundHalfEven, want: 3},
+		{amount: 100000, percent: 12.5, mode: RoundHalfEven, want: 12500},
+	}
+	for _, tt := range tests {
+		if got := tt.amount.MulPercent(tt.percent, tt.mode); got != tt.want {
+			t.Errorf("%v.MulPercent(%v, %d) = %v, want %v", tt.amount, tt.percent, tt.mode, got, tt.want)
+		}
+	}
+
+	if got := Money(1999).Mul(3, RoundHalfEven); got != 5997 {
+		t.Errorf("Mul(3) = %v, want 59.97", got)
+	}
+	if got := Money(1000).Mul(0.005, RoundHalfUp); got != 5 {
+		t.Errorf("Mul(0.005) = %v, want 0.05", got)
+	}
+	if got := MaxMoney.Mul(1e10, RoundHalfEven); got != 0 {
+		t.Errorf("Mul out of range = %v, want 0", got)
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	shares := Money(1000).Allocate(3)
+	want := []Money{334, 333, 333}
+	for i := range want {
+		if shares[i] != want[i] {
+			t.Fatalf("Allocate(3) = %v, want %v", shares, want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import "reflect"
+
+// PriceListVersionAt returns the version of a price list in effect on the
+// YYYY-MM-DD date, or nil when the first version starts later
+func PriceListVersionAt(versions []PriceListVersion, date string) *PriceListVersion {
+	var found *PriceListVersion
+	for i := range versions {
+		v := &versions[i]
+		if v.EffectiveFrom != "" && date < v.EffectiveFrom {
+			continue
+		}
+		if found == nil || v.EffectiveFrom > found.EffectiveFrom {
+			found = v
+		}
+	}
+	return found
+}
+
+// PriceListVersionDue reports whether the version in effect today differs
+// from the services the price list shows, because a scheduled version has
+// started
+func PriceListVersionDue(versions []PriceListVersion, main, additional []PriceListItem, today string) (*PriceListVersion, bool) {
+	v := PriceListVersionAt(versions, today)
+	if v == nil || SamePriceLists(v, main, additional) {
+		return nil, false
+	}
+	return v, true
+}
+
+// SamePriceLists reports whether a version holds the given services; no
+// version is the same as an empty list
+func SamePriceLists(v *PriceListVersion, main, additional []PriceListItem) bool {
+	if v == nil {
+		return len(main)+len(additional) == 0
+	}
+	return sameItems(v.Main, main) && sameItems(v.Additional, additional)
+}
+
+func sameItems(a, b []PriceListItem) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
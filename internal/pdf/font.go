@@ -0,0 +1,401 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"embed"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+//go:embed fonts/DejaVuSans.ttf fonts/DejaVuSans-Bold.ttf
+var fontFiles embed.FS
+
+// fonts holds the regular and the bold face, indexed by fontStyle
+var fonts = [2]*font{
+	mustLoadFont("fonts/DejaVuSans.ttf", "DejaVuSans"),
+	mustLoadFont("fonts/DejaVuSans-Bold.ttf", "DejaVuSans-Bold"),
+}
+
+func fontStyle(bold bool) int {
+	if bold {
+		return 1
+	}
+	return 0
+}
+
+// font is a TrueType font read far enough to measure text and to embed the
+// glyphs a document uses
+type font struct {
+	name       string
+	size       int
+	tables     map[string][]byte
+	unitsPerEm int
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+	loca       []int
+	advances   []int
+	glyphs     map[rune]uint16
+}
+
+// subsetTables are the tables a font embedded for PDF needs; the rest only
+// matter to operating systems installing the font
+var subsetTables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep"}
+
+func mustLoadFont(path, name string) *font {
+	data, err := fontFiles.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	f, err := parseFont(data, name)
+	if err != nil {
+		panic(fmt.Sprintf("pdf: %s: %v", path, err))
+	}
+	return f
+}
+
+func u16(b []byte, off int) int { return int(binary.BigEndian.Uint16(b[off:])) }
+func i16(b []byte, off int) int { return int(int16(binary.BigEndian.Uint16(b[off:]))) }
+func u32(b []byte, off int) int { return int(binary.BigEndian.Uint32(b[off:])) }
+
+func parseFont(data []byte, name string) (*font, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("file is too short")
+	}
+	f := &font{name: name, size: len(data), tables: make(map[string][]byte)}
+
+	numTables := u16(data, 4)
+	for i := 0; i < numTables; i++ {
+		rec := 12 + 16*i
+		if rec+16 > len(data) {
+			return nil, fmt.Errorf("table directory is truncated")
+		}
+		offset, length := u32(data, rec+8), u32(data, rec+12)
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("table %q is truncated", data[rec:rec+4])
+		}
+		f.tables[string(data[rec:rec+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("no %q table", tag)
+		}
+	}
+
+	head := f.tables["head"]
+	f.unitsPerEm = u16(head, 18)
+	f.bbox = [4]int{i16(head, 36), i16(head, 38), i16(head, 40), i16(head, 42)}
+	longLoca := i16(head, 50) == 1
+
+	hhea := f.tables["hhea"]
+	f.ascent, f.descent = i16(hhea, 4), i16(hhea, 6)
+	f.capHeight = f.ascent
+	if os2, ok := f.tables["OS/2"]; ok && u16(os2, 0) >= 2 && len(os2) >= 90 {
+		f.capHeight = i16(os2, 88)
+	}
+
+	numGlyphs := u16(f.tables["maxp"], 4)
+	loca := f.tables["loca"]
+	f.loca = make([]int, numGlyphs+1)
+	for i := range f.loca {
+		if longLoca {
+			f.loca[i] = u32(loca, 4*i)
+		} else {
+			f.loca[i] = 2 * u16(loca, 2*i)
+		}
+	}
+
+	hmtx := f.tables["hmtx"]
+	metrics := u16(hhea, 34)
+	f.advances = make([]int, numGlyphs)
+	for i := range f.advances {
+		if i < metrics {
+			f.advances[i] = u16(hmtx, 4*i)
+		} else {
+			f.advances[i] = f.advances[metrics-1]
+		}
+	}
+
+	glyphs, err := parseCmap(f.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.glyphs = glyphs
+	return f, nil
+}
+
+// parseCmap reads the Unicode character map, preferring the full repertoire
+// (format 12) to the basic plane (format 4)
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	best, bestFormat := -1, 0
+	for i := 0; i < u16(cmap, 2); i++ {
+		rec := 4 + 8*i
+		platform, encoding, offset := u16(cmap, rec), u16(cmap, rec+2), u32(cmap, rec+4)
+		if platform != 3 || (encoding != 1 && encoding != 10) {
+			continue
+		}
+		if format := u16(cmap, offset); format > bestFormat && (format == 4 || format == 12) {
+			best, bestFormat = offset, format
+		}
+	}
+
+	glyphs := make(map[rune]uint16)
+	switch bestFormat {
+	case 12:
+		for i := 0; i < u32(cmap, best+12); i++ {
+			group := best + 16 + 12*i
+			start, end, gid := u32(cmap, group), u32(cmap, group+4), u32(cmap, group+8)
+			for c := start; c <= end; c++ {
+				glyphs[rune(c)] = uint16(gid + c - start)
+			}
+		}
+	case 4:
+		segments := u16(cmap, best+6) / 2
+		ends := best + 14
+		starts := ends + 2*segments + 2
+		deltas := starts + 2*segments
+		rangeOffsets := deltas + 2*segments
+		for s := 0; s < segments; s++ {
+			start, end := u16(cmap, starts+2*s), u16(cmap, ends+2*s)
+			delta, rangeOffset := u16(cmap, deltas+2*s), u16(cmap, rangeOffsets+2*s)
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				gid := (c + delta) & 0xFFFF
+				if rangeOffset != 0 {
+					gid = u16(cmap, rangeOffsets+2*s+rangeOffset+2*(c-start))
+					if gid != 0 {
+						gid = (gid + delta) & 0xFFFF
+					}
+				}
+				if gid != 0 {
+					glyphs[rune(c)] = uint16(gid)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no Unicode character map")
+	}
+	return glyphs, nil
+}
+
+// glyph returns the glyph of a character, a question mark when the font
+// has none
+func (f *font) glyph(r rune) uint16 {
+	if gid, ok := f.glyphs[r]; ok {
+		return gid
+	}
+	return f.glyphs['?']
+}
+
+// width returns the advance of a glyph in 1/1000 em
+func (f *font) width(gid uint16) int {
+	return f.advances[gid] * 1000 / f.unitsPerEm
+}
+
+func (f *font) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+func (f *font) glyphData(gid int) []byte {
+	return f.tables["glyf"][f.loca[gid]:f.loca[gid+1]]
+}
+
+// withComponents adds the glyphs composite glyphs are built from
+func (f *font) withComponents(used map[uint16]bool) {
+	queue := make([]uint16, 0, len(used))
+	for gid := range used {
+		queue = append(queue, gid)
+	}
+	for len(queue) > 0 {
+		gid := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		data := f.glyphData(int(gid))
+		if len(data) < 10 || i16(data, 0) >= 0 {
+			continue
+		}
+		for off := 10; off+4 <= len(data); {
+			flags, component := u16(data, off), uint16(u16(data, off+2))
+			if !used[component] {
+				used[component] = true
+				queue = append(queue, component)
+			}
+			off += 4
+			if flags&0x0001 != 0 {
+				off += 4
+			} else {
+				off += 2
+			}
+			switch {
+			case flags&0x0008 != 0:
+				off += 2
+			case flags&0x0040 != 0:
+				off += 4
+			case flags&0x0080 != 0:
+				off += 8
+			}
+			if flags&0x0020 == 0 {
+				break
+			}
+		}
+	}
+}
+
+// subset returns the font with the outlines of all glyphs but the used ones
+// removed. Glyph numbers are kept, so text can address glyphs directly.
+func (f *font) subset(used map[uint16]bool) []byte {
+	keep := map[uint16]bool{0: true}
+	for gid := range used {
+		keep[gid] = true
+	}
+	f.withComponents(keep)
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 4*len(f.loca))
+	for gid := 0; gid < len(f.loca)-1; gid++ {
+		binary.BigEndian.PutUint32(loca[4*gid:], uint32(glyf.Len()))
+		if keep[uint16(gid)] {
+			glyf.Write(f.glyphData(gid))
+			for glyf.Len()%4 != 0 {
+				glyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*(len(f.loca)-1):], uint32(glyf.Len()))
+
+	head := append([]byte{}, f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)  // checkSumAdjustment, set below
+	binary.BigEndian.PutUint16(head[50:], 1) // long loca offsets
+
+	tables := map[string][]byte{"glyf": glyf.Bytes(), "loca": loca, "head": head}
+	var tags []string
+	for _, tag := range subsetTables {
+		if _, ok := tables[tag]; !ok {
+			data, present := f.tables[tag]
+			if !present {
+				continue
+			}
+			tables[tag] = data
+		}
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var out bytes.Buffer
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= len(tags) {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+	binary.Write(&out, binary.BigEndian, []uint16{
+		1, 0, uint16(len(tags)), uint16(searchRange), uint16(entrySelector), uint16(16*len(tags) - searchRange),
+	})
+
+	offset := 12 + 16*len(tags)
+	var headOffset int
+	for _, tag := range tags {
+		data := tables[tag]
+		if tag == "head" {
+			headOffset = offset
+		}
+		out.WriteString(tag)
+		binary.Write(&out, binary.BigEndian, []uint32{checksum(data), uint32(offset), uint32(len(data))})
+		offset += (len(data) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		out.Write(tables[tag])
+		for out.Len()%4 != 0 {
+			out.WriteByte(0)
+		}
+	}
+
+	file := out.Bytes()
+	binary.BigEndian.PutUint32(file[headOffset+8:], 0xB1B0AFBA-checksum(file))
+	return file
+}
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// fontObjects returns the PDF objects embedding the used glyphs of the font
+// as a Type 0 font with two-byte glyph codes: the font, its descendant, the
+// descriptor, the font file and the ToUnicode map. first is the number of
+// the first object.
+func (f *font) fontObjects(used map[uint16]rune, first int) []string {
+	gids := make([]uint16, 0, len(used))
+	keep := make(map[uint16]bool, len(used))
+	for gid := range used {
+		gids = append(gids, gid)
+		keep[gid] = true
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	// Subset fonts are named with a tag that changes with the glyphs
+	var key bytes.Buffer
+	for _, gid := range gids {
+		binary.Write(&key, binary.BigEndian, gid)
+	}
+	hash := crc32.ChecksumIEEE(key.Bytes())
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(hash%26)
+		hash /= 26
+	}
+	baseName := string(tag) + "+" + f.name
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.width(gid))
+	}
+
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(gids); start += 100 {
+		chunk := gids[start:min(start+100, len(gids))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(chunk))
+		for _, gid := range chunk {
+			fmt.Fprintf(&cmap, "<%04X> <", gid)
+			for _, unit := range utf16.Encode([]rune{used[gid]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+
+	var file bytes.Buffer
+	zw := zlib.NewWriter(&file)
+	zw.Write(f.subset(keep))
+	zw.Close()
+
+	return []string{
+		fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H "+
+			"/DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", baseName, first+1, first+4),
+		fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s "+
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+			"/FontDescriptor %d 0 R /CIDToGIDMap /Identity /DW %d /W [%s] >>",
+			baseName, first+2, f.width(0), widths.String()),
+		fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] "+
+			"/ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			baseName, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+			f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), first+3),
+		fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", file.Len(), file.String()),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", cmap.Len(), cmap.String()),
+	}
+}
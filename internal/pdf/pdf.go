@@ -0,0 +1,158 @@
+// Package pdf writes simple text documents as PDF without external tools.
+//
+// Text is set in DejaVu Sans, embedded as a subset of the glyphs the document
+// uses, so Russian letters render the same in every viewer. A ToUnicode map
+// keeps the text searchable and copyable.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Align is the horizontal alignment of a text relative to its x position
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+// Document is a PDF document built page by page
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+	// used maps the glyphs set in each font style to their characters
+	used [2]map[uint16]rune
+}
+
+// New creates an empty document with the given title
+func New(title string) *Document {
+	return &Document{title: title, used: [2]map[uint16]rune{{}, {}}}
+}
+
+// AddPage starts a new A4 page; following drawing goes to it
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// PageCount returns the number of pages
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+func (d *Document) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// Text draws text with its baseline at y, measured from the top of the page
+func (d *Document) Text(x, y, size float64, bold bool, align Align, text string) {
+	style := fontStyle(bold)
+	f := fonts[style]
+
+	switch align {
+	case AlignRight:
+		x -= TextWidth(text, size, bold)
+	case AlignCenter:
+		x -= TextWidth(text, size, bold) / 2
+	}
+
+	var codes strings.Builder
+	for _, r := range text {
+		if r < 0x20 {
+			r = ' '
+		}
+		gid := f.glyph(r)
+		if _, ok := d.used[style][gid]; !ok {
+			d.used[style][gid] = r
+		}
+		fmt.Fprintf(&codes, "%04X", gid)
+	}
+
+	fmt.Fprintf(d.page(), "BT /F%d %.2f Tf %.2f %.2f Td <%s> Tj ET\n",
+		style+1, size, x, PageHeight-y, codes.String())
+}
+
+// Line draws a thin line between two points measured from the top of the page
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+// Bytes returns the complete PDF file
+func (d *Document) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Fixed objects: 1 catalog, 2 pages, 3 info, 4-8 regular font, 9-13 bold font
+	const (
+		regularFontObject = 4
+		boldFontObject    = 9
+		firstPageObject   = 14
+	)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+i*2)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object(fmt.Sprintf("<< /Title %s /Producer (backend-go) >>", textString(d.title)))
+	for _, body := range fonts[0].fontObjects(d.used[0], regularFontObject) {
+		object(body)
+	}
+	for _, body := range fonts[1].fontObjects(d.used[1], boldFontObject) {
+		object(body)
+	}
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, regularFontObject, boldFontObject, firstPageObject+i*2+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// textString encodes s as a UTF-16 PDF string for document metadata
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
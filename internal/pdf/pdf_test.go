@@ -0,0 +1,299 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var testLines = []struct {
+	text string
+	bold bool
+}{
+	{text: "Расчётный листок — Иванов Пётр", bold: true},
+	{text: "Начислено: 12 345,67 руб. № 5 «Ёлка»", bold: false},
+	{text: "Счёт на оплату (ООО \"Зорин\")", bold: false},
+	{text: "Итого к выплате", bold: true},
+}
+
+// testDocument builds a two page document with the test lines
+func testDocument() []byte {
+	doc := New("Расчётный листок")
+	for page := 0; page < 2; page++ {
+		doc.AddPage()
+		for i, line := range testLines {
+			doc.Text(40, 60+float64(i)*20, 11, line.bold, AlignLeft, line.text)
+		}
+		doc.Line(40, 200, 500, 200)
+	}
+	return doc.Bytes()
+}
+
+// pdfObjects reads the objects of a file through its xref table, checking
+// that every offset points at the object it lists
+func pdfObjects(t *testing.T, data []byte) map[int][]byte {
+	t.Helper()
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	lines := strings.Split(string(data[xref:]), "\n")
+	var first, count int
+	if _, err := fmt.Sscan(lines[1], &first, &count); err != nil || first != 0 {
+		t.Fatalf("bad xref subsection %q", lines[1])
+	}
+
+	objects := make(map[int][]byte)
+	for n := 1; n < count; n++ {
+		entry := lines[2+n]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("bad xref entry %d: %q", n, entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		header := []byte(strconv.Itoa(n) + " 0 obj\n")
+		if !bytes.HasPrefix(data[offset:], header) {
+			t.Fatalf("xref offset %d of object %d points at %q", offset, n, data[offset:offset+10])
+		}
+		body := data[offset+len(header):]
+		end := bytes.Index(body, []byte("\nendobj\n"))
+		if end < 0 {
+			t.Fatalf("object %d has no endobj", n)
+		}
+		objects[n] = body[:end]
+	}
+	return objects
+}
+
+// streamData returns the data of a stream object, inflated when compressed
+func streamData(t *testing.T, object []byte) []byte {
+	t.Helper()
+	m := regexp.MustCompile(`/Length (\d+)`).FindSubmatch(object)
+	start := bytes.Index(object, []byte("stream\n"))
+	if m == nil || start < 0 {
+		t.Fatalf("not a stream: %.40q", object)
+	}
+	length, _ := strconv.Atoi(string(m[1]))
+	data := object[start+len("stream\n"):]
+	if len(data) < length || !bytes.HasPrefix(data[length:], []byte("\nendstream")) {
+		t.Fatalf("stream /Length %d does not end at endstream", length)
+	}
+	data = data[:length]
+	if !bytes.Contains(object[:start], []byte("/FlateDecode")) {
+		return data
+	}
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inflated, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inflated
+}
+
+// reference returns the object number a key of a dictionary refers to
+func reference(t *testing.T, object []byte, key string) int {
+	t.Helper()
+	m := regexp.MustCompile(`/` + key + ` \[?(\d+) 0 R`).FindSubmatch(object)
+	if m == nil {
+		t.Fatalf("no /%s in %.80q", key, object)
+	}
+	n, _ := strconv.Atoi(string(m[1]))
+	return n
+}
+
+// pageFonts returns the Type 0 font objects of the first page by resource name
+func pageFonts(t *testing.T, objects map[int][]byte) map[string]int {
+	t.Helper()
+	pages := objects[reference(t, objects[1], "Pages")]
+	page := objects[reference(t, pages, "Kids")]
+	fonts := make(map[string]int)
+	for _, m := range regexp.MustCompile(`/(F\d) (\d+) 0 R`).FindAllSubmatch(page, -1) {
+		n, _ := strconv.Atoi(string(m[2]))
+		fonts[string(m[1])] = n
+	}
+	if len(fonts) != 2 {
+		t.Fatalf("page fonts = %v, want F1 and F2", fonts)
+	}
+	return fonts
+}
+
+func TestXrefOffsets(t *testing.T) {
+	data := testDocument()
+	objects := pdfObjects(t, data)
+	if len(objects) != 17 {
+		t.Errorf("got %d objects, want 17", len(objects))
+	}
+	if !bytes.Contains(objects[3], []byte("/Title <FEFF0420")) {
+		t.Errorf("title is not a UTF-16 string: %q", objects[3])
+	}
+}
+
+// fontTables reads the table directory of a TrueType file, checking the
+// checksum of every table and of the whole file
+func fontTables(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	tables := make(map[string][]byte)
+	for i := 0; i < u16(data, 4); i++ {
+		rec := 12 + 16*i
+		tag := string(data[rec : rec+4])
+		sum, offset, length := uint32(u32(data, rec+4)), u32(data, rec+8), u32(data, rec+12)
+		if offset%4 != 0 || offset+length > len(data) {
+			t.Fatalf("table %q at %d+%d is misplaced", tag, offset, length)
+		}
+		table := data[offset : offset+length]
+		if tag != "head" && checksum(table) != sum {
+			t.Errorf("table %q checksum %08x, directory says %08x", tag, checksum(table), sum)
+		}
+		tables[tag] = table
+	}
+	if sum := checksum(data); sum != 0xB1B0AFBA {
+		t.Errorf("file checksum %08x, want B1B0AFBA", sum)
+	}
+	return tables
+}
+
+func TestSubsetFont(t *testing.T) {
+	objects := pdfObjects(t, testDocument())
+
+	for name, fontObject := range pageFonts(t, objects) {
+		f := fonts[0]
+		if name == "F2" {
+			f = fonts[1]
+		}
+		descendant := objects[reference(t, objects[fontObject], "DescendantFonts")]
+		descriptor := objects[reference(t, descendant, "FontDescriptor")]
+		subset := streamData(t, objects[reference(t, descriptor, "FontFile2")])
+
+		tables := fontTables(t, subset)
+		for _, tag := range []string{"glyf", "head", "hhea", "hmtx", "loca", "maxp"} {
+			if _, ok := tables[tag]; !ok {
+				t.Fatalf("%s: subset has no %q table", name, tag)
+			}
+		}
+		numGlyphs := u16(tables["maxp"], 4)
+		if numGlyphs != len(f.loca)-1 {
+			t.Fatalf("%s: subset has %d glyphs, font has %d", name, numGlyphs, len(f.loca)-1)
+		}
+		if i16(tables["head"], 50) != 1 {
+			t.Fatalf("%s: subset loca is not long", name)
+		}
+		loca := tables["loca"]
+		glyph := func(gid int) []byte {
+			start, end := u32(loca, 4*gid), u32(loca, 4*gid+4)
+			if start > end || end > len(tables["glyf"]) {
+				t.Fatalf("%s: glyph %d at %d..%d is out of glyf", name, gid, start, end)
+			}
+			return tables["glyf"][start:end]
+		}
+
+		used := map[uint16]bool{}
+		for _, line := range testLines {
+			if (name == "F2") != line.bold {
+				continue
+			}
+			for _, r := range line.text {
+				gid := f.glyph(r)
+				if gid == f.glyphs['?'] && r != '?' {
+					t.Errorf("%s: font has no glyph for %q", name, r)
+				}
+				used[gid] = true
+			}
+		}
+		f.withComponents(used)
+		for gid := range used {
+			got := glyph(int(gid))
+			if want := f.glyphData(int(gid)); !bytes.Equal(got[:len(want)], want) || len(got)-len(want) > 3 {
+				t.Errorf("%s: glyph %d differs from the font", name, gid)
+			}
+		}
+		if got := glyph(int(f.glyph('Z'))); len(got) != 0 {
+			t.Errorf("%s: unused glyph Z was kept", name)
+		}
+	}
+}
+
+func TestToUnicode(t *testing.T) {
+	objects := pdfObjects(t, testDocument())
+
+	cmaps := make(map[string]map[string]string)
+	for name, fontObject := range pageFonts(t, objects) {
+		cmap := string(streamData(t, objects[reference(t, objects[fontObject], "ToUnicode")]))
+		codes := make(map[string]string)
+		for _, m := range regexp.MustCompile(`<([0-9A-F]{4})> <([0-9A-F]+)>`).FindAllStringSubmatch(cmap, -1) {
+			units, err := hex.DecodeString(m[2])
+			if err != nil || len(units)%2 != 0 {
+				t.Fatalf("bad ToUnicode entry %q", m[0])
+			}
+			var runes []rune
+			for i := 0; i < len(units); i += 2 {
+				runes = append(runes, rune(binary.BigEndian.Uint16(units[i:])))
+			}
+			codes[m[1]] = string(runes)
+		}
+		cmaps[name] = codes
+	}
+
+	pages := objects[reference(t, objects[1], "Pages")]
+	page := objects[reference(t, pages, "Kids")]
+	content := string(streamData(t, objects[reference(t, page, "Contents")]))
+
+	var got []string
+	for _, m := range regexp.MustCompile(`/(F\d) [\d.]+ Tf [\d.]+ [\d.]+ Td <([0-9A-F]*)> Tj`).FindAllStringSubmatch(content, -1) {
+		var text strings.Builder
+		for i := 0; i+4 <= len(m[2]); i += 4 {
+			r, ok := cmaps[m[1]][m[2][i:i+4]]
+			if !ok {
+				t.Fatalf("glyph %s of %s is not in its ToUnicode map", m[2][i:i+4], m[1])
+			}
+			text.WriteString(r)
+		}
+		got = append(got, text.String())
+	}
+
+	if len(got) != len(testLines) {
+		t.Fatalf("page shows %d texts, want %d", len(got), len(testLines))
+	}
+	for i, line := range testLines {
+		if got[i] != line.text {
+			t.Errorf("text %d maps back to %q, want %q", i, got[i], line.text)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	if w := TextWidth("", 10, false); w != 0 {
+		t.Errorf("empty width = %v", w)
+	}
+	regular, bold := TextWidth("Итого", 10, false), TextWidth("Итого", 10, true)
+	if regular <= 0 || bold <= regular {
+		t.Errorf("widths regular %v, bold %v", regular, bold)
+	}
+	if w := TextWidth("ИтогоИтого", 10, false); w != 2*regular {
+		t.Errorf("width of a doubled text = %v, want %v", w, 2*regular)
+	}
+
+	long := "Мойка кузова с пеной и сушкой"
+	short := Truncate(long, TextWidth(long, 10, false)/2, 10, false)
+	if !strings.HasSuffix(short, "…") || TextWidth(short, 10, false) > TextWidth(long, 10, false)/2 {
+		t.Errorf("Truncate = %q", short)
+	}
+	for _, line := range Wrap(long, 60, 10, false) {
+		if TextWidth(line, 10, false) > 60 {
+			t.Errorf("wrapped line %q is wider than 60", line)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"strings"
+)
+
+// glyphs returns the glyphs of text in the font; control characters are set
+// as spaces
+func glyphs(f *font, text string) []uint16 {
+	result := make([]uint16, 0, len(text))
+	for _, r := range text {
+		if r < 0x20 {
+			r = ' '
+		}
+		result = append(result, f.glyph(r))
+	}
+	return result
+}
+
+// TextWidth returns the width of text in points
+func TextWidth(text string, size float64, bold bool) float64 {
+	f := fonts[fontStyle(bold)]
+	total := 0
+	for _, gid := range glyphs(f, text) {
+		total += f.width(gid)
+	}
+	return float64(total) * size / 1000
+}
+
+// Truncate shortens text with an ellipsis so it fits into width
+func Truncate(text string, width, size float64, bold bool) string {
+	if TextWidth(text, size, bold) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := string(runes) + "…"
+		if TextWidth(candidate, size, bold) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Wrap breaks text into lines that fit into width, splitting at spaces.
+// Words longer than the width are truncated.
+func Wrap(text string, width, size float64, bold bool) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if TextWidth(candidate, size, bold) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = Truncate(word, width, size, bold)
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+)
+
+// AggregatorRegisterRow is a wash the aggregator's register says it pays for
+type AggregatorRegisterRow struct {
+	Row           int
+	VehicleNumber string
+	Date          string // YYYY-MM-DD
+	ServiceName   string
+	Amount        models.Money
+}
+
+// registerColumns lists the header names aggregators use for each column,
+// lowercased
+var registerColumns = map[string][]string{
+	"plate":   {"госномер", "гос номер", "гос. номер", "номер тс", "номер автомобиля", "номер", "автомобиль", "plate", "vehicle", "vehicle number"},
+	"date":    {"дата", "дата мойки", "дата оказания услуги", "дата и время", "date"},
+	"service": {"услуга", "наименование услуги", "вид мойки", "service"},
+	"amount":  {"сумма", "стоимость", "сумма к оплате", "цена", "amount", "price"},
+}
+
+// plateLookalikes maps the Cyrillic letters allowed on Russian plates to
+// the Latin letters that look the same
+var plateLookalikes = map[rune]rune{
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H',
+	'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X',
+}
+
+// NormalizePlate brings a plate number to one spelling: upper case, letters
+// and digits only, Cyrillic letters replaced by their Latin look-alikes
+func NormalizePlate(plate string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(plate) {
+		if l, ok := plateLookalikes[r]; ok {
+			r = l
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func normalizeServiceName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// registerDate reads a register date with an optional time
+func registerDate(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if day, _, ok := strings.Cut(value, " "); ok {
+		value = day
+	}
+	if day, _, ok := strings.Cut(value, "T"); ok {
+		value = day
+	}
+	for _, layout := range []string{"02.01.2006", "2006-01-02", "02.01.06", "02/01/2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01-02"), true
+		}
+	}
+	return "", false
+}
+
+// registerAmount reads an amount written with spaces between thousands and a
+// decimal comma or point
+func registerAmount(value string) (models.Money, error) {
+	value = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "₽"), "руб.")
+	return models.ParseMoney(value)
+}
+
+// readCSV reads a CSV file that may be UTF-8 or Windows-1251, separated by
+// semicolons, commas or tabs
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	text := string(data)
+	if !utf8.Valid(data) {
+		text = decodeWindows1251(data)
+	}
+
+	header, _, _ := strings.Cut(text, "\n")
+	delimiter := ';'
+	for _, d := range []rune{'\t', ','} {
+		if strings.Count(header, string(d)) > strings.Count(header, string(delimiter)) {
+			delimiter = d
+		}
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	return records, nil
+}
+
+// csvColumns finds the index of each column by its header names
+func csvColumns(header []string, aliases map[string][]string) map[string]int {
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for column, names := range aliases {
+			if _, found := columns[column]; found {
+				continue
+			}
+			for _, alias := range names {
+				if name == alias {
+					columns[column] = i
+					break
+				}
+			}
+		}
+	}
+	return columns
+}
+
+// csvField returns the trimmed value of the column in the record, empty when
+// the file has no such column
+func csvField(record []string, columns map[string]int, column string) string {
+	i, ok := columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// ParseAggregatorRegister reads an aggregator's CSV register. The file may be
+// UTF-8 or Windows-1251, separated by semicolons, commas or tabs; columns are
+// found by their header names.
+func ParseAggregatorRegister(data []byte) ([]AggregatorRegisterRow, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("register is empty")
+	}
+
+	columns := csvColumns(records[0], registerColumns)
+	for _, column := range []string{"plate", "date", "amount"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("register has no %s column", column)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		return csvField(record, columns, column)
+	}
+
+	var rows []AggregatorRegisterRow
+	for n, record := range records[1:] {
+		line := n + 2
+		plate := field(record, "plate")
+		if plate == "" {
+			// Blank lines and totals at the bottom
+			continue
+		}
+		date, ok := registerDate(field(record, "date"))
+		if !ok {
+			return nil, fmt.Errorf("line %d: invalid date %q", line, field(record, "date"))
+		}
+		amount, err := registerAmount(field(record, "amount"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, field(record, "amount"))
+		}
+		rows = append(rows, AggregatorRegisterRow{
+			Row:           line,
+			VehicleNumber: plate,
+			Date:          date,
+			ServiceName:   field(record, "service"),
+			Amount:        amount,
+		})
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("register has no washes")
+	}
+	return rows, nil
+}
+
+// RegisterDates returns the first and last dates of the register
+func RegisterDates(rows []AggregatorRegisterRow) (string, string) {
+	from, to := rows[0].Date, rows[0].Date
+	for _, row := range rows[1:] {
+		if row.Date < from {
+			from = row.Date
+		}
+		if row.Date > to {
+			to = row.Date
+		}
+	}
+	return from, to
+}
+
+// washServiceNames returns the normalized names of all services of a wash
+func washServiceNames(event *models.WashEvent) []string {
+	names := []string{normalizeServiceName(event.Services.Main.ServiceName)}
+	for _, service := range event.Services.Additional {
+		names = append(names, normalizeServiceName(service.ServiceName))
+	}
+	return names
+}
+
+// ReconcileAggregatorRegister pairs the register rows with the aggregator's
+// washes from dateFrom to dateTo by plate and date. Among several washes of
+// the same car on a day the one with the row's service, then its amount, is
+// taken. Rows and washes left unpaired are missing on the other side.
+func ReconcileAggregatorRegister(rows []AggregatorRegisterRow, washEvents []models.WashEvent, aggregatorID, dateFrom, dateTo string) []models.AggregatorRegisterItem {
+	type candidate struct {
+		event *models.WashEvent
+		date  string
+		used  bool
+	}
+
+	byKey := make(map[string][]*candidate)
+	var washes []*candidate
+	for i := range washEvents {
+		event := &washEvents[i]
+		if event.PaymentMethod != models.WashPaymentAggregator || event.SourceID != aggregatorID {
+			continue
+		}
+		t, ok := ParseTimestamp(event.Timestamp)
+		if !ok {
+			continue
+		}
+		date := LocalDate(t)
+		if date < dateFrom || date > dateTo {
+			continue
+		}
+		w := &candidate{event: event, date: date}
+		washes = append(washes, w)
+		key := NormalizePlate(event.VehicleNumber) + "|" + date
+		byKey[key] = append(byKey[key], w)
+	}
+	sort.SliceStable(washes, func(i, j int) bool {
+		return washes[i].event.Timestamp < washes[j].event.Timestamp
+	})
+
+	items := make([]models.AggregatorRegisterItem, 0, len(rows)+len(washes))
+	for _, row := range rows {
+		item := models.AggregatorRegisterItem{
+			Row:           row.Row,
+			VehicleNumber: row.VehicleNumber,
+			Date:          row.Date,
+			ServiceName:   row.ServiceName,
+			Amount:        row.Amount,
+		}
+
+		service := normalizeServiceName(row.ServiceName)
+		var best *candidate
+		bestScore := -1
+		for _, w := range byKey[NormalizePlate(row.VehicleNumber)+"|"+row.Date] {
+			if w.used {
+				continue
+			}
+			score := 0
+			if service != "" {
+				for _, name := range washServiceNames(w.event) {
+					if name == service {
+						score += 2
+						break
+					}
+				}
+			}
+			if w.event.TotalAmount == row.Amount {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = w, score
+			}
+		}
+
+		if best == nil {
+			item.Status = models.AggregatorRegisterMissingOurs
+			items = append(items, item)
+			continue
+		}
+
+		best.used = true
+		item.WashEventID = best.event.ID
+		item.WashServiceName = best.event.Services.Main.ServiceName
+		item.WashAmount = best.event.TotalAmount
+		item.ServiceMismatch = service != "" && bestScore < 2
+		item.Status = models.AggregatorRegisterMatched
+		if best.event.TotalAmount != row.Amount {
+			item.Status = models.AggregatorRegisterPriceMismatch
+		}
+		items = append(items, item)
+	}
+
+	for _, w := range washes {
+		if w.used {
+			continue
+		}
+		items = append(items, models.AggregatorRegisterItem{
+			Status:          models.AggregatorRegisterMissingTheirs,
+			VehicleNumber:   w.event.VehicleNumber,
+			Date:            w.date,
+			WashEventID:     w.event.ID,
+			WashServiceName: w.event.Services.Main.ServiceName,
+			WashAmount:      w.event.TotalAmount,
+		})
+	}
+
+	for i := range items {
+		items[i].ID = fmt.Sprintf("item_%d", i+1)
+	}
+	return items
+}
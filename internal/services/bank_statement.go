@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"backend-go/internal/models"
+)
+
+// BankStatement is a file in the 1C ClientBankExchange format: the header
+// keys and one set of keys per payment document
+type BankStatement struct {
+	Header    map[string]string
+	Documents []map[string]string
+}
+
+// win1251High maps Windows-1251 codes 0x80..0xBF; 0xC0..0xFF are А..я
+var win1251High = [64]rune{
+	'Ђ', 'Ѓ', '‚', 'ѓ', '„', '…', '†', '‡', '€', '‰', 'Љ', '‹', 'Њ', 'Ќ', 'Ћ', 'Џ',
+	'ђ', '‘', '’', '“', '”', '•', '–', '—', '\uFFFD', '™', 'љ', '›', 'њ', 'ќ', 'ћ', 'џ',
+	'\u00A0', 'Ў', 'ў', 'Ј', '¤', 'Ґ', '¦', '§', 'Ё', '©', 'Є', '«', '¬', '\u00AD', '®', 'Ї',
+	'°', '±', 'І', 'і', 'ґ', 'µ', '¶', '·', 'ё', '№', 'є', '»', 'ј', 'Ѕ', 'ѕ', 'ї',
+}
+
+// cp866Tail maps DOS (CP866) codes 0xF0..0xFF; 0x80..0xAF are А..п and
+// 0xE0..0xEF are р..я. Box drawing characters are not expected in statements.
+var cp866Tail = [16]rune{'Ё', 'ё', 'Є', 'є', 'Ї', 'ї', 'Ў', 'ў', '°', '∙', '·', '√', '№', '¤', '■', '\u00A0'}
+
+func decodeWindows1251(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c >= 0xC0:
+			b.WriteRune(rune(c-0xC0) + 'А')
+		default:
+			b.WriteRune(win1251High[c-0x80])
+		}
+	}
+	return b.String()
+}
+
+func decodeCP866(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		switch {
+		case c < 0x80:
+			b.WriteByte(c)
+		case c < 0xB0:
+			b.WriteRune(rune(c-0x80) + 'А')
+		case c >= 0xE0 && c < 0xF0:
+			b.WriteRune(rune(c-0xE0) + 'р')
+		case c >= 0xF0:
+			b.WriteRune(cp866Tail[c-0xF0])
+		default:
+			b.WriteRune('?')
+		}
+	}
+	return b.String()
+}
+
+// decodeBankStatement converts a statement to UTF-8. Banks export in
+// Windows-1251 ("Кодировка=Windows") or CP866 ("Кодировка=DOS"); some
+// export UTF-8 already.
+func decodeBankStatement(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	if text := decodeCP866(data); strings.Contains(text, "Кодировка=DOS") {
+		return text
+	}
+	return decodeWindows1251(data)
+}
+
+// ParseBankStatement reads a statement in the 1C ClientBankExchange format
+func ParseBankStatement(data []byte) (*BankStatement, error) {
+	text := decodeBankStatement(data)
+	if !strings.HasPrefix(strings.TrimSpace(text), "1CClientBankExchange") {
+		return nil, fmt.Errorf("not a 1CClientBankExchange file")
+	}
+
+	st := &BankStatement{Header: make(map[string]string)}
+	var doc map[string]string
+	inAccount := false
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, _ := strings.Cut(line, "=")
+
+		switch key {
+		case "СекцияДокумент":
+			doc = map[string]string{"СекцияДокумент": value}
+			continue
+		case "КонецДокумента":
+			if doc != nil {
+				st.Documents = append(st.Documents, doc)
+			}
+			doc = nil
+			continue
+		case "СекцияРасчСчет":
+			inAccount = true
+			continue
+		case "КонецРасчСчет":
+			inAccount = false
+			continue
+		case "КонецФайла":
+			return st, nil
+		}
+
+		switch {
+		case doc != nil:
+			doc[key] = value
+		case inAccount && key == "РасчСчет":
+			st.Header[key] = value
+		case !inAccount:
+			// Header keys; the account section keys are balances we do not need
+			if _, ok := st.Header[key]; !ok {
+				st.Header[key] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// BankDate converts a DD.MM.YYYY date to YYYY-MM-DD
+func BankDate(value string) string {
+	t, err := time.Parse("02.01.2006", strings.TrimSpace(value))
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+// IncomingBankPayments returns the payments the statement received to our
+// account. A document is incoming when the recipient account is the
+// statement's account or, when that is missing, the recipient INN is ours.
+// Without either there is no telling incoming documents, which is an error.
+func IncomingBankPayments(st *BankStatement, ownINN string) ([]models.BankPayment, error) {
+	account := st.Header["РасчСчет"]
+	if account == "" && ownINN == "" {
+		return nil, fmt.Errorf("the statement has no account (РасчСчет); fill in the company INN to tell incoming payments from outgoing ones")
+	}
+
+	var payments []models.BankPayment
+	for _, doc := range st.Documents {
+		recipientAccount := doc["ПолучательСчет"]
+		if recipientAccount == "" {
+			recipientAccount = doc["ПолучательРасчСчет"]
+		}
+		switch {
+		case account != "":
+			if recipientAccount != account {
+				continue
+			}
+		case ownINN != "":
+			if doc["ПолучательИНН"] != ownINN {
+				continue
+			}
+		}
+
+		amount, err := models.ParseMoney(doc["Сумма"])
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("document %s: invalid amount %q", doc["Номер"], doc["Сумма"])
+		}
+
+		date := BankDate(doc["ДатаПоступило"])
+		if date == "" {
+			date = BankDate(doc["Дата"])
+		}
+
+		payerName := doc["Плательщик1"]
+		if payerName == "" {
+			payerName = doc["Плательщик"]
+		}
+		payerAccount := doc["ПлательщикСчет"]
+		if payerAccount == "" {
+			payerAccount = doc["ПлательщикРасчСчет"]
+		}
+
+		payments = append(payments, models.BankPayment{
+			DocumentNumber: doc["Номер"],
+			Date:           date,
+			Amount:         amount,
+			PayerName:      payerName,
+			PayerINN:       doc["ПлательщикИНН"],
+			PayerKPP:       doc["ПлательщикКПП"],
+			PayerAccount:   payerAccount,
+			Purpose:        doc["НазначениеПлатежа"],
+		})
+	}
+	return payments, nil
+}
+
+// BankPaymentDescription describes a client transaction posted from a bank
+// payment
+func BankPaymentDescription(p *models.BankPayment) string {
+	description := "Платёжное поручение № " + p.DocumentNumber
+	if t, err := time.Parse("2006-01-02", p.Date); err == nil {
+		description += " от " + t.Format("02.01.2006")
+	}
+	if p.Purpose != "" {
+		description += ": " + p.Purpose
+	}
+	return description
+}
+
+// BankDocumentKey identifies a bank payment across statements
+func BankDocumentKey(p *models.BankPayment) string {
+	payer := p.PayerINN
+	if payer == "" {
+		payer = p.PayerAccount
+	}
+	return fmt.Sprintf("%s|%s|%s|%d", p.Date, p.DocumentNumber, payer, p.Amount.Kopecks())
+}
+
+// BankClient is a counter agent or aggregator payments can be matched to
+type BankClient struct {
+	Type      models.InvoiceClientType
+	ID        string
+	Name      string
+	Companies []models.CounterAgentCompany
+}
+
+// payerLevel is a way of recognising the payer among a client's companies
+type payerLevel struct {
+	name    string
+	matches func(c *models.CounterAgentCompany) bool
+}
+
+// payerLevels lists the ways of recognising the payer, most reliable first:
+// by the payer's account, by INN and KPP, by INN alone
+func payerLevels(p *models.BankPayment) []payerLevel {
+	return []payerLevel{
+		{"account", func(c *models.CounterAgentCompany) bool {
+			return p.PayerAccount != "" && c.SettlementAccount == p.PayerAccount
+		}},
+		{"inn+kpp", func(c *models.CounterAgentCompany) bool {
+			return p.PayerINN != "" && p.PayerKPP != "" && c.INN == p.PayerINN && c.KPP == p.PayerKPP
+		}},
+		{"inn", func(c *models.CounterAgentCompany) bool {
+			return p.PayerINN != "" && c.INN == p.PayerINN
+		}},
+	}
+}
+
+// PayerCompany returns the client's company that made the payment
+func PayerCompany(p *models.BankPayment, companies []models.CounterAgentCompany) (models.CounterAgentCompany, bool) {
+	for _, level := range payerLevels(p) {
+		for i := range companies {
+			if level.matches(&companies[i]) {
+				return companies[i], true
+			}
+		}
+	}
+	return models.CounterAgentCompany{}, false
+}
+
+// MatchBankPayment finds the client who made the payment: by the payer's
+// account, then by INN and KPP, then by INN alone. A payer matching several
+// clients at the same level is left for manual assignment.
+func MatchBankPayment(p *models.BankPayment, clients []BankClient) (*BankClient, string) {
+	levels := payerLevels(p)
+
+	for _, level := range levels {
+		var found []*BankClient
+		for i := range clients {
+			for j := range clients[i].Companies {
+				if level.matches(&clients[i].Companies[j]) {
+					found = append(found, &clients[i])
+					break
+				}
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], level.name
+		default:
+			return nil, ""
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const testStatement = `1CClientBankExchange
+ВерсияФормата=1.03
+Кодировка={encoding}
+Отправитель=Бухгалтерия
+ДатаНачала=01.03.2024
+ДатаКонца=31.03.2024
+{account}СекцияДокумент=Платежное поручение
+Номер=118
+Дата=04.03.2024
+Сумма=15000.50
+ПлательщикСчет=40702810938000000001
+Плательщик1=ООО "Ёлка"
+ПлательщикИНН=7707083893
+ПлательщикКПП=770701001
+ПолучательСчет=40817810000000000001
+ПолучательИНН=500100732259
+ДатаПоступило=05.03.2024
+НазначениеПлатежа=Оплата по счёту № 7 за мойку
+КонецДокумента
+СекцияДокумент=Платежное поручение
+Номер=41
+Дата=06.03.2024
+Сумма=2300.00
+ПлательщикСчет=40817810000000000001
+ПлательщикИНН=500100732259
+ПолучательСчет=40702810938000000001
+ПолучательИНН=7707083893
+НазначениеПлатежа=Закупка химии
+КонецДокумента
+КонецФайла
+`
+
+const testStatementAccount = `СекцияРасчСчет
+ДатаНачала=01.03.2024
+РасчСчет=40817810000000000001
+НачальныйОстаток=0.00
+КонецРасчСчет
+`
+
+// encodeBankStatement encodes text with the single-byte encoding decode reads
+func encodeBankStatement(text string, decode func([]byte) string) []byte {
+	codes := make(map[rune]byte)
+	for c := 0; c < 256; c++ {
+		r := []rune(decode([]byte{byte(c)}))[0]
+		if _, ok := codes[r]; !ok {
+			codes[r] = byte(c)
+		}
+	}
+	var data []byte
+	for _, r := range text {
+		c, ok := codes[r]
+		if !ok {
+			panic("cannot encode " + string(r))
+		}
+		data = append(data, c)
+	}
+	return data
+}
+
+func statementText(encoding string, withAccount bool) string {
+	account := ""
+	if withAccount {
+		account = testStatementAccount
+	}
+	text := strings.ReplaceAll(testStatement, "{encoding}", encoding)
+	return strings.ReplaceAll(text, "{account}", account)
+}
+
+func TestDecodeBankStatement(t *testing.T) {
+	text := statementText("Windows", true)
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "utf-8", data: []byte(text), want: text},
+		{name: "utf-8 with BOM", data: append([]byte("\uFEFF"), text...), want: text},
+		{name: "windows-1251", data: encodeBankStatement(text, decodeWindows1251), want: text},
+		{
+			name: "cp866",
+			data: encodeBankStatement(statementText("DOS", true), decodeCP866),
+			want: statementText("DOS", true),
+		},
+	}
+	for _, tt := range tests {
+		if got := decodeBankStatement(tt.data); got != tt.want {
+			t.Errorf("%s: decoded text differs:\n%s", tt.name, got)
+		}
+	}
+}
+
+func TestParseBankStatement(t *testing.T) {
+	st, err := ParseBankStatement(encodeBankStatement(statementText("Windows", true), decodeWindows1251))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.Header["РасчСчет"]; got != "40817810000000000001" {
+		t.Errorf("account = %q", got)
+	}
+	// The account section's dates do not override the header's
+	if got := st.Header["ДатаНачала"]; got != "01.03.2024" {
+		t.Errorf("start date = %q", got)
+	}
+	if _, ok := st.Header["НачальныйОстаток"]; ok {
+		t.Error("account section balance leaked into the header")
+	}
+	if len(st.Documents) != 2 || st.Documents[0]["Плательщик1"] != `ООО "Ёлка"` {
+		t.Errorf("documents = %v", st.Documents)
+	}
+
+	if _, err := ParseBankStatement([]byte("Номер=1\nКонецФайла\n")); err == nil {
+		t.Error("a file without the 1CClientBankExchange marker was accepted")
+	}
+}
+
+func TestIncomingBankPayments(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		ownINN      string
+		wantErr     bool
+		wantNumbers []string
+	}{
+		{
+			name:        "windows-1251 with account",
+			data:        encodeBankStatement(statementText("Windows", true), decodeWindows1251),
+			wantNumbers: []string{"118"},
+		},
+		{
+			name:        "cp866 with account",
+			data:        encodeBankStatement(statementText("DOS", true), decodeCP866),
+			wantNumbers: []string{"118"},
+		},
+		{
+			name:        "account wins over the INN",
+			data:        []byte(statementText("Windows", true)),
+			ownINN:      "7707083893",
+			wantNumbers: []string{"118"},
+		},
+		{
+			name:        "no account, by INN",
+			data:        encodeBankStatement(statementText("Windows", false), decodeWindows1251),
+			ownINN:      "500100732259",
+			wantNumbers: []string{"118"},
+		},
+		{
+			name:    "no account and no INN",
+			data:    []byte(statementText("Windows", false)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		st, err := ParseBankStatement(tt.data)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		payments, err := IncomingBankPayments(st, tt.ownINN)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %d payments, want error", tt.name, len(payments))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var numbers []string
+		for _, p := range payments {
+			numbers = append(numbers, p.DocumentNumber)
+		}
+		if strings.Join(numbers, ",") != strings.Join(tt.wantNumbers, ",") {
+			t.Errorf("%s: documents %v, want %v", tt.name, numbers, tt.wantNumbers)
+			continue
+		}
+
+		p := payments[0]
+		if p.Date != "2024-03-05" || p.Amount != 1500050 || p.PayerName != `ООО "Ёлка"` ||
+			p.PayerINN != "7707083893" || p.PayerKPP != "770701001" ||
+			p.PayerAccount != "40702810938000000001" || p.Purpose != "Оплата по счёту № 7 за мойку" {
+			t.Errorf("%s: payment = %+v", tt.name, p)
+		}
+	}
+}
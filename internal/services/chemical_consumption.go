@@ -0,0 +1,361 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// ChemicalPurchaseCategory is the expense category of chemical purchases
+const ChemicalPurchaseCategory = "Закупка химии"
+
+// IsChemicalPurchase reports whether an expense adds chemical to the inventory
+func IsChemicalPurchase(expense *models.Expense) bool {
+	return expense.Category == ChemicalPurchaseCategory && expense.Quantity > 0 &&
+		strings.HasPrefix(strings.ToLower(expense.Unit), "кг")
+}
+
+// AverageChemicalCostPerKg returns the average price of chemical bought by
+// the kilogram before until, so later purchases do not reprice a settled
+// period. A zero until takes all purchases.
+func AverageChemicalCostPerKg(expenses []models.Expense, until time.Time) models.Money {
+	var total models.Money
+	var kg float64
+	for i := range expenses {
+		if !IsChemicalPurchase(&expenses[i]) {
+			continue
+		}
+		if !until.IsZero() {
+			if t, ok := ParseTimestamp(expenses[i].Date); !ok || !t.Before(until) {
+				continue
+			}
+		}
+		total += expenses[i].Amount
+		kg += expenses[i].Quantity
+	}
+	if kg == 0 {
+		return 0
+	}
+	return models.Money(math.Round(float64(total) / kg))
+}
+
+// ChemicalPrices returns the average price of chemical bought by the
+// kilogram as it stood after each day with purchases, oldest first. Each day
+// is priced with the purchases made up to and on it, so later purchases do
+// not reprice settled days.
+func ChemicalPrices(expenses []models.Expense) []models.ChemicalPrice {
+	type purchase struct {
+		date   string
+		amount models.Money
+		kg     float64
+	}
+	var purchases []purchase
+	for i := range expenses {
+		if !IsChemicalPurchase(&expenses[i]) {
+			continue
+		}
+		t, ok := ParseTimestamp(expenses[i].Date)
+		if !ok {
+			continue
+		}
+		purchases = append(purchases, purchase{
+			date:   t.In(time.Local).Format("2006-01-02"),
+			amount: expenses[i].Amount,
+			kg:     expenses[i].Quantity,
+		})
+	}
+	sort.SliceStable(purchases, func(i, j int) bool { return purchases[i].date < purchases[j].date })
+
+	var prices []models.ChemicalPrice
+	var total models.Money
+	var kg float64
+	for i, p := range purchases {
+		total += p.amount
+		kg += p.kg
+		if i+1 < len(purchases) && purchases[i+1].date == p.date {
+			continue
+		}
+		prices = append(prices, models.ChemicalPrice{
+			From:      p.date,
+			CostPerKg: models.Money(math.Round(float64(total) / kg)),
+		})
+	}
+	return prices
+}
+
+// WithChemicalCost returns the schemes with chemical rules that have no cost
+// price of their own priced at the average purchase prices
+func WithChemicalCost(schemes []models.SalaryScheme, prices []models.ChemicalPrice) []models.SalaryScheme {
+	result := make([]models.SalaryScheme, len(schemes))
+	for i, scheme := range schemes {
+		scheme.ChemicalRule = ruleWithCost(scheme.ChemicalRule, prices)
+		if len(scheme.PreviousVersions) > 0 {
+			scheme.PreviousVersions = WithChemicalCost(scheme.PreviousVersions, prices)
+		}
+		result[i] = scheme
+	}
+	return result
+}
+
+func ruleWithCost(rule *models.ChemicalRule, prices []models.ChemicalPrice) *models.ChemicalRule {
+	if rule == nil || rule.CostPerKg > 0 {
+		return rule
+	}
+	priced := *rule
+	priced.PurchasePrices = prices
+	return &priced
+}
+
+// chemicalCostOn returns the price of a kilogram of chemical under the rule
+// on the YYYY-MM-DD date, zero before the first purchase
+func chemicalCostOn(rule *models.ChemicalRule, date string) models.Money {
+	if rule.CostPerKg > 0 {
+		return rule.CostPerKg
+	}
+	var cost models.Money
+	for _, price := range rule.PurchasePrices {
+		if price.From > date {
+			break
+		}
+		cost = price.CostPerKg
+	}
+	return cost
+}
+
+// serviceUsage is an employee's norm and recorded grams for one service of a wash
+type serviceUsage struct {
+	serviceName string
+	norm        float64
+	actual      float64
+}
+
+// employeeChemicalUsage returns the employee's consumption for each service of
+// a wash they recorded consumption for, against the service's per-employee
+// norm. Services priced before the norm existed share their consumption
+// equally among the employees of the wash.
+func employeeChemicalUsage(event *models.WashEvent, employeeID string) []serviceUsage {
+	items := append([]models.PriceListItem{event.Services.Main}, event.Services.Additional...)
+
+	var usage []serviceUsage
+	for _, service := range items {
+		for _, ec := range service.EmployeeConsumptions {
+			if ec.EmployeeID != employeeID {
+				continue
+			}
+			usage = append(usage, serviceUsage{
+				serviceName: service.ServiceName,
+				norm:        employeeChemicalNorm(&service, len(event.EmployeeIDs)),
+				actual:      ec.Amount,
+			})
+			break
+		}
+	}
+	return usage
+}
+
+// employeeChemicalNorm returns the grams one employee is expected to use on
+// the service when employees work the wash
+func employeeChemicalNorm(service *models.PriceListItem, employees int) float64 {
+	if service.EmployeeChemicalNorm > 0 {
+		return service.EmployeeChemicalNorm
+	}
+	if employees == 0 {
+		return 0
+	}
+	return service.ChemicalConsumption / float64(employees)
+}
+
+// chemicalDay collects an employee's chemical consumption on one calendar day
+type chemicalDay struct {
+	date   time.Time
+	norm   float64
+	actual float64
+	scheme *models.SalaryScheme // scheme version in effect on the day
+}
+
+// trackChemicalUsage records the employee's consumption on a wash for
+// schemes with a chemical rule
+func trackChemicalUsage(days map[string]map[string]*chemicalDay, employeeID string, event *models.WashEvent, scheme *models.SalaryScheme) {
+	usage := employeeChemicalUsage(event, employeeID)
+	if len(usage) == 0 {
+		return
+	}
+
+	t, ok := ParseTimestamp(event.Timestamp)
+	if !ok {
+		return
+	}
+	t = t.In(time.Local)
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	key := date.Format("2006-01-02")
+
+	if days[employeeID] == nil {
+		days[employeeID] = make(map[string]*chemicalDay)
+	}
+	day, ok := days[employeeID][key]
+	if !ok {
+		day = &chemicalDay{date: date, scheme: scheme}
+		days[employeeID][key] = day
+	}
+
+	// Services without a norm give nothing to compare against
+	for _, u := range usage {
+		if u.norm > 0 {
+			day.norm += u.norm
+			day.actual += u.actual
+		}
+	}
+}
+
+// applyChemicalRules produces overuse deductions and savings bonuses for each
+// day, priced as chemical cost on the day. Only the part of the difference
+// beyond the tolerance is settled.
+func applyChemicalRules(days map[string]*chemicalDay) []models.SalaryAdjustment {
+	var adjustments []models.SalaryAdjustment
+
+	for key, day := range days {
+		rule := day.scheme.ChemicalRule
+		if rule == nil {
+			continue
+		}
+		costPerKg := chemicalCostOn(rule, key)
+		if costPerKg <= 0 {
+			continue
+		}
+
+		variance := day.actual - day.norm
+		settled := math.Abs(variance) - day.norm*rule.TolerancePercent/100
+		if settled <= 0 {
+			continue
+		}
+		settled = roundGrams(settled)
+		amount := costPerKg.Mul(settled/1000, models.RoundHalfEven)
+		if amount == 0 {
+			continue
+		}
+
+		adj := models.SalaryAdjustment{
+			Timestamp: day.date.Format(time.RFC3339),
+			Date:      key,
+		}
+		switch {
+		case variance > 0 && rule.DeductOveruse:
+			adj.Type = models.SalaryComponentChemOveruse
+			adj.Amount = -amount
+			adj.Description = fmt.Sprintf("Перерасход химии: %g г сверх нормы", settled)
+		case variance < 0 && rule.RewardSavings:
+			adj.Type = models.SalaryComponentChemSavings
+			adj.Amount = amount
+			adj.Description = fmt.Sprintf("Экономия химии: %g г", settled)
+		default:
+			continue
+		}
+		adjustments = append(adjustments, adj)
+	}
+
+	return adjustments
+}
+
+// ChemicalConsumptionReport compares each employee's recorded consumption in
+// the period with the norms of the services they performed. The variance is
+// priced at costPerKg; consumption on services without a norm is reported
+// separately.
+func ChemicalConsumptionReport(
+	washEvents []models.WashEvent,
+	employees []models.Employee,
+	period Period,
+	costPerKg models.Money,
+) []models.ChemicalConsumptionData {
+	data := make(map[string]*models.ChemicalConsumptionData, len(employees))
+	serviceIndex := make(map[string]map[string]int)
+	for _, emp := range employees {
+		data[emp.ID] = &models.ChemicalConsumptionData{
+			EmployeeID:   emp.ID,
+			EmployeeName: emp.FullName,
+			Services:     []models.ServiceChemicalConsumption{},
+		}
+		serviceIndex[emp.ID] = make(map[string]int)
+	}
+
+	for i := range washEvents {
+		event := &washEvents[i]
+		if !period.ContainsTimestamp(event.Timestamp) {
+			continue
+		}
+
+		for _, empID := range event.EmployeeIDs {
+			d, ok := data[empID]
+			if !ok {
+				continue
+			}
+			usage := employeeChemicalUsage(event, empID)
+			if len(usage) == 0 {
+				continue
+			}
+
+			d.WashCount++
+			for _, u := range usage {
+				if u.norm <= 0 {
+					d.UnnormedGrams += u.actual
+					continue
+				}
+				d.NormGrams += u.norm
+				d.ActualGrams += u.actual
+
+				idx, ok := serviceIndex[empID][u.serviceName]
+				if !ok {
+					idx = len(d.Services)
+					serviceIndex[empID][u.serviceName] = idx
+					d.Services = append(d.Services, models.ServiceChemicalConsumption{ServiceName: u.serviceName})
+				}
+				s := &d.Services[idx]
+				s.Count++
+				s.NormGrams += u.norm
+				s.ActualGrams += u.actual
+			}
+		}
+	}
+
+	result := make([]models.ChemicalConsumptionData, 0, len(data))
+	for _, d := range data {
+		d.NormGrams = roundGrams(d.NormGrams)
+		d.ActualGrams = roundGrams(d.ActualGrams)
+		d.UnnormedGrams = roundGrams(d.UnnormedGrams)
+		d.VarianceGrams = roundGrams(d.ActualGrams - d.NormGrams)
+		if d.NormGrams > 0 {
+			d.VariancePercent = math.Round(d.VarianceGrams/d.NormGrams*1000) / 10
+		}
+		d.VarianceCost = costPerKg.Mul(d.VarianceGrams/1000, models.RoundHalfEven)
+
+		for i := range d.Services {
+			s := &d.Services[i]
+			s.NormGrams = roundGrams(s.NormGrams)
+			s.ActualGrams = roundGrams(s.ActualGrams)
+			s.VarianceGrams = roundGrams(s.ActualGrams - s.NormGrams)
+		}
+		sort.SliceStable(d.Services, func(a, b int) bool {
+			return d.Services[a].VarianceGrams > d.Services[b].VarianceGrams
+		})
+
+		result = append(result, *d)
+	}
+
+	// Biggest overuse first
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].VarianceGrams != result[j].VarianceGrams {
+			return result[i].VarianceGrams > result[j].VarianceGrams
+		}
+		return result[i].EmployeeName < result[j].EmployeeName
+	})
+
+	return result
+}
+
+// roundGrams drops float noise below a hundredth of a gram
+func roundGrams(grams float64) float64 {
+	return math.Round(grams*100) / 100
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"sort"
+	"strings"
+
+	"backend-go/internal/models"
+)
+
+// BuildClientStatement fills the ledger of a client's washes and payments in
+// the period, oldest first. Everything before the period goes into the
+// opening balance.
+func BuildClientStatement(st *models.ClientStatement, washEvents []models.WashEvent, transactions []models.ClientTransaction, period Period) {
+	st.OpeningBalance = 0
+	st.Entries = []models.ClientStatementEntry{}
+
+	for _, event := range washEvents {
+		if !isClientWash(&event, st.ClientType, st.ClientID) {
+			continue
+		}
+		if period.IsBeforeTimestamp(event.Timestamp) {
+			st.OpeningBalance -= event.TotalAmount
+			continue
+		}
+		if !period.ContainsTimestamp(event.Timestamp) {
+			continue
+		}
+
+		var services []string
+		for _, service := range append([]models.PriceListItem{event.Services.Main}, event.Services.Additional...) {
+			if service.ServiceName != "" {
+				services = append(services, service.ServiceName)
+			}
+		}
+		st.Entries = append(st.Entries, models.ClientStatementEntry{
+			Timestamp:     event.Timestamp,
+			Type:          "wash",
+			ID:            event.ID,
+			VehicleNumber: event.VehicleNumber,
+			Services:      services,
+			Debit:         event.TotalAmount,
+		})
+	}
+
+	for _, trans := range transactions {
+		if period.IsBeforeTimestamp(trans.Date) {
+			st.OpeningBalance += trans.Amount
+			continue
+		}
+		if !period.ContainsTimestamp(trans.Date) {
+			continue
+		}
+		st.Entries = append(st.Entries, models.ClientStatementEntry{
+			Timestamp:   trans.Date,
+			Type:        "payment",
+			ID:          trans.ID,
+			Description: trans.Description,
+			Credit:      trans.Amount,
+		})
+	}
+
+	sort.SliceStable(st.Entries, func(i, j int) bool {
+		a, _ := ParseTimestamp(st.Entries[i].Timestamp)
+		b, _ := ParseTimestamp(st.Entries[j].Timestamp)
+		return a.Before(b)
+	})
+
+	balance := st.OpeningBalance
+	st.DebitTotal, st.CreditTotal = 0, 0
+	for i := range st.Entries {
+		entry := &st.Entries[i]
+		balance += entry.Credit - entry.Debit
+		entry.Balance = balance
+		st.DebitTotal += entry.Debit
+		st.CreditTotal += entry.Credit
+	}
+	st.ClosingBalance = balance
+}
+
+// csvAmount formats an amount for spreadsheets with a Russian locale
+func csvAmount(m models.Money) string {
+	if m == 0 {
+		return ""
+	}
+	return strings.Replace(m.String(), ".", ",", 1)
+}
+
+// ClientStatementCSV renders the statement as semicolon separated CSV with a
+// UTF-8 byte order mark, which spreadsheets with a Russian locale open as is
+func ClientStatementCSV(st *models.ClientStatement) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF")
+
+	w := csv.NewWriter(&buf)
+	w.Comma = ';'
+
+	balance := func(m models.Money) string {
+		return strings.Replace(m.String(), ".", ",", 1)
+	}
+
+	records := [][]string{
+		{"Дата", "Операция", "Госномер", "Услуги / описание", "Списано", "Зачислено", "Остаток"},
+		{"", "Входящий остаток", "", "", "", "", balance(st.OpeningBalance)},
+	}
+	for _, entry := range st.Entries {
+		operation, details := "Оплата", entry.Description
+		if entry.Type == "wash" {
+			operation, details = "Мойка", strings.Join(entry.Services, ", ")
+		}
+		records = append(records, []string{
+			formatTimestamp(entry.Timestamp),
+			operation,
+			entry.VehicleNumber,
+			details,
+			csvAmount(entry.Debit),
+			csvAmount(entry.Credit),
+			balance(entry.Balance),
+		})
+	}
+	records = append(records,
+		[]string{"", "Итого", "", "", csvAmount(st.DebitTotal), csvAmount(st.CreditTotal), ""},
+		[]string{"", "Исходящий остаток", "", "", "", "", balance(st.ClosingBalance)},
+	)
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// CounterAgentCreditStatus works out the counter agent's debt and whether it
+// is over its credit limit or overdue at the given time
+func CounterAgentCreditStatus(agent *models.CounterAgent, washEvents []models.WashEvent, transactions []models.ClientTransaction, now time.Time) models.CreditStatus {
+	status := models.CreditStatus{
+		CounterAgentID:   agent.ID,
+		CounterAgentName: agent.Name,
+		CreditLimit:      agent.CreditLimit,
+		PaymentTermDays:  agent.PaymentTermDays,
+	}
+
+	var paid models.Money
+	for _, trans := range transactions {
+		paid += trans.Amount
+	}
+
+	var washes []models.WashEvent
+	for _, event := range washEvents {
+		if isClientWash(&event, models.InvoiceClientCounterAgent, agent.ID) {
+			washes = append(washes, event)
+		}
+	}
+	sort.SliceStable(washes, func(i, j int) bool {
+		a, _ := ParseTimestamp(washes[i].Timestamp)
+		b, _ := ParseTimestamp(washes[j].Timestamp)
+		return a.Before(b)
+	})
+
+	// Payments settle the oldest washes first
+	dueBefore := now.AddDate(0, 0, -agent.PaymentTermDays)
+	for _, event := range washes {
+		unpaid := event.TotalAmount
+		if paid >= unpaid {
+			paid -= unpaid
+			continue
+		}
+		unpaid -= paid
+		paid = 0
+		status.Debt += unpaid
+
+		if agent.PaymentTermDays <= 0 {
+			continue
+		}
+		if t, ok := ParseTimestamp(event.Timestamp); ok && t.Before(dueBefore) {
+			status.OverdueAmount += unpaid
+			if status.OverdueSince == "" {
+				status.OverdueSince = event.Timestamp
+			}
+		}
+	}
+
+	status.Overdue = status.OverdueAmount > 0
+	if agent.CreditLimit > 0 {
+		status.Available = agent.CreditLimit - status.Debt
+		status.OverLimit = status.Debt > agent.CreditLimit
+	}
+	status.Blocked = agent.CreditBlock && (status.OverLimit || status.Overdue)
+	return status
+}
+
+// CreditAllows reports whether a new contract wash of the given amount stays
+// within the counter agent's credit terms
+func CreditAllows(status *models.CreditStatus, amount models.Money) bool {
+	if status.Overdue {
+		return false
+	}
+	return status.CreditLimit <= 0 || status.Debt+amount <= status.CreditLimit
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// EnterpriseDataFormat is the version of the 1C EnterpriseData exchange
+// format the export follows
+const EnterpriseDataFormat = "http://v8.1c.ru/edi/edi_stnd/EnterpriseData/1.8"
+
+// EnterpriseDataPayment is a client payment with the requisites of the client
+// who made it
+type EnterpriseDataPayment struct {
+	Transaction models.ClientTransaction
+	ClientName  string
+	Company     models.CounterAgentCompany
+}
+
+// EnterpriseDataInput is what goes into an export to 1C
+type EnterpriseDataInput struct {
+	Organization models.CounterAgentCompany
+	Invoices     []models.Invoice
+	Payments     []EnterpriseDataPayment
+	Expenses     []models.Expense
+	CreatedAt    time.Time
+}
+
+// The message follows the EnterpriseData layout: a header and a body of
+// catalog items and documents. Every object carries a Ссылка GUID derived from
+// our own ID, so importing an export again updates the records in 1C.
+type edMessage struct {
+	XMLName  xml.Name `xml:"Message"`
+	XMLNSMsg string   `xml:"xmlns:msg,attr"`
+	Header   edHeader `xml:"msg:Header"`
+	Body     edBody   `xml:"Body"`
+}
+
+type edHeader struct {
+	Format           string `xml:"msg:Format"`
+	CreationDate     string `xml:"msg:CreationDate"`
+	AvailableVersion string `xml:"msg:AvailableVersion"`
+}
+
+type edBody struct {
+	XMLNS   string        `xml:"xmlns,attr"`
+	Objects []interface{} `xml:""`
+}
+
+// edParty identifies the organization or a counterparty inside a document
+type edParty struct {
+	Ref      string `xml:"Ссылка"`
+	Name     string `xml:"Наименование"`
+	FullName string `xml:"НаименованиеПолное,omitempty"`
+	INN      string `xml:"ИНН,omitempty"`
+	KPP      string `xml:"КПП,omitempty"`
+	Kind     string `xml:"ЮридическоеФизическоеЛицо,omitempty"`
+}
+
+type edOrganization struct {
+	XMLName xml.Name `xml:"Справочник.Организации"`
+	Keys    edParty  `xml:"КлючевыеСвойства"`
+}
+
+type edCounterparty struct {
+	XMLName xml.Name `xml:"Справочник.Контрагенты"`
+	Keys    edParty  `xml:"КлючевыеСвойства"`
+}
+
+type edDocumentKeys struct {
+	Ref          string  `xml:"Ссылка"`
+	Date         string  `xml:"Дата"`
+	Number       string  `xml:"Номер,omitempty"`
+	Organization edParty `xml:"Организация"`
+}
+
+type edNamed struct {
+	Name string `xml:"Наименование"`
+}
+
+type edServiceRow struct {
+	Item     edNamed `xml:"Номенклатура"`
+	Unit     string  `xml:"ЕдиницаИзмерения"`
+	Quantity int     `xml:"Количество"`
+	Price    string  `xml:"Цена"`
+	Amount   string  `xml:"Сумма"`
+	VATRate  string  `xml:"СтавкаНДС"`
+}
+
+type edSalesDocument struct {
+	XMLName      xml.Name
+	Keys         edDocumentKeys `xml:"КлючевыеСвойства"`
+	DeletionMark bool           `xml:"ПометкаУдаления,omitempty"`
+	Operation    string         `xml:"ВидОперации,omitempty"`
+	Currency     string         `xml:"Валюта"`
+	Amount       string         `xml:"Сумма"`
+	Counterparty edParty        `xml:"Контрагент"`
+	Services     []edServiceRow `xml:"Услуги>Строка"`
+}
+
+type edPaymentDocument struct {
+	XMLName      xml.Name       `xml:"Документ.ПоступлениеБезналичныхДенежныхСредств"`
+	Keys         edDocumentKeys `xml:"КлючевыеСвойства"`
+	Operation    string         `xml:"ВидОперации"`
+	Amount       string         `xml:"Сумма"`
+	Counterparty edParty        `xml:"Контрагент"`
+	Purpose      string         `xml:"НазначениеПлатежа,omitempty"`
+}
+
+type edExpenseDocument struct {
+	XMLName   xml.Name       `xml:"Документ.РасходныйКассовыйОрдер"`
+	Keys      edDocumentKeys `xml:"КлючевыеСвойства"`
+	Operation string         `xml:"ВидОперации"`
+	Amount    string         `xml:"Сумма"`
+	Item      edNamed        `xml:"СтатьяДвиженияДенежныхСредств"`
+	Basis     string         `xml:"Основание,omitempty"`
+}
+
+// stableGUID derives a GUID from a kind of object and our ID for it. The same
+// object always gets the same GUID.
+func stableGUID(kind, id string) string {
+	sum := sha1.Sum([]byte("backend-go/1c/" + kind + "/" + id))
+	sum[6] = sum[6]&0x0f | 0x50 // version 5
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// edAmount formats an amount the way 1C reads numbers
+func edAmount(m models.Money) string {
+	return m.String()
+}
+
+// edDate formats a date or timestamp as the xs:dateTime 1C expects
+func edDate(value string) string {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Format("2006-01-02T15:04:05")
+	}
+	if t, ok := ParseTimestamp(value); ok {
+		return t.In(time.Local).Format("2006-01-02T15:04:05")
+	}
+	return value
+}
+
+// partyKey identifies a counterparty by INN and KPP, or by our client when it
+// has no INN on file
+func partyKey(company *models.CounterAgentCompany, fallback string) string {
+	if company.INN != "" {
+		return company.INN + "/" + company.KPP
+	}
+	return "client/" + fallback
+}
+
+func edPartyOf(kind string, company *models.CounterAgentCompany, fallbackID, fallbackName string) edParty {
+	name := companyName(company)
+	if name == "" {
+		name = fallbackName
+	}
+	p := edParty{
+		Ref:  stableGUID(kind, partyKey(company, fallbackID)),
+		Name: name,
+		INN:  company.INN,
+		KPP:  company.KPP,
+	}
+	switch len(company.INN) {
+	case 10:
+		p.Kind = "ЮридическоеЛицо"
+	case 12:
+		p.Kind = "ФизическоеЛицо"
+	}
+	return p
+}
+
+// BuildEnterpriseData renders invoices with their acts, client payments and
+// expenses as a 1C EnterpriseData message. Draft invoices are left out;
+// cancelled ones are exported marked for deletion so 1C drops them too.
+func BuildEnterpriseData(in *EnterpriseDataInput) ([]byte, error) {
+	org := edPartyOf("organization", &in.Organization, "own", "Организация")
+	org.Kind = ""
+	orgFull := org
+	orgFull.FullName = org.Name
+
+	body := edBody{XMLNS: EnterpriseDataFormat}
+	body.Objects = append(body.Objects, edOrganization{Keys: orgFull})
+
+	seen := make(map[string]bool)
+	counterparty := func(p edParty) edParty {
+		if !seen[p.Ref] {
+			seen[p.Ref] = true
+			full := p
+			full.FullName = p.Name
+			body.Objects = append(body.Objects, edCounterparty{Keys: full})
+		}
+		p.Kind = ""
+		return p
+	}
+
+	var documents []interface{}
+	for _, inv := range in.Invoices {
+		if inv.Status == models.InvoiceDraft {
+			continue
+		}
+		customer := counterparty(edPartyOf("counterparty", &inv.Customer, inv.ClientID, inv.ClientName))
+
+		rows := make([]edServiceRow, len(inv.Lines))
+		for i, line := range inv.Lines {
+			rows[i] = edServiceRow{
+				Item:     edNamed{line.ServiceName},
+				Unit:     line.Unit,
+				Quantity: line.Quantity,
+				Price:    edAmount(line.Price),
+				Amount:   edAmount(line.Amount),
+				VATRate:  "БезНДС",
+			}
+		}
+
+		number := fmt.Sprintf("%d", inv.Number)
+		cancelled := inv.Status == models.InvoiceCancelled
+		documents = append(documents,
+			edSalesDocument{
+				XMLName:      xml.Name{Local: "Документ.СчетНаОплатуПокупателю"},
+				Keys:         edDocumentKeys{Ref: stableGUID("invoice", inv.ID), Date: edDate(inv.Date), Number: number, Organization: org},
+				DeletionMark: cancelled,
+				Currency:     "643",
+				Amount:       edAmount(inv.Total),
+				Counterparty: customer,
+				Services:     rows,
+			},
+			edSalesDocument{
+				XMLName:      xml.Name{Local: "Документ.РеализацияТоваровУслуг"},
+				Keys:         edDocumentKeys{Ref: stableGUID("act", inv.ID), Date: edDate(inv.Date), Number: number, Organization: org},
+				DeletionMark: cancelled,
+				Operation:    "РеализацияКлиенту",
+				Currency:     "643",
+				Amount:       edAmount(inv.Total),
+				Counterparty: customer,
+				Services:     rows,
+			},
+		)
+	}
+
+	for _, p := range in.Payments {
+		payer := counterparty(edPartyOf("counterparty", &p.Company, p.Transaction.ClientID, p.ClientName))
+		documents = append(documents, edPaymentDocument{
+			Keys:         edDocumentKeys{Ref: stableGUID("payment", p.Transaction.ID), Date: edDate(p.Transaction.Date), Organization: org},
+			Operation:    "ОплатаОтПокупателя",
+			Amount:       edAmount(p.Transaction.Amount),
+			Counterparty: payer,
+			Purpose:      p.Transaction.Description,
+		})
+	}
+
+	for _, exp := range in.Expenses {
+		item := exp.Category
+		if item == "" {
+			item = "Прочие расходы"
+		}
+		documents = append(documents, edExpenseDocument{
+			Keys:      edDocumentKeys{Ref: stableGUID("expense", exp.ID), Date: edDate(exp.Date), Organization: org},
+			Operation: "ПрочийРасход",
+			Amount:    edAmount(exp.Amount),
+			Item:      edNamed{item},
+			Basis:     strings.TrimSpace(exp.Description),
+		})
+	}
+	body.Objects = append(body.Objects, documents...)
+
+	msg := edMessage{
+		XMLNSMsg: "http://www.1c.ru/SSL/Exchange/Message",
+		Header: edHeader{
+			Format:           EnterpriseDataFormat,
+			CreationDate:     in.CreatedAt.Format("2006-01-02T15:04:05"),
+			AvailableVersion: "1.8",
+		},
+		Body: body,
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "\t")
+	if err := enc.Encode(msg); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
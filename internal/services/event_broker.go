@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Event types pushed to live clients
+const (
+	EventWashCreated          = "washEvent.created"
+	EventWashUpdated          = "washEvent.updated"
+	EventWashDeleted          = "washEvent.deleted"
+	EventInventoryUpdated     = "inventory.updated"
+	EventClientPaymentCreated = "clientPayment.created"
+	EventClientPaymentDeleted = "clientPayment.deleted"
+	EventShiftOpened          = "shift.opened"
+	EventShiftClosed          = "shift.closed"
+	EventStreamReset          = "stream.reset"
+)
+
+const (
+	defaultEventHistorySize    = 256
+	defaultSubscriberQueueSize = 64
+)
+
+// Event represents a single server-sent event
+type Event struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	Timestamp string          `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// EventBroker fans out events to live subscribers and keeps a bounded
+// history so reconnecting clients can replay what they missed.
+// Event IDs of a run start after the boot time in microseconds, so they
+// keep growing across restarts and an ID from a previous run is recognized.
+type EventBroker struct {
+	mu          sync.RWMutex
+	startID     uint64
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[chan Event]struct{}
+}
+
+func NewEventBroker() *EventBroker {
+	startID := uint64(time.Now().UnixMicro())
+	return &EventBroker{
+		startID:     startID,
+		lastID:      startID,
+		historySize: defaultEventHistorySize,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish records an event and delivers it to all subscribers.
+// A slow subscriber whose queue is full is closed rather than left with a
+// gap; its client reconnects and catches up through Last-Event-ID replay.
+func (b *EventBroker) Publish(eventType string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event := Event{
+		ID:        b.lastID,
+		Type:      eventType,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Data:      data,
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber. Events recorded after lastEventID are
+// returned for replay; complete is false when some of them have already been
+// dropped from history and the client should reload its state.
+func (b *EventBroker) Subscribe(lastEventID uint64) (ch chan Event, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch = make(chan Event, defaultSubscriberQueueSize)
+	b.subscribers[ch] = struct{}{}
+
+	if lastEventID == 0 || lastEventID == b.lastID {
+		return ch, nil, true
+	}
+
+	// An ID outside this run means the client saw a previous run, whose
+	// events are lost, and has to reload
+	if lastEventID < b.startID || lastEventID > b.lastID {
+		return ch, nil, false
+	}
+
+	complete = true
+
+	if len(b.history) > 0 && b.history[0].ID > lastEventID+1 {
+		complete = false
+	}
+
+	for _, event := range b.history {
+		if event.ID > lastEventID {
+			replay = append(replay, event)
+		}
+	}
+
+	return ch, replay, complete
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *EventBroker) Unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// LastID returns the ID of the most recently published event
+func (b *EventBroker) LastID() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.lastID
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"backend-go/internal/models"
+	"backend-go/internal/pdf"
+)
+
+// invoiceDocumentView holds the formatted invoice and act for both renderers
+type invoiceDocumentView struct {
+	Number        string
+	Date          string
+	Cancelled     bool
+	Seller        models.CounterAgentCompany
+	SellerLine    string
+	SellerActLine string
+	CustomerLine  string
+	CustomerAct   string
+	Lines         []invoiceLineView
+	Total         string
+	TotalInWords  string
+	Count         int
+}
+
+type invoiceLineView struct {
+	No       int
+	Name     string
+	Quantity int
+	Unit     string
+	Price    string
+	Amount   string
+}
+
+// InvoiceNumberLabel returns the printed number of an invoice; drafts have none
+func InvoiceNumberLabel(inv *models.Invoice) string {
+	if inv.Number == 0 {
+		return "б/н"
+	}
+	return fmt.Sprintf("%d", inv.Number)
+}
+
+// requisitesLine joins the non-empty requisites with commas
+func requisitesLine(parts ...string) string {
+	var kept []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			kept = append(kept, p)
+		}
+	}
+	return strings.Join(kept, ", ")
+}
+
+func withLabel(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + " " + value
+}
+
+// companyName returns the name a company is invoiced under
+func companyName(c *models.CounterAgentCompany) string {
+	if c.CustomerName != "" {
+		return c.CustomerName
+	}
+	return c.CompanyName
+}
+
+func invoiceDocument(inv *models.Invoice) invoiceDocumentView {
+	v := invoiceDocumentView{
+		Number:    InvoiceNumberLabel(inv),
+		Date:      documentDate(inv.Date),
+		Cancelled: inv.Status == models.InvoiceCancelled,
+		Seller:    inv.Seller,
+		SellerLine: requisitesLine(companyName(&inv.Seller), withLabel("ИНН", inv.Seller.INN),
+			inv.Seller.LegalAddress),
+		SellerActLine: requisitesLine(companyName(&inv.Seller), withLabel("ИНН:", inv.Seller.INN),
+			withLabel("ОГРН:", inv.Seller.OGRNNumber), withLabel("Адрес:", inv.Seller.LegalAddress)),
+		CustomerLine: requisitesLine(companyName(&inv.Customer), withLabel("ИНН", inv.Customer.INN),
+			withLabel("КПП", inv.Customer.KPP), inv.Customer.LegalAddress),
+		CustomerAct: requisitesLine(companyName(&inv.Customer), withLabel("ИНН:", inv.Customer.INN),
+			withLabel("КПП:", inv.Customer.KPP), withLabel("Адрес:", inv.Customer.LegalAddress)),
+		Total:        FormatRubles(inv.Total),
+		TotalInWords: AmountInWords(inv.Total),
+		Count:        len(inv.Lines),
+	}
+
+	for i, line := range inv.Lines {
+		v.Lines = append(v.Lines, invoiceLineView{
+			No:       i + 1,
+			Name:     line.ServiceName,
+			Quantity: line.Quantity,
+			Unit:     line.Unit,
+			Price:    FormatRubles(line.Price),
+			Amount:   FormatRubles(line.Amount),
+		})
+	}
+
+	return v
+}
+
+// invoiceTemplates holds the invoice and act pages; the shared style is also
+// used by the reconciliation statement
+var invoiceTemplates = template.Must(template.New("").Parse(`{{define "style"}}<style>
+body { font-family: Arial, Helvetica, sans-serif; font-size: 12px; margin: 24px; color: #000; }
+h1 { font-size: 16px; border-bottom: 2px solid #000; padding-bottom: 4px; margin: 16px 0; }
+h2 { font-size: 16px; text-align: center; margin: 24px 0; }
+table { border-collapse: collapse; width: 100%; }
+table.lines th, table.lines td { border: 1px solid #000; padding: 2px 4px; }
+td.num { text-align: right; white-space: nowrap; }
+td.center { text-align: center; }
+table.bank td { padding: 2px 4px; vertical-align: top; }
+table.totals td { text-align: right; font-weight: bold; padding: 2px 4px; }
+.cancelled { color: #b00; font-size: 18px; font-weight: bold; text-align: center; border: 2px solid #b00; padding: 6px; }
+.signatures { display: flex; justify-content: space-between; margin-top: 48px; }
+.signatures div { width: 40%; }
+.sign-line { border-bottom: 1px solid #000; margin-top: 32px; }
+.hint { font-size: 10px; text-align: center; }
+.page { page-break-after: always; }
+@media print { @page { size: A4; margin: 20mm; } body { margin: 0; } }
+</style>{{end}}
+
+{{define "invoicePage"}}<table class="bank">
+<tr><td style="width:50%">Банк получателя<br><b>{{.Seller.BankName}}</b></td><td>БИК<br>Сч. №</td><td><b>{{.Seller.BIK}}</b><br><b>{{.Seller.CorrespondentAccount}}</b></td></tr>
+<tr><td>ИНН {{.Seller.INN}}<br>Получатель</td><td colspan="2">КПП {{.Seller.KPP}}<br><b>{{.Seller.CompanyName}}</b></td></tr>
+<tr><td>Сч. №</td><td colspan="2"><b>{{.Seller.SettlementAccount}}</b></td></tr>
+</table>
+<h1>Счёт на оплату № {{.Number}} от {{.Date}} г.</h1>
+<table>
+<tr><td style="width:90px">Исполнитель:</td><td><b>{{.SellerLine}}</b></td></tr>
+<tr><td>Заказчик:</td><td><b>{{.CustomerLine}}</b></td></tr>
+</table>
+<table class="lines" style="margin:16px 0">
+<tr><th>№</th><th>Товары (работы, услуги)</th><th>Кол-во</th><th>Ед.</th><th>Цена</th><th>Сумма</th></tr>
+{{range .Lines}}<tr><td class="center">{{.No}}</td><td>{{.Name}}</td><td class="num">{{.Quantity}}</td><td>{{.Unit}}</td><td class="num">{{.Price}}</td><td class="num">{{.Amount}}</td></tr>
+{{end}}</table>
+<table class="totals">
+<tr><td>Итого:</td><td style="width:120px">{{.Total}}</td></tr>
+<tr><td>В том числе НДС:</td><td>Без НДС</td></tr>
+<tr><td>Всего к оплате:</td><td>{{.Total}}</td></tr>
+</table>
+<p>Всего наименований {{.Count}}, на сумму {{.Total}} руб.</p>
+<p><b>{{.TotalInWords}}</b></p>
+<p style="margin-top:32px; border-top:2px solid #000; padding-top:16px"><b>Руководитель</b> ______________________ / {{.Seller.OwnerName}} /</p>
+{{end}}
+
+{{define "actPage"}}<h2>Акт № {{.Number}} от {{.Date}} г.</h2>
+<p><b>Исполнитель:</b> {{.SellerActLine}}</p>
+<p><b>Заказчик:</b> {{.CustomerAct}}</p>
+<table class="lines" style="margin:16px 0">
+<tr><th>№</th><th>Наименование работы (услуги)</th><th>Ед. изм.</th><th>Кол-во</th><th>Цена</th><th>Сумма</th></tr>
+{{range .Lines}}<tr><td class="center">{{.No}}</td><td>{{.Name}}</td><td class="center">{{.Unit}}</td><td class="num">{{.Quantity}}</td><td class="num">{{.Price}}</td><td class="num">{{.Amount}}</td></tr>
+{{end}}<tr><td colspan="5" class="num"><b>Итого:</b></td><td class="num"><b>{{.Total}}</b></td></tr>
+</table>
+<p>Всего оказано услуг {{.Count}}, на сумму {{.Total}} руб.</p>
+<p><b>{{.TotalInWords}}</b></p>
+<p><b>Без налога (НДС).</b></p>
+<p>Вышеперечисленные работы (услуги) выполнены полностью и в срок. Заказчик претензий по объёму, качеству и срокам оказания услуг не имеет.</p>
+<div class="signatures">
+<div><b>Исполнитель</b><div class="sign-line"></div><p class="hint">(подпись)</p><p class="hint">М.П.</p></div>
+<div><b>Заказчик</b><div class="sign-line"></div><p class="hint">(подпись)</p><p class="hint">М.П.</p></div>
+</div>
+{{end}}
+
+{{define "invoice"}}<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Счёт № {{.Number}} от {{.Date}}</title>
+{{template "style"}}
+</head>
+<body>
+{{if .Cancelled}}<p class="cancelled">АННУЛИРОВАН</p>{{end}}
+<div class="page">
+{{template "invoicePage" .}}
+</div>
+<div>
+{{template "actPage" .}}
+</div>
+</body>
+</html>
+{{end}}
+
+{{define "act"}}<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Акт № {{.Number}} от {{.Date}}</title>
+{{template "style"}}
+</head>
+<body>
+{{if .Cancelled}}<p class="cancelled">АННУЛИРОВАН</p>{{end}}
+{{template "actPage" .}}
+</body>
+</html>
+{{end}}`))
+
+// RenderInvoiceHTML renders the invoice and its act as a printable HTML page
+func RenderInvoiceHTML(inv *models.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := invoiceTemplates.ExecuteTemplate(&buf, "invoice", invoiceDocument(inv)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderActHTML renders the act of services rendered for an invoice as a
+// printable HTML page
+func RenderActHTML(inv *models.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := invoiceTemplates.ExecuteTemplate(&buf, "act", invoiceDocument(inv)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Invoice PDF layout in points
+const (
+	invoiceMargin     = 50.0
+	invoiceLineHeight = 13.0
+	invoiceFontSize   = 9.0
+)
+
+// invoicePDF lays out invoice text top to bottom, adding pages as needed
+type invoicePDF struct {
+	doc *pdf.Document
+	y   float64
+}
+
+func (w *invoicePDF) newPage() {
+	w.doc.AddPage()
+	w.y = invoiceMargin
+}
+
+func (w *invoicePDF) ensure(height float64) {
+	if w.doc.PageCount() == 0 || w.y+height > pdf.PageHeight-invoiceMargin {
+		w.newPage()
+	}
+}
+
+// paragraph draws wrapped text starting at x; label is drawn bold in front
+func (w *invoicePDF) paragraph(x float64, label, text string, size float64, bold bool) {
+	width := pdf.PageWidth - invoiceMargin - x
+	if label != "" {
+		w.ensure(invoiceLineHeight)
+		w.y += invoiceLineHeight
+		w.doc.Text(x, w.y, size, true, pdf.AlignLeft, label)
+		offset := pdf.TextWidth(label+" ", size, true)
+		lines := pdf.Wrap(text, width-offset, size, bold)
+		w.doc.Text(x+offset, w.y, size, bold, pdf.AlignLeft, lines[0])
+		for _, line := range lines[1:] {
+			w.ensure(invoiceLineHeight)
+			w.y += invoiceLineHeight
+			w.doc.Text(x+offset, w.y, size, bold, pdf.AlignLeft, line)
+		}
+		return
+	}
+	for _, line := range pdf.Wrap(text, width, size, bold) {
+		w.ensure(invoiceLineHeight)
+		w.y += invoiceLineHeight
+		w.doc.Text(x, w.y, size, bold, pdf.AlignLeft, line)
+	}
+}
+
+// invoiceColumns are the x positions of the line table columns: number,
+// name, quantity, unit, price, amount and the right edge
+var invoiceColumns = []float64{invoiceMargin, invoiceMargin + 22, invoiceMargin + 290, invoiceMargin + 335, invoiceMargin + 370, invoiceMargin + 432, pdf.PageWidth - invoiceMargin}
+
+var invoiceAligns = []pdf.Align{pdf.AlignCenter, pdf.AlignLeft, pdf.AlignRight, pdf.AlignCenter, pdf.AlignRight, pdf.AlignRight}
+
+// tableRow draws a bordered table row; edges are the x positions of the cell
+// borders, one more than the values
+func (w *invoicePDF) tableRow(edges []float64, aligns []pdf.Align, values []string, bold bool) {
+	w.ensure(invoiceLineHeight + 4)
+	top := w.y
+	w.y += invoiceLineHeight
+	for i, value := range values {
+		left, right := edges[i], edges[i+1]
+		value = pdf.Truncate(value, right-left-6, invoiceFontSize, bold)
+		switch aligns[i] {
+		case pdf.AlignRight:
+			w.doc.Text(right-3, w.y-3, invoiceFontSize, bold, pdf.AlignRight, value)
+		case pdf.AlignCenter:
+			w.doc.Text((left+right)/2, w.y-3, invoiceFontSize, bold, pdf.AlignCenter, value)
+		default:
+			w.doc.Text(left+3, w.y-3, invoiceFontSize, bold, pdf.AlignLeft, value)
+		}
+	}
+
+	// Cell borders
+	w.doc.Line(edges[0], top, edges[len(edges)-1], top)
+	w.doc.Line(edges[0], w.y, edges[len(edges)-1], w.y)
+	for _, x := range edges {
+		w.doc.Line(x, top, x, w.y)
+	}
+}
+
+// table draws a header and rows, repeating the header on each new page
+func (w *invoicePDF) table(edges []float64, aligns []pdf.Align, header []string, rows [][]string) {
+	w.y += 6
+	w.tableRow(edges, aligns, header, true)
+	for _, row := range rows {
+		if w.y+invoiceLineHeight > pdf.PageHeight-invoiceMargin {
+			w.newPage()
+			w.tableRow(edges, aligns, header, true)
+		}
+		w.tableRow(edges, aligns, row, false)
+	}
+}
+
+func (w *invoicePDF) linesTable(v *invoiceDocumentView, header []string) {
+	rows := make([][]string, len(v.Lines))
+	for i, line := range v.Lines {
+		rows[i] = []string{fmt.Sprint(line.No), line.Name, fmt.Sprint(line.Quantity), line.Unit, line.Price, line.Amount}
+	}
+	w.table(invoiceColumns, invoiceAligns, header, rows)
+}
+
+// totalLine draws a right aligned label and amount
+func (w *invoicePDF) totalLine(label, amount string) {
+	right := pdf.PageWidth - invoiceMargin
+	w.ensure(invoiceLineHeight)
+	w.y += invoiceLineHeight
+	w.doc.Text(right-100, w.y, invoiceFontSize, true, pdf.AlignRight, label)
+	w.doc.Text(right-3, w.y, invoiceFontSize, true, pdf.AlignRight, amount)
+}
+
+func (w *invoicePDF) signatures(left, right string) {
+	w.ensure(invoiceLineHeight * 5)
+	half := (pdf.PageWidth - 2*invoiceMargin) / 2
+	w.y += invoiceLineHeight * 2
+	w.doc.Text(invoiceMargin, w.y, invoiceFontSize, true, pdf.AlignLeft, left)
+	w.doc.Text(invoiceMargin+half+20, w.y, invoiceFontSize, true, pdf.AlignLeft, right)
+	w.y += invoiceLineHeight * 2
+	w.doc.Line(invoiceMargin, w.y, invoiceMargin+half-20, w.y)
+	w.doc.Line(invoiceMargin+half+20, w.y, pdf.PageWidth-invoiceMargin, w.y)
+	w.y += invoiceLineHeight
+	w.doc.Text(invoiceMargin+(half-20)/2, w.y, 7, false, pdf.AlignCenter, "(подпись)   М.П.")
+	w.doc.Text(invoiceMargin+half+20+(half-20)/2, w.y, 7, false, pdf.AlignCenter, "(подпись)   М.П.")
+}
+
+func (w *invoicePDF) cancelledMark(v *invoiceDocumentView) {
+	if v.Cancelled {
+		w.y += 16
+		w.doc.Text(pdf.PageWidth/2, w.y, 14, true, pdf.AlignCenter, "АННУЛИРОВАН")
+		w.y += 6
+	}
+}
+
+func (w *invoicePDF) invoicePage(v *invoiceDocumentView) {
+	x := invoiceMargin
+	right := pdf.PageWidth - invoiceMargin
+
+	w.newPage()
+	w.cancelledMark(v)
+	w.paragraph(x, "Банк получателя:", v.Seller.BankName, invoiceFontSize, false)
+	w.paragraph(x, "БИК:", v.Seller.BIK, invoiceFontSize, false)
+	w.paragraph(x, "Корр. счёт:", v.Seller.CorrespondentAccount, invoiceFontSize, false)
+	w.paragraph(x, "Получатель:", requisitesLine(v.Seller.CompanyName, withLabel("ИНН", v.Seller.INN), withLabel("КПП", v.Seller.KPP)), invoiceFontSize, false)
+	w.paragraph(x, "Расчётный счёт:", v.Seller.SettlementAccount, invoiceFontSize, false)
+
+	w.y += 24
+	w.doc.Text(x, w.y, 13, true, pdf.AlignLeft, fmt.Sprintf("Счёт на оплату № %s от %s г.", v.Number, v.Date))
+	w.y += 5
+	w.doc.Line(x, w.y, right, w.y)
+	w.y += 4
+	w.paragraph(x, "Исполнитель:", v.SellerLine, invoiceFontSize, false)
+	w.paragraph(x, "Заказчик:", v.CustomerLine, invoiceFontSize, false)
+
+	w.linesTable(v, []string{"№", "Товары (работы, услуги)", "Кол-во", "Ед.", "Цена", "Сумма"})
+	w.totalLine("Итого:", v.Total)
+	w.totalLine("В том числе НДС:", "Без НДС")
+	w.totalLine("Всего к оплате:", v.Total)
+
+	w.y += 6
+	w.paragraph(x, "", fmt.Sprintf("Всего наименований %d, на сумму %s руб.", v.Count, v.Total), invoiceFontSize, false)
+	w.paragraph(x, "", v.TotalInWords, invoiceFontSize, true)
+
+	w.ensure(invoiceLineHeight * 4)
+	w.y += invoiceLineHeight * 3
+	w.doc.Text(x, w.y, invoiceFontSize, true, pdf.AlignLeft, "Руководитель")
+	w.doc.Line(x+80, w.y+2, right-140, w.y+2)
+	w.doc.Text(right, w.y, invoiceFontSize, false, pdf.AlignRight, "/ "+v.Seller.OwnerName+" /")
+}
+
+func (w *invoicePDF) actPage(v *invoiceDocumentView) {
+	x := invoiceMargin
+
+	w.newPage()
+	w.cancelledMark(v)
+	w.y += 20
+	w.doc.Text(pdf.PageWidth/2, w.y, 13, true, pdf.AlignCenter, fmt.Sprintf("Акт № %s от %s г.", v.Number, v.Date))
+	w.y += 10
+	w.paragraph(x, "Исполнитель:", v.SellerActLine, invoiceFontSize, false)
+	w.paragraph(x, "Заказчик:", v.CustomerAct, invoiceFontSize, false)
+
+	w.linesTable(v, []string{"№", "Наименование работы (услуги)", "Кол-во", "Ед.", "Цена", "Сумма"})
+	w.totalLine("Итого:", v.Total)
+
+	w.y += 6
+	w.paragraph(x, "", fmt.Sprintf("Всего оказано услуг %d, на сумму %s руб.", v.Count, v.Total), invoiceFontSize, false)
+	w.paragraph(x, "", v.TotalInWords, invoiceFontSize, true)
+	w.paragraph(x, "", "Без налога (НДС).", invoiceFontSize, true)
+	w.y += 6
+	w.paragraph(x, "", "Вышеперечисленные работы (услуги) выполнены полностью и в срок. Заказчик претензий по объёму, качеству и срокам оказания услуг не имеет.", invoiceFontSize, false)
+	w.signatures("Исполнитель", "Заказчик")
+}
+
+// RenderInvoicePDF renders the invoice and its act as an A4 PDF document,
+// each starting on its own page
+func RenderInvoicePDF(inv *models.Invoice) []byte {
+	v := invoiceDocument(inv)
+	w := &invoicePDF{doc: pdf.New(fmt.Sprintf("Счёт № %s от %s", v.Number, v.Date))}
+	w.invoicePage(&v)
+	w.actPage(&v)
+	return w.doc.Bytes()
+}
+
+// RenderActPDF renders the act of services rendered for an invoice on its own
+func RenderActPDF(inv *models.Invoice) []byte {
+	v := invoiceDocument(inv)
+	w := &invoicePDF{doc: pdf.New(fmt.Sprintf("Акт № %s от %s", v.Number, v.Date))}
+	w.actPage(&v)
+	return w.doc.Bytes()
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// InvoiceUnit is the unit services are billed in
+const InvoiceUnit = "шт"
+
+// invoiceTransitions lists the statuses an invoice can move to from each status
+var invoiceTransitions = map[models.InvoiceStatus][]models.InvoiceStatus{
+	models.InvoiceDraft:  {models.InvoiceIssued},
+	models.InvoiceIssued: {models.InvoicePaid, models.InvoiceCancelled},
+	models.InvoicePaid:   {models.InvoiceIssued},
+}
+
+// CanTransitionInvoice reports whether an invoice may move between statuses.
+// Drafts are deleted rather than cancelled; a paid invoice can only go back
+// to issued when the payment is undone.
+func CanTransitionInvoice(from, to models.InvoiceStatus) bool {
+	for _, status := range invoiceTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isClientWash reports whether the wash was paid by the counter agent or
+// aggregator
+func isClientWash(event *models.WashEvent, clientType models.InvoiceClientType, clientID string) bool {
+	method := models.WashPaymentCounterAgentContract
+	if clientType == models.InvoiceClientAggregator {
+		method = models.WashPaymentAggregator
+	}
+	return event.PaymentMethod == method && event.SourceID == clientID
+}
+
+// InvoiceWashes returns the washes of the client in the period, oldest first
+func InvoiceWashes(washEvents []models.WashEvent, clientType models.InvoiceClientType, clientID string, period Period) []models.WashEvent {
+	var result []models.WashEvent
+	for _, event := range washEvents {
+		if isClientWash(&event, clientType, clientID) && period.ContainsTimestamp(event.Timestamp) {
+			result = append(result, event)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp < result[j].Timestamp
+	})
+	return result
+}
+
+// BuildInvoiceLines aggregates the services of the washes into invoice lines.
+// Services sold at different prices get a line per price.
+func BuildInvoiceLines(washEvents []models.WashEvent) []models.InvoiceLine {
+	type key struct {
+		name  string
+		price models.Money
+	}
+
+	index := make(map[key]int)
+	lines := []models.InvoiceLine{}
+	for _, event := range washEvents {
+		for _, service := range append([]models.PriceListItem{event.Services.Main}, event.Services.Additional...) {
+			if service.ServiceName == "" {
+				continue
+			}
+			k := key{service.ServiceName, service.Price}
+			i, ok := index[k]
+			if !ok {
+				i = len(lines)
+				index[k] = i
+				lines = append(lines, models.InvoiceLine{
+					ServiceName: service.ServiceName,
+					Unit:        InvoiceUnit,
+					Price:       service.Price,
+				})
+			}
+			lines[i].Quantity++
+			lines[i].Amount += service.Price
+		}
+	}
+	return lines
+}
+
+// ValidateInvoiceLines checks lines entered by hand: every line names a
+// service, has a positive quantity and a price that is not negative
+func ValidateInvoiceLines(lines []models.InvoiceLine) error {
+	for i, line := range lines {
+		if strings.TrimSpace(line.ServiceName) == "" {
+			return fmt.Errorf("line %d: serviceName is required", i+1)
+		}
+		if line.Quantity <= 0 {
+			return fmt.Errorf("line %d: quantity must be positive", i+1)
+		}
+		if line.Price < 0 {
+			return fmt.Errorf("line %d: price must not be negative", i+1)
+		}
+	}
+	return nil
+}
+
+// NormalizeInvoiceLines fills in units and recalculates line amounts
+func NormalizeInvoiceLines(lines []models.InvoiceLine) []models.InvoiceLine {
+	result := make([]models.InvoiceLine, len(lines))
+	for i, line := range lines {
+		if line.Unit == "" {
+			line.Unit = InvoiceUnit
+		}
+		line.Amount = line.Price.Mul(float64(line.Quantity), models.RoundHalfEven)
+		result[i] = line
+	}
+	return result
+}
+
+// InvoiceTotal sums the invoice lines
+func InvoiceTotal(lines []models.InvoiceLine) models.Money {
+	var total models.Money
+	for _, line := range lines {
+		total += line.Amount
+	}
+	return total
+}
+
+// NextInvoiceNumber returns the number the next invoice issued in the year
+// gets. Issued invoices are never deleted, so numbers have no gaps.
+func NextInvoiceNumber(invoices []models.Invoice, year int) int {
+	last := 0
+	for _, inv := range invoices {
+		if inv.Year == year && inv.Number > last {
+			last = inv.Number
+		}
+	}
+	return last + 1
+}
+
+// InvoiceYear returns the calendar year of an invoice date
+func InvoiceYear(date string) int {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return time.Now().Year()
+	}
+	return t.Year()
+}
+
+// CustomerCompany picks the requisites an invoice is made out to: the company
+// with the given name, or the first one
+func CustomerCompany(companies []models.CounterAgentCompany, name string) (models.CounterAgentCompany, bool) {
+	for _, company := range companies {
+		if name == "" || company.CompanyName == name || company.CustomerName == name {
+			return company, true
+		}
+	}
+	return models.CounterAgentCompany{}, false
+}
+
+var (
+	wordsUnits = [2][20]string{
+		{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять", "десять",
+			"одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать",
+			"семнадцать", "восемнадцать", "девятнадцать"},
+		{"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять", "десять",
+			"одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать",
+			"семнадцать", "восемнадцать", "девятнадцать"},
+	}
+	wordsTens     = [10]string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	wordsHundreds = [10]string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+
+	// Orders of magnitude from thousands up with their gender (1 is feminine)
+	wordsScales = []struct {
+		forms    [3]string
+		feminine int
+	}{
+		{[3]string{"тысяча", "тысячи", "тысяч"}, 1},
+		{[3]string{"миллион", "миллиона", "миллионов"}, 0},
+		{[3]string{"миллиард", "миллиарда", "миллиардов"}, 0},
+	}
+)
+
+// plural picks the Russian plural form for n: one, few or many
+func plural(n int64, forms [3]string) string {
+	n %= 100
+	if n > 10 && n < 20 {
+		return forms[2]
+	}
+	switch n % 10 {
+	case 1:
+		return forms[0]
+	case 2, 3, 4:
+		return forms[1]
+	default:
+		return forms[2]
+	}
+}
+
+// triadWords spells a number below a thousand
+func triadWords(n int64, feminine int) []string {
+	var words []string
+	if n >= 100 {
+		words = append(words, wordsHundreds[n/100])
+		n %= 100
+	}
+	if n >= 20 {
+		words = append(words, wordsTens[n/10])
+		n %= 10
+	}
+	if n > 0 {
+		words = append(words, wordsUnits[feminine][n])
+	}
+	return words
+}
+
+// AmountInWords spells an amount in Russian the way invoices state it, e.g.
+// "Одна тысяча двести рублей 50 копеек"
+func AmountInWords(m models.Money) string {
+	kopecks := m.Kopecks()
+	sign := ""
+	if kopecks < 0 {
+		sign = "минус "
+		kopecks = -kopecks
+	}
+	rubles, kop := kopecks/100, kopecks%100
+
+	var words []string
+	if rubles == 0 {
+		words = []string{"ноль"}
+	} else {
+		words = triadWords(rubles%1000, 0)
+		rest := rubles / 1000
+		for i := 0; rest > 0 && i < len(wordsScales); i++ {
+			triad := rest % 1000
+			if triad > 0 {
+				scale := append(triadWords(triad, wordsScales[i].feminine), plural(triad, wordsScales[i].forms))
+				words = append(scale, words...)
+			}
+			rest /= 1000
+		}
+	}
+
+	text := sign + strings.Join(words, " ") + " " + plural(rubles, [3]string{"рубль", "рубля", "рублей"})
+	runes := []rune(text)
+	text = strings.ToUpper(string(runes[0])) + string(runes[1:])
+
+	return fmt.Sprintf("%s %02d %s", text, kop, plural(kop, [3]string{"копейка", "копейки", "копеек"}))
+}
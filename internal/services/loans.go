@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// DefaultExpectedEarningsMonths is used when the loan settings do not say how
+// many months expected earnings are averaged over
+const DefaultExpectedEarningsMonths = 3
+
+// ValidateLoanRepaymentPlan checks the amount or percentage of a repayment plan
+func ValidateLoanRepaymentPlan(plan *models.LoanRepaymentPlan) error {
+	switch plan.Type {
+	case models.LoanRepaymentFixed:
+		if plan.Amount <= 0 {
+			return fmt.Errorf("repayment amount must be positive")
+		}
+	case models.LoanRepaymentPercentage:
+		if plan.Percentage <= 0 || plan.Percentage > 100 {
+			return fmt.Errorf("repayment percentage must be between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("invalid repayment type: %s", plan.Type)
+	}
+	return nil
+}
+
+// EmployeeLoans returns the employee's loans with a repayment plan, oldest first
+func EmployeeLoans(transactions []models.EmployeeTransaction) []models.EmployeeLoan {
+	repayments := make(map[string][]models.EmployeeTransaction)
+	for _, trans := range transactions {
+		if trans.Type == models.EmpTransLoanRepayment && trans.LoanID != "" {
+			repayments[trans.LoanID] = append(repayments[trans.LoanID], trans)
+		}
+	}
+
+	loans := []models.EmployeeLoan{}
+	for _, trans := range transactions {
+		if trans.Type != models.EmpTransLoan || trans.Repayment == nil {
+			continue
+		}
+		loan := models.EmployeeLoan{
+			Loan:       trans,
+			Repayments: repayments[trans.ID],
+		}
+		if loan.Repayments == nil {
+			loan.Repayments = []models.EmployeeTransaction{}
+		}
+		sort.Slice(loan.Repayments, func(i, j int) bool {
+			return loan.Repayments[i].Date < loan.Repayments[j].Date
+		})
+		for _, r := range loan.Repayments {
+			loan.Repaid += r.Amount
+		}
+		loan.Outstanding = trans.Amount - loan.Repaid
+		if loan.Outstanding < 0 {
+			loan.Outstanding = 0
+		}
+		loans = append(loans, loan)
+	}
+
+	sort.SliceStable(loans, func(i, j int) bool {
+		return loans[i].Loan.Date < loans[j].Loan.Date
+	})
+	return loans
+}
+
+// OutstandingLoans returns the total not yet repaid on the employee's loans
+func OutstandingLoans(loans []models.EmployeeLoan) models.Money {
+	var total models.Money
+	for _, loan := range loans {
+		total += loan.Outstanding
+	}
+	return total
+}
+
+// ScheduleLoanRepayments produces the repayments withheld from an employee's
+// salary at the settlement of a payroll period. Loans are repaid oldest
+// first and the total withheld never exceeds the period earnings.
+func ScheduleLoanRepayments(
+	transactions []models.EmployeeTransaction,
+	earnings models.Money,
+	period *models.PayrollPeriod,
+	r Period,
+) []models.EmployeeTransaction {
+	var result []models.EmployeeTransaction
+	available := earnings
+	date := r.To.Add(-time.Second).UTC().Format(time.RFC3339Nano)
+
+	for _, loan := range EmployeeLoans(transactions) {
+		if available <= 0 {
+			break
+		}
+		// Loans issued after the period are repaid from later periods
+		issued, ok := ParseTimestamp(loan.Loan.Date)
+		if loan.Outstanding <= 0 || !ok || !issued.Before(r.To) {
+			continue
+		}
+
+		plan := loan.Loan.Repayment
+		amount := plan.Amount
+		if plan.Type == models.LoanRepaymentPercentage {
+			amount = earnings.MulPercent(plan.Percentage, models.RoundHalfEven)
+		}
+		if amount > loan.Outstanding {
+			amount = loan.Outstanding
+		}
+		if amount > available {
+			amount = available
+		}
+		if amount <= 0 {
+			continue
+		}
+		available -= amount
+
+		result = append(result, models.EmployeeTransaction{
+			EmployeeID:      loan.Loan.EmployeeID,
+			Date:            date,
+			Type:            models.EmpTransLoanRepayment,
+			Amount:          amount,
+			Description:     fmt.Sprintf("Погашение займа от %s", issued.In(time.Local).Format("02.01.2006")),
+			LoanID:          loan.Loan.ID,
+			PayrollPeriodID: period.ID,
+		})
+	}
+
+	return result
+}
+
+// ExpectedMonthlyEarnings averages the employee's earnings over the full
+// months before the given time
+func ExpectedMonthlyEarnings(
+	calculator *SalaryCalculator,
+	washEvents []models.WashEvent,
+	employees []models.Employee,
+	schemes []models.SalaryScheme,
+	employeeID string,
+	before time.Time,
+	months int,
+) models.Money {
+	if months <= 0 {
+		months = DefaultExpectedEarningsMonths
+	}
+
+	before = before.In(time.Local)
+	to := time.Date(before.Year(), before.Month(), 1, 0, 0, 0, 0, time.Local)
+	period := Period{From: to.AddDate(0, -months, 0), To: to}
+
+	report := calculator.GeneratePeriodReport(washEvents, employees, schemes, nil, period)
+	for _, data := range report {
+		if data.EmployeeID == employeeID {
+			return data.TotalEarnings.Allocate(months)[months-1]
+		}
+	}
+	return 0
+}
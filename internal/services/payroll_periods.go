@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// PayrollPeriodRange converts the inclusive dates of a payroll period into a
+// Period in server local time
+func PayrollPeriodRange(period *models.PayrollPeriod) (Period, error) {
+	from, err := time.ParseInLocation("2006-01-02", period.From, time.Local)
+	if err != nil {
+		return Period{}, fmt.Errorf("invalid payroll period start: %s", period.From)
+	}
+
+	to, err := time.ParseInLocation("2006-01-02", period.To, time.Local)
+	if err != nil {
+		return Period{}, fmt.Errorf("invalid payroll period end: %s", period.To)
+	}
+
+	if to.Before(from) {
+		return Period{}, fmt.Errorf("payroll period ends before it starts")
+	}
+
+	return Period{From: from, To: to.AddDate(0, 0, 1)}, nil
+}
+
+// PayrollPeriodsOverlap reports whether two payroll periods share any day
+func PayrollPeriodsOverlap(a, b *models.PayrollPeriod) bool {
+	return a.From <= b.To && b.From <= a.To
+}
+
+// FindClosedPayrollPeriod returns the closed payroll period covering the
+// given timestamp, or nil when the timestamp is not locked
+func FindClosedPayrollPeriod(periods []models.PayrollPeriod, timestamp string) *models.PayrollPeriod {
+	t, ok := ParseTimestamp(timestamp)
+	if !ok {
+		return nil
+	}
+
+	for i := range periods {
+		if periods[i].Status != models.PayrollPeriodClosed {
+			continue
+		}
+		r, err := PayrollPeriodRange(&periods[i])
+		if err != nil {
+			continue
+		}
+		if r.Contains(t) {
+			return &periods[i]
+		}
+	}
+	return nil
+}
+
+// FindClosedPayrollPeriodForScheme returns a closed payroll period whose
+// frozen report was calculated with the given scheme, which therefore has to
+// be kept for it
+func FindClosedPayrollPeriodForScheme(periods []models.PayrollPeriod, schemeID string) *models.PayrollPeriod {
+	for i := range periods {
+		if periods[i].Status != models.PayrollPeriodClosed {
+			continue
+		}
+		for _, id := range periods[i].SchemeIDs {
+			if id == schemeID {
+				return &periods[i]
+			}
+		}
+	}
+	return nil
+}
+
+// FindClosedPayrollPeriodForSchemeVersion returns a closed payroll period
+// that a new version of the scheme effective from the given date would
+// change, i.e. one calculated with the scheme and ending on or after that date
+func FindClosedPayrollPeriodForSchemeVersion(periods []models.PayrollPeriod, schemeID string, effectiveFrom string) *models.PayrollPeriod {
+	for i := range periods {
+		if periods[i].Status != models.PayrollPeriodClosed || periods[i].To < effectiveFrom {
+			continue
+		}
+		for _, id := range periods[i].SchemeIDs {
+			if id == schemeID {
+				return &periods[i]
+			}
+		}
+	}
+	return nil
+}
+
+// FindClosedPayrollPeriodForAssignmentChange returns a closed payroll period
+// in which the employee's scheme assignment differs between before and after
+func FindClosedPayrollPeriodForAssignmentChange(periods []models.PayrollPeriod, before, after *models.Employee) *models.PayrollPeriod {
+	for i := range periods {
+		if periods[i].Status != models.PayrollPeriodClosed {
+			continue
+		}
+		r, err := PayrollPeriodRange(&periods[i])
+		if err != nil {
+			continue
+		}
+		for day := r.From; day.Before(r.To); day = day.AddDate(0, 0, 1) {
+			date := LocalDate(day)
+			if SchemeIDAt(before, date) != SchemeIDAt(after, date) {
+				return &periods[i]
+			}
+		}
+	}
+	return nil
+}
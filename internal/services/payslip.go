@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"backend-go/internal/models"
+	"backend-go/internal/pdf"
+)
+
+// Payslip is an employee's salary report for one period prepared for printing
+type Payslip struct {
+	EmployeeName string
+	From         string // YYYY-MM-DD, inclusive; empty for all history
+	To           string // YYYY-MM-DD, inclusive; empty for all history
+	GeneratedAt  time.Time
+	Data         models.SalaryReportData
+}
+
+// payslipLine is a labelled amount of the payslip summary
+type payslipLine struct {
+	Label  string
+	Amount string
+	Total  bool
+}
+
+// payslipRow is a row of a payslip table
+type payslipRow struct {
+	Date        string
+	Title       string
+	Description string
+	Amount      string
+}
+
+// payslipView holds the formatted payslip for both renderers
+type payslipView struct {
+	EmployeeName string
+	PeriodLabel  string
+	GeneratedAt  string
+	Summary      []payslipLine
+	Washes       []payslipRow
+	Adjustments  []payslipRow
+	Transactions []payslipRow
+}
+
+var transactionTypeLabels = map[models.EmployeeTransactionType]string{
+	models.EmpTransBonus:    "Премия",
+	models.EmpTransFine:     "Штраф",
+	models.EmpTransPayment:  "Выплата (аванс)",
+	models.EmpTransLoan:     "Займ",
+	models.EmpTransPurchase: "Покупка",
+
+	models.EmpTransLoanRepayment: "Удержание в счёт займа",
+}
+
+func (p *Payslip) view() payslipView {
+	data := &p.Data
+
+	var adjustmentsTotal models.Money
+	for _, adj := range data.Adjustments {
+		adjustmentsTotal += adj.Amount
+	}
+
+	v := payslipView{
+		EmployeeName: p.EmployeeName,
+		PeriodLabel:  periodLabel(p.From, p.To),
+		GeneratedAt:  p.GeneratedAt.In(time.Local).Format("02.01.2006 15:04"),
+		Summary: []payslipLine{
+			{Label: "Входящий остаток", Amount: FormatRubles(data.OpeningBalance)},
+			{Label: "Начислено за мойки", Amount: FormatRubles(data.TotalEarnings - adjustmentsTotal)},
+			{Label: "Оклад, доплаты и ограничения", Amount: FormatRubles(adjustmentsTotal)},
+			{Label: "Премии", Amount: FormatRubles(data.Bonuses)},
+			{Label: "Штрафы", Amount: FormatRubles(-data.Fines)},
+			{Label: "Выплачено (авансы)", Amount: FormatRubles(-data.Payouts)},
+			{Label: "Займы", Amount: FormatRubles(-data.Loans)},
+			{Label: "Удержания в счёт займов", Amount: FormatRubles(-data.LoanRepayments)},
+			{Label: "Покупки", Amount: FormatRubles(-data.Purchases)},
+			{Label: "Итого к выплате", Amount: FormatRubles(data.ClosingBalance), Total: true},
+		},
+	}
+
+	// Washes are listed in the order they happened
+	for i := len(data.Breakdown) - 1; i >= 0; i-- {
+		item := data.Breakdown[i]
+		row := payslipRow{
+			Date:   formatTimestamp(item.Timestamp),
+			Title:  item.VehicleNumber,
+			Amount: FormatRubles(item.Earnings),
+		}
+		if len(item.UnpaidServices) > 0 {
+			row.Description = "Не оплачено: " + strings.Join(item.UnpaidServices, ", ")
+		}
+		if item.Tier != "" {
+			row.Description = strings.TrimSpace("Уровень: " + item.Tier + ". " + row.Description)
+		}
+		v.Washes = append(v.Washes, row)
+	}
+
+	for _, adj := range data.Adjustments {
+		v.Adjustments = append(v.Adjustments, payslipRow{
+			Date:   formatTimestamp(adj.Date),
+			Title:  adj.Description,
+			Amount: FormatRubles(adj.Amount),
+		})
+	}
+
+	for _, trans := range data.Transactions {
+		amount := trans.Amount
+		if trans.Type != models.EmpTransBonus {
+			amount = -amount
+		}
+		title := transactionTypeLabels[trans.Type]
+		if title == "" {
+			title = string(trans.Type)
+		}
+		row := payslipRow{
+			Date:        formatTimestamp(trans.Date),
+			Title:       title,
+			Description: trans.Description,
+			Amount:      FormatRubles(amount),
+		}
+		// Loans repaid in instalments do not reduce the balance when issued
+		if trans.Type == models.EmpTransLoan && trans.Repayment != nil {
+			row.Title = "Займ с удержанием из зарплаты"
+			row.Description = strings.TrimSpace(FormatRubles(trans.Amount) + " " + trans.Description)
+			row.Amount = "—"
+		}
+		v.Transactions = append(v.Transactions, row)
+	}
+
+	return v
+}
+
+var payslipTemplate = template.Must(template.New("payslip").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Расчётный листок — {{.EmployeeName}}</title>
+<style>
+body { font-family: Arial, Helvetica, sans-serif; font-size: 13px; margin: 24px; color: #222; }
+h1 { font-size: 20px; margin: 0 0 4px; }
+h2 { font-size: 15px; margin: 24px 0 8px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ddd; padding: 4px 6px; text-align: left; vertical-align: top; }
+td.amount, th.amount { text-align: right; white-space: nowrap; }
+table.summary { width: 420px; }
+tr.total td { font-weight: bold; border-top: 2px solid #222; }
+.muted { color: #777; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Расчётный листок</h1>
+<div>Сотрудник: <b>{{.EmployeeName}}</b></div>
+<div>Период: {{.PeriodLabel}}</div>
+<div class="muted">Сформирован {{.GeneratedAt}}</div>
+
+<h2>Итоги</h2>
+<table class="summary">
+{{range .Summary}}<tr{{if .Total}} class="total"{{end}}><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>
+
+<h2>Мойки</h2>
+{{if .Washes}}<table>
+<tr><th>Дата</th><th>Госномер</th><th>Примечание</th><th class="amount">Начислено</th></tr>
+{{range .Washes}}<tr><td>{{.Date}}</td><td>{{.Title}}</td><td>{{.Description}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>{{else}}<p class="muted">Моек за период нет</p>{{end}}
+
+{{if .Adjustments}}<h2>Оклад, доплаты и ограничения</h2>
+<table>
+<tr><th>Дата</th><th>Описание</th><th class="amount">Сумма</th></tr>
+{{range .Adjustments}}<tr><td>{{.Date}}</td><td>{{.Title}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>{{end}}
+
+{{if .Transactions}}<h2>Премии, штрафы и выплаты</h2>
+<table>
+<tr><th>Дата</th><th>Вид</th><th>Описание</th><th class="amount">Сумма</th></tr>
+{{range .Transactions}}<tr><td>{{.Date}}</td><td>{{.Title}}</td><td>{{.Description}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// RenderPayslipHTML renders the payslip as a printable HTML page
+func RenderPayslipHTML(p *Payslip) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := payslipTemplate.Execute(&buf, p.view()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Payslip PDF layout in points
+const (
+	payslipMargin     = 40.0
+	payslipLineHeight = 14.0
+	payslipFontSize   = 9.0
+)
+
+// payslipPDF lays out payslip text top to bottom, adding pages as needed
+type payslipPDF struct {
+	doc *pdf.Document
+	y   float64
+}
+
+func (w *payslipPDF) newPage() {
+	w.doc.AddPage()
+	w.y = payslipMargin
+}
+
+// ensure starts a new page unless height fits on the current one
+func (w *payslipPDF) ensure(height float64) {
+	if w.doc.PageCount() == 0 || w.y+height > pdf.PageHeight-payslipMargin {
+		w.newPage()
+	}
+}
+
+// row draws a table row; columns are x positions, the last one right aligned
+func (w *payslipPDF) row(columns []float64, values []string, bold bool) {
+	w.ensure(payslipLineHeight)
+	w.y += payslipLineHeight
+	last := len(values) - 1
+	for i, value := range values {
+		if i == last {
+			w.doc.Text(columns[i], w.y, payslipFontSize, bold, pdf.AlignRight, value)
+			continue
+		}
+		width := columns[i+1] - columns[i] - 6
+		if i+1 == last {
+			width -= 70 // keep clear of the right aligned amount
+		}
+		w.doc.Text(columns[i], w.y, payslipFontSize, bold, pdf.AlignLeft, pdf.Truncate(value, width, payslipFontSize, bold))
+	}
+}
+
+func (w *payslipPDF) heading(text string) {
+	w.ensure(payslipLineHeight * 3)
+	w.y += payslipLineHeight * 1.5
+	w.doc.Text(payslipMargin, w.y, 11, true, pdf.AlignLeft, text)
+	w.y += 4
+}
+
+func (w *payslipPDF) table(columns []float64, header []string, rows []payslipRow, fields func(payslipRow) []string) {
+	w.row(columns, header, true)
+	w.doc.Line(payslipMargin, w.y+4, pdf.PageWidth-payslipMargin, w.y+4)
+	w.y += 4
+	for _, r := range rows {
+		if w.y+payslipLineHeight > pdf.PageHeight-payslipMargin {
+			// Repeat the header on the next page
+			w.newPage()
+			w.row(columns, header, true)
+			w.doc.Line(payslipMargin, w.y+4, pdf.PageWidth-payslipMargin, w.y+4)
+			w.y += 4
+		}
+		w.row(columns, fields(r), false)
+	}
+}
+
+// RenderPayslipPDF renders the payslip as an A4 PDF document
+func RenderPayslipPDF(p *Payslip) []byte {
+	v := p.view()
+	w := &payslipPDF{doc: pdf.New("Расчётный листок — " + v.EmployeeName)}
+	w.newPage()
+
+	right := pdf.PageWidth - payslipMargin
+
+	w.y += 16
+	w.doc.Text(payslipMargin, w.y, 16, true, pdf.AlignLeft, "Расчётный листок")
+	w.y += 20
+	w.doc.Text(payslipMargin, w.y, 10, false, pdf.AlignLeft, "Сотрудник: "+v.EmployeeName)
+	w.y += payslipLineHeight
+	w.doc.Text(payslipMargin, w.y, 10, false, pdf.AlignLeft, "Период: "+v.PeriodLabel)
+	w.y += payslipLineHeight
+	w.doc.Text(payslipMargin, w.y, 8, false, pdf.AlignLeft, "Сформирован "+v.GeneratedAt)
+
+	w.heading("Итоги")
+	summaryColumns := []float64{payslipMargin, payslipMargin + 300}
+	for _, line := range v.Summary {
+		if line.Total {
+			w.doc.Line(payslipMargin, w.y+4, payslipMargin+300, w.y+4)
+			w.y += 4
+		}
+		w.row(summaryColumns, []string{line.Label, line.Amount}, line.Total)
+	}
+
+	w.heading("Мойки")
+	if len(v.Washes) == 0 {
+		w.ensure(payslipLineHeight)
+		w.y += payslipLineHeight
+		w.doc.Text(payslipMargin, w.y, payslipFontSize, false, pdf.AlignLeft, "Моек за период нет")
+	} else {
+		w.table([]float64{payslipMargin, payslipMargin + 80, payslipMargin + 170, right},
+			[]string{"Дата", "Госномер", "Примечание", "Начислено"}, v.Washes,
+			func(r payslipRow) []string { return []string{r.Date, r.Title, r.Description, r.Amount} })
+	}
+
+	if len(v.Adjustments) > 0 {
+		w.heading("Оклад, доплаты и ограничения")
+		w.table([]float64{payslipMargin, payslipMargin + 80, right},
+			[]string{"Дата", "Описание", "Сумма"}, v.Adjustments,
+			func(r payslipRow) []string { return []string{r.Date, r.Title, r.Amount} })
+	}
+
+	if len(v.Transactions) > 0 {
+		w.heading("Премии, штрафы и выплаты")
+		w.table([]float64{payslipMargin, payslipMargin + 80, payslipMargin + 180, right},
+			[]string{"Дата", "Вид", "Описание", "Сумма"}, v.Transactions,
+			func(r payslipRow) []string { return []string{r.Date, r.Title, r.Description, r.Amount} })
+	}
+
+	return w.doc.Bytes()
+}
+
+// FormatRubles formats an amount the Russian way, e.g. "1 234,50"
+func FormatRubles(m models.Money) string {
+	s := m.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign = "-"
+		s = s[1:]
+	}
+	intPart, frac := s[:len(s)-3], s[len(s)-2:]
+
+	var groups []string
+	for len(intPart) > 3 {
+		groups = append([]string{intPart[len(intPart)-3:]}, groups...)
+		intPart = intPart[:len(intPart)-3]
+	}
+	groups = append([]string{intPart}, groups...)
+
+	return sign + strings.Join(groups, " ") + "," + frac
+}
+
+// formatTimestamp formats an ISO timestamp or date as a local date and time
+func formatTimestamp(value string) string {
+	if len(value) == len("2006-01-02") {
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			return t.Format("02.01.2006")
+		}
+	}
+	t, ok := ParseTimestamp(value)
+	if !ok {
+		return value
+	}
+	return t.In(time.Local).Format("02.01.2006 15:04")
+}
+
+// periodLabel describes an inclusive date range
+func periodLabel(from, to string) string {
+	format := func(date string) string {
+		if t, err := time.Parse("2006-01-02", date); err == nil {
+			return t.Format("02.01.2006")
+		}
+		return date
+	}
+
+	switch {
+	case from == "" && to == "":
+		return "за всё время"
+	case from == "":
+		return "по " + format(to)
+	case to == "":
+		return "с " + format(from)
+	default:
+		return fmt.Sprintf("%s — %s", format(from), format(to))
+	}
+}
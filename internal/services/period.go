@@ -0,0 +1,61 @@
+package services
+
+import (
+	"time"
+)
+
+// Period represents a reporting period. Zero bounds are open-ended.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseTimestamp parses ISO timestamps stored in data files
+func ParseTimestamp(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IsBounded reports whether the period has at least one bound
+func (p Period) IsBounded() bool {
+	return !p.From.IsZero() || !p.To.IsZero()
+}
+
+// Contains reports whether t falls within the period (To is exclusive)
+func (p Period) Contains(t time.Time) bool {
+	if !p.From.IsZero() && t.Before(p.From) {
+		return false
+	}
+	if !p.To.IsZero() && !t.Before(p.To) {
+		return false
+	}
+	return true
+}
+
+// IsBefore reports whether t falls before the start of the period
+func (p Period) IsBefore(t time.Time) bool {
+	return !p.From.IsZero() && t.Before(p.From)
+}
+
+// ContainsTimestamp is Contains for an ISO timestamp string.
+// Unparseable timestamps only belong to unbounded periods.
+func (p Period) ContainsTimestamp(value string) bool {
+	t, ok := ParseTimestamp(value)
+	if !ok {
+		return !p.IsBounded()
+	}
+	return p.Contains(t)
+}
+
+// IsBeforeTimestamp is IsBefore for an ISO timestamp string
+func (p Period) IsBeforeTimestamp(value string) bool {
+	t, ok := ParseTimestamp(value)
+	if !ok {
+		return false
+	}
+	return p.IsBefore(t)
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"backend-go/internal/models"
+)
+
+// Sections of a price list row
+const (
+	PriceListSectionMain       = "main"
+	PriceListSectionAdditional = "additional"
+)
+
+// PriceListCSVRow is a service of an uploaded CSV price list
+type PriceListCSVRow struct {
+	Row         int
+	Section     string // PriceListSectionMain, PriceListSectionAdditional or empty
+	ServiceID   string
+	ServiceName string
+	Price       models.Money
+	// ChemicalConsumption is nil when the cell is blank
+	ChemicalConsumption *float64
+}
+
+// priceListColumns lists the header names of each price list column,
+// lowercased
+var priceListColumns = map[string][]string{
+	"section":  {"раздел", "section"},
+	"id":       {"id услуги", "id", "serviceid", "service id"},
+	"service":  {"услуга", "наименование услуги", "наименование", "service", "servicename", "service name"},
+	"price":    {"цена", "стоимость", "цена, руб.", "price"},
+	"chemical": {"расход химии, г", "расход химии", "chemicalconsumption", "chemical consumption"},
+}
+
+// priceListSections maps the section names a file may use to the sections
+var priceListSections = map[string]string{
+	"основной":       PriceListSectionMain,
+	"основные":       PriceListSectionMain,
+	"main":           PriceListSectionMain,
+	"дополнительный": PriceListSectionAdditional,
+	"дополнительные": PriceListSectionAdditional,
+	"additional":     PriceListSectionAdditional,
+}
+
+// csvDecimal formats a number for spreadsheets with a Russian locale
+func csvDecimal(f float64) string {
+	return strings.Replace(strconv.FormatFloat(f, 'f', -1, 64), ".", ",", 1)
+}
+
+// PriceListCSV renders a price list as semicolon separated CSV with a UTF-8
+// byte order mark, one service per row. The service ID column lets a file
+// edited in a spreadsheet be matched back to the services when renamed.
+func PriceListCSV(main, additional []models.PriceListItem) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF")
+
+	w := csv.NewWriter(&buf)
+	w.Comma = ';'
+
+	records := [][]string{
+		{"Раздел", "ID услуги", "Услуга", "Цена", "Расход химии, г"},
+	}
+	for _, section := range []struct {
+		name  string
+		items []models.PriceListItem
+	}{
+		{"Основной", main},
+		{"Дополнительный", additional},
+	} {
+		for _, item := range section.items {
+			records = append(records, []string{
+				section.name,
+				item.ServiceID,
+				item.ServiceName,
+				strings.Replace(item.Price.String(), ".", ",", 1),
+				csvDecimal(item.ChemicalConsumption),
+			})
+		}
+	}
+
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ParsePriceListCSV reads a CSV price list. Like aggregator registers, the
+// file may be UTF-8 or Windows-1251, separated by semicolons, commas or tabs;
+// columns are found by their header names and only the service and price
+// columns are required.
+func ParsePriceListCSV(data []byte) ([]PriceListCSVRow, error) {
+	records, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("price list is empty")
+	}
+
+	columns := csvColumns(records[0], priceListColumns)
+	for _, column := range []string{"service", "price"} {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("price list has no %s column", column)
+		}
+	}
+
+	field := func(record []string, column string) string {
+		return csvField(record, columns, column)
+	}
+
+	var rows []PriceListCSVRow
+	for n, record := range records[1:] {
+		line := n + 2
+		name := field(record, "service")
+		if name == "" {
+			if field(record, "price") != "" {
+				return nil, fmt.Errorf("line %d: service name is empty", line)
+			}
+			continue
+		}
+
+		price, err := registerAmount(field(record, "price"))
+		if err != nil || price < 0 {
+			return nil, fmt.Errorf("line %d: invalid price %q", line, field(record, "price"))
+		}
+
+		row := PriceListCSVRow{
+			Row:         line,
+			ServiceID:   field(record, "id"),
+			ServiceName: collapseSpaces(name),
+			Price:       price,
+		}
+
+		if value := field(record, "section"); value != "" {
+			section, ok := priceListSections[strings.ToLower(value)]
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown section %q", line, value)
+			}
+			row.Section = section
+		}
+
+		if value := strings.ReplaceAll(field(record, "chemical"), " ", ""); value != "" {
+			consumption, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+			if err != nil || consumption < 0 {
+				return nil, fmt.Errorf("line %d: invalid chemical consumption %q", line, field(record, "chemical"))
+			}
+			row.ChemicalConsumption = &consumption
+		}
+
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("price list has no services")
+	}
+	return rows, nil
+}
+
+// collapseSpaces trims a name and collapses runs of whitespace in it
+func collapseSpaces(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// MergePriceListCSV builds the price list described by the rows from the
+// current services. Rows are matched to services by their ID, then by name;
+// a matched service keeps its ID, its name when the row differs from it only
+// in whitespace, its per-employee consumption norms and, when the cell is
+// blank, its chemical consumption and section. Services
+// missing from the file are removed. withAdditional tells whether the list
+// has additional services at all.
+func MergePriceListCSV(rows []PriceListCSVRow, main, additional []models.PriceListItem, withAdditional bool) ([]models.PriceListItem, []models.PriceListItem, error) {
+	type current struct {
+		item       models.PriceListItem
+		additional bool
+		used       bool
+	}
+
+	byID := make(map[string]*current)
+	byName := make(map[string]*current)
+	for i, items := range [][]models.PriceListItem{main, additional} {
+		for _, item := range items {
+			cur := &current{item: item, additional: i == 1}
+			if item.ServiceID != "" {
+				byID[item.ServiceID] = cur
+			}
+			if _, ok := byName[normalizeServiceName(item.ServiceName)]; !ok {
+				byName[normalizeServiceName(item.ServiceName)] = cur
+			}
+		}
+	}
+
+	resultMain := []models.PriceListItem{}
+	resultAdditional := []models.PriceListItem{}
+	lines := make(map[string]int)
+	for _, row := range rows {
+		name := normalizeServiceName(row.ServiceName)
+		if line, ok := lines[name]; ok {
+			return nil, nil, fmt.Errorf("line %d: service %q is already on line %d", row.Row, row.ServiceName, line)
+		}
+		lines[name] = row.Row
+
+		match := byID[row.ServiceID]
+		if row.ServiceID == "" || match == nil || match.used {
+			match = byName[name]
+		}
+
+		var item models.PriceListItem
+		isAdditional := false
+		if match != nil && !match.used {
+			match.used = true
+			item, isAdditional = match.item, match.additional
+		}
+		// Spreadsheets trim and collapse spaces; that alone is not a rename
+		if collapseSpaces(item.ServiceName) != collapseSpaces(row.ServiceName) {
+			item.ServiceName = row.ServiceName
+		}
+		item.Price = row.Price
+		if row.ChemicalConsumption != nil {
+			item.ChemicalConsumption = *row.ChemicalConsumption
+		}
+
+		switch row.Section {
+		case PriceListSectionMain:
+			isAdditional = false
+		case PriceListSectionAdditional:
+			if !withAdditional {
+				return nil, nil, fmt.Errorf("line %d: the price list has no additional services", row.Row)
+			}
+			isAdditional = true
+		}
+
+		if isAdditional {
+			resultAdditional = append(resultAdditional, item)
+		} else {
+			resultMain = append(resultMain, item)
+		}
+	}
+	return resultMain, resultAdditional, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"backend-go/internal/models"
+)
+
+// priceListItems formats items as "id|name|price|chemical|norm" for comparison
+func priceListItems(items []models.PriceListItem) string {
+	var out []string
+	for _, item := range items {
+		out = append(out, fmt.Sprintf("%s|%s|%v|%g|%g", item.ServiceID, item.ServiceName, item.Price,
+			item.ChemicalConsumption, item.EmployeeChemicalNorm))
+	}
+	return strings.Join(out, "; ")
+}
+
+func TestMergePriceListCSV(t *testing.T) {
+	main := []models.PriceListItem{
+		{ServiceID: "s1", ServiceName: "Мойка кузова", Price: 50000, ChemicalConsumption: 30, EmployeeChemicalNorm: 10},
+		{ServiceID: "s2", ServiceName: "Чистка  ковриков", Price: 10000, ChemicalConsumption: 2},
+	}
+	additional := []models.PriceListItem{
+		{ServiceID: "s3", ServiceName: "Воск", Price: 30000, ChemicalConsumption: 5},
+	}
+	header := "Раздел;ID услуги;Услуга;Цена;Расход химии, г\n"
+
+	tests := []struct {
+		name           string
+		csv            string
+		withAdditional bool
+		wantMain       string
+		wantAdditional string
+		wantErr        bool
+	}{
+		{
+			name: "unchanged export",
+			csv: header +
+				"Основной;s1;Мойка кузова;500,00;30\n" +
+				"Основной;s2;Чистка  ковриков;100,00;2\n" +
+				"Дополнительный;s3;Воск;300,00;5\n",
+			withAdditional: true,
+			wantMain:       "s1|Мойка кузова|500.00|30|10; s2|Чистка  ковриков|100.00|2|0",
+			wantAdditional: "s3|Воск|300.00|5|0",
+		},
+		{
+			name: "renamed by ID, blank cells keep section and chemicals",
+			csv: header +
+				";s1;Мойка кузова с пеной;600;\n" +
+				";s3;Воск;350;\n",
+			withAdditional: true,
+			wantMain:       "s1|Мойка кузова с пеной|600.00|30|10",
+			wantAdditional: "s3|Воск|350.00|5|0",
+		},
+		{
+			name: "ID is matched before the name",
+			csv: header +
+				";s2;Мойка кузова;100;\n" +
+				";s1;Чистка ковриков;500;\n",
+			wantMain: "s2|Мойка кузова|100.00|2|0; s1|Чистка ковриков|500.00|30|10",
+		},
+		{
+			name: "matched by name without an ID, whitespace is not a rename",
+			csv: "Услуга;Цена\n" +
+				"  Чистка ковриков ;120\n" +
+				"Мойка   кузова;550\n" +
+				"Чернение шин;200\n",
+			wantMain: "s2|Чистка  ковриков|120.00|2|0; s1|Мойка кузова|550.00|30|10; |Чернение шин|200.00|0|0",
+		},
+		{
+			name: "unknown ID falls back to the name",
+			csv: header +
+				";s9;Воск;310;7\n",
+			withAdditional: true,
+			wantAdditional: "s3|Воск|310.00|7|0",
+		},
+		{
+			name: "moved to another section",
+			csv: header +
+				"Дополнительный;s1;Мойка кузова;500;\n" +
+				"Основной;s3;Воск;300;\n",
+			withAdditional: true,
+			wantMain:       "s3|Воск|300.00|5|0",
+			wantAdditional: "s1|Мойка кузова|500.00|30|10",
+		},
+		{
+			name:    "additional section in a list without one",
+			csv:     header + "Дополнительный;;Полировка;900;\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate service",
+			csv:     header + ";;Воск;300;\n;;ВОСК;300;\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		rows, err := ParsePriceListCSV([]byte(tt.csv))
+		if err != nil {
+			t.Fatalf("%s: ParsePriceListCSV: %v", tt.name, err)
+		}
+		gotMain, gotAdditional, err := MergePriceListCSV(rows, main, additional, tt.withAdditional)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %s, want error", tt.name, priceListItems(gotMain))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := priceListItems(gotMain); got != tt.wantMain {
+			t.Errorf("%s: main = %s, want %s", tt.name, got, tt.wantMain)
+		}
+		if got := priceListItems(gotAdditional); got != tt.wantAdditional {
+			t.Errorf("%s: additional = %s, want %s", tt.name, got, tt.wantAdditional)
+		}
+	}
+}
+
+func TestPriceListCSVRoundTrip(t *testing.T) {
+	main := []models.PriceListItem{
+		{ServiceID: "s1", ServiceName: "Мойка  кузова", Price: 50050, ChemicalConsumption: 12.5},
+	}
+	additional := []models.PriceListItem{
+		{ServiceID: "s2", ServiceName: "Воск; жидкий", Price: 30000},
+	}
+	data, err := PriceListCSV(main, additional)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ParsePriceListCSV(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotMain, gotAdditional, err := MergePriceListCSV(rows, main, additional, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := priceListItems(gotMain), priceListItems(main); got != want {
+		t.Errorf("main = %s, want %s", got, want)
+	}
+	if got, want := priceListItems(gotAdditional), priceListItems(additional); got != want {
+		t.Errorf("additional = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// validSeasonDay checks an MM-DD day of the year; 02-29 is allowed
+func validSeasonDay(value string) bool {
+	_, err := time.Parse("2006-01-02", "2000-"+value)
+	return err == nil && len(value) == 5
+}
+
+// ValidatePriceListSchedule checks the validity rules of the aggregator's
+// price lists
+func ValidatePriceListSchedule(agg *models.Aggregator) error {
+	for _, list := range agg.PriceLists {
+		for _, v := range list.Validity {
+			for _, date := range []string{v.ValidFrom, v.ValidTo} {
+				if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+					return fmt.Errorf("price list %q: invalid date %q, expected YYYY-MM-DD", list.Name, date)
+				}
+			}
+			if v.ValidFrom != "" && v.ValidTo != "" && v.ValidTo < v.ValidFrom {
+				return fmt.Errorf("price list %q: validTo %s is before validFrom %s", list.Name, v.ValidTo, v.ValidFrom)
+			}
+			if (v.SeasonFrom == "") != (v.SeasonTo == "") {
+				return fmt.Errorf("price list %q: a season needs both seasonFrom and seasonTo", list.Name)
+			}
+			for _, day := range []string{v.SeasonFrom, v.SeasonTo} {
+				if day != "" && !validSeasonDay(day) {
+					return fmt.Errorf("price list %q: invalid season day %q, expected MM-DD", list.Name, day)
+				}
+			}
+			if v.ValidFrom == "" && v.ValidTo == "" && v.SeasonFrom == "" {
+				return fmt.Errorf("price list %q: a validity rule needs dates or a season", list.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validityMatches reports whether the rule covers the YYYY-MM-DD date
+func validityMatches(v *models.PriceListValidity, date string) bool {
+	if v.ValidFrom != "" && date < v.ValidFrom {
+		return false
+	}
+	if v.ValidTo != "" && date > v.ValidTo {
+		return false
+	}
+	if v.SeasonFrom == "" {
+		return true
+	}
+	day := date[5:]
+	if v.SeasonFrom <= v.SeasonTo {
+		return day >= v.SeasonFrom && day <= v.SeasonTo
+	}
+	return day >= v.SeasonFrom || day <= v.SeasonTo
+}
+
+// scheduledPriceLists returns the names of the price lists scheduled on the
+// YYYY-MM-DD date
+func scheduledPriceLists(agg *models.Aggregator, date string) []string {
+	var names []string
+	for _, list := range agg.PriceLists {
+		for i := range list.Validity {
+			if validityMatches(&list.Validity[i], date) {
+				names = append(names, list.Name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// hasPriceListSchedule reports whether any of the aggregator's price lists
+// is scheduled
+func hasPriceListSchedule(agg *models.Aggregator) bool {
+	for _, list := range agg.PriceLists {
+		if len(list.Validity) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregatorPriceListName returns the price list of the aggregator in effect
+// at the given time: the first list scheduled on that day, or the list chosen
+// by hand when none is
+func AggregatorPriceListName(agg *models.Aggregator, at time.Time) string {
+	if names := scheduledPriceLists(agg, LocalDate(at)); len(names) > 0 {
+		return names[0]
+	}
+	return agg.ActivePriceListName
+}
+
+// PriceListScheduleIssues reports the gaps and overlaps in the schedule of
+// the aggregator's price lists from the start of the current year to the end
+// of the next. Aggregators without scheduled lists have none.
+func PriceListScheduleIssues(agg *models.Aggregator, now time.Time) []models.PriceListScheduleIssue {
+	issues := []models.PriceListScheduleIssue{}
+	if !hasPriceListSchedule(agg) {
+		return issues
+	}
+
+	now = now.In(time.Local)
+	day := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(now.Year()+1, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+	var current *models.PriceListScheduleIssue
+	for ; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		names := scheduledPriceLists(agg, date)
+
+		var issueType string
+		switch {
+		case len(names) == 0:
+			issueType = "gap"
+		case len(names) > 1:
+			issueType = "overlap"
+		}
+
+		if current != nil && (current.Type != issueType || strings.Join(current.PriceLists, "\n") != strings.Join(names, "\n")) {
+			issues = append(issues, *current)
+			current = nil
+		}
+		if issueType == "" {
+			continue
+		}
+		if current == nil {
+			current = &models.PriceListScheduleIssue{Type: issueType, From: date, PriceLists: names}
+		}
+		current.To = date
+	}
+	if current != nil {
+		issues = append(issues, *current)
+	}
+	return issues
+}
+
+// ResolveWashPriceLists fills in the price list of aggregator washes saved
+// without one from the schedule on the day of the wash, so rate schemes bound
+// to a price list match them. It returns the washes it filled in.
+func ResolveWashPriceLists(washEvents []models.WashEvent, aggregators []models.Aggregator) []*models.WashEvent {
+	byID := make(map[string]*models.Aggregator, len(aggregators))
+	for i := range aggregators {
+		byID[aggregators[i].ID] = &aggregators[i]
+	}
+
+	var resolved []*models.WashEvent
+	for i := range washEvents {
+		event := &washEvents[i]
+		if event.PaymentMethod != models.WashPaymentAggregator || event.PriceListName != "" {
+			continue
+		}
+		agg, ok := byID[event.SourceID]
+		if !ok || !hasPriceListSchedule(agg) {
+			continue
+		}
+		if t, ok := ParseTimestamp(event.Timestamp); ok {
+			event.PriceListName = AggregatorPriceListName(agg, t)
+			event.PriceListScheduled = true
+			resolved = append(resolved, event)
+		}
+	}
+	return resolved
+}
+
+// PriceWashFromList sets the services of a wash to their prices in the price
+// list, matching them by stable ID, then by name. The total and net amounts
+// move by the same difference, so discounts made by hand are kept; custom
+// services and services missing from the list keep their price.
+func PriceWashFromList(event *models.WashEvent, items []models.PriceListItem) {
+	byID := make(map[string]models.Money, len(items))
+	byName := make(map[string]models.Money, len(items))
+	for _, item := range items {
+		if item.ServiceID != "" {
+			byID[item.ServiceID] = item.Price
+		}
+		byName[item.ServiceName] = item.Price
+	}
+
+	reprice := func(service *models.PriceListItem) {
+		if service.IsCustom {
+			return
+		}
+		price, ok := byID[service.ServiceID]
+		if service.ServiceID == "" || !ok {
+			if price, ok = byName[service.ServiceName]; !ok {
+				return
+			}
+		}
+		diff := price - service.Price
+		service.Price = price
+		event.TotalAmount += diff
+		if event.NetAmount != 0 {
+			event.NetAmount += diff
+		}
+	}
+
+	reprice(&event.Services.Main)
+	for i := range event.Services.Additional {
+		reprice(&event.Services.Additional[i])
+	}
+}
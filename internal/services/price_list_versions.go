@@ -0,0 +1,118 @@
+package services
+
+import (
+	"sort"
+
+	"backend-go/internal/models"
+)
+
+// FindPriceListVersion returns the version with the given number
+func FindPriceListVersion(versions []models.PriceListVersion, number int) *models.PriceListVersion {
+	for i := range versions {
+		if versions[i].Version == number {
+			return &versions[i]
+		}
+	}
+	return nil
+}
+
+// VersionPriceList records a saved price list as a version effective from
+// the given date, replacing a version starting on the same day. A list saved
+// before versioning becomes version 1, in effect since the beginning; a save
+// that changes nothing adds no version. It returns the versions and the
+// version in effect today, which the price list shows.
+func VersionPriceList(versions []models.PriceListVersion, live, saved models.PriceListVersion, effectiveFrom, today string) ([]models.PriceListVersion, models.PriceListVersion) {
+	result := append([]models.PriceListVersion{}, versions...)
+	if len(result) == 0 && len(live.Main)+len(live.Additional) > 0 {
+		live.Version = 1
+		live.EffectiveFrom = ""
+		result = append(result, live)
+	}
+
+	if !models.SamePriceLists(models.PriceListVersionAt(result, effectiveFrom), saved.Main, saved.Additional) {
+		maxVersion := 0
+		kept := result[:0]
+		for _, v := range result {
+			if v.Version > maxVersion {
+				maxVersion = v.Version
+			}
+			if v.EffectiveFrom != effectiveFrom {
+				kept = append(kept, v)
+			}
+		}
+		saved.Version = maxVersion + 1
+		saved.EffectiveFrom = effectiveFrom
+		result = append(kept, saved)
+		sort.SliceStable(result, func(i, j int) bool {
+			return result[i].EffectiveFrom < result[j].EffectiveFrom
+		})
+	}
+
+	current := models.PriceListVersionAt(result, today)
+	if current == nil {
+		return result, live
+	}
+	return result, *current
+}
+
+// DiffPriceListVersions lists the services added, removed, renamed,
+// repriced or given another chemical consumption from one version to
+// another. Services are matched by their stable ID, then by name ignoring
+// case and spacing, as versions saved before services had IDs have none.
+func DiffPriceListVersions(from, to *models.PriceListVersion) models.PriceListDiff {
+	diff := models.PriceListDiff{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Added:       []models.PriceListItem{},
+		Removed:     []models.PriceListItem{},
+		Changed:     []models.PriceListChange{},
+	}
+
+	before := append(append([]models.PriceListItem{}, from.Main...), from.Additional...)
+	byID := make(map[string]int)
+	byName := make(map[string]int)
+	for i, item := range before {
+		if item.ServiceID != "" {
+			byID[item.ServiceID] = i
+		}
+		byName[normalizeServiceName(item.ServiceName)] = i
+	}
+
+	matched := make([]bool, len(before))
+	for _, item := range append(append([]models.PriceListItem{}, to.Main...), to.Additional...) {
+		i, ok := byID[item.ServiceID]
+		if !ok || item.ServiceID == "" {
+			i, ok = byName[normalizeServiceName(item.ServiceName)]
+			ok = ok && (before[i].ServiceID == "" || before[i].ServiceID == item.ServiceID)
+		}
+		if !ok || matched[i] {
+			diff.Added = append(diff.Added, item)
+			continue
+		}
+		matched[i] = true
+
+		old := before[i]
+		if old.ServiceName == item.ServiceName && old.Price == item.Price && old.ChemicalConsumption == item.ChemicalConsumption {
+			continue
+		}
+		change := models.PriceListChange{
+			ServiceID:                   item.ServiceID,
+			ServiceName:                 item.ServiceName,
+			PreviousPrice:               old.Price,
+			Price:                       item.Price,
+			PreviousChemicalConsumption: old.ChemicalConsumption,
+			ChemicalConsumption:         item.ChemicalConsumption,
+		}
+		if old.ServiceName != item.ServiceName {
+			change.PreviousName = old.ServiceName
+		}
+		diff.Changed = append(diff.Changed, change)
+	}
+
+	for i, item := range before {
+		if !matched[i] {
+			diff.Removed = append(diff.Removed, item)
+		}
+	}
+	return diff
+}
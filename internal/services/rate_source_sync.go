@@ -0,0 +1,196 @@
+package services
+
+import (
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// RateSourceItems returns the services of the price list a rate source points
+// at. An aggregator source without a price list name uses the list in effect
+// now.
+func RateSourceItems(source *models.RateSource, retail *models.RetailPriceConfig, aggregators []models.Aggregator, counterAgents []models.CounterAgent) []models.PriceListItem {
+	var items []models.PriceListItem
+
+	switch {
+	case source == nil:
+	case source.Type == models.RateSourceRetail:
+		if retail != nil {
+			items = append(append(items, retail.MainPriceList...), retail.AdditionalPriceList...)
+		}
+	case source.Type == models.RateSourceAggregator:
+		for i := range aggregators {
+			if aggregators[i].ID == source.ID {
+				items = AggregatorPriceList(&aggregators[i], source.PriceListName)
+			}
+		}
+	case source.Type == models.RateSourceCounterAgent:
+		for _, agent := range counterAgents {
+			if agent.ID == source.ID {
+				items = append(append(items, agent.PriceList...), agent.AdditionalPriceList...)
+			}
+		}
+	}
+
+	return items
+}
+
+// AggregatorPriceList returns the named price list of the aggregator, or the
+// one in effect now when name is empty
+func AggregatorPriceList(agg *models.Aggregator, name string) []models.PriceListItem {
+	if name == "" {
+		name = AggregatorPriceListName(agg, time.Now())
+	}
+	for _, list := range agg.PriceLists {
+		if list.Name == name {
+			return list.Services
+		}
+	}
+	return nil
+}
+
+// WashRateSource returns the price list a wash was priced from, in the form
+// rate schemes refer to it
+func WashRateSource(event *models.WashEvent) *models.RateSource {
+	sourceType := getWashSourceType(event)
+	switch sourceType {
+	case "retail":
+		return &models.RateSource{Type: models.RateSourceRetail, ID: "retail"}
+	case "aggregator", "counterAgent":
+		return &models.RateSource{
+			Type:          models.RateSourceType(sourceType),
+			ID:            event.SourceID,
+			PriceListName: event.PriceListName,
+		}
+	default:
+		return nil
+	}
+}
+
+// StampServiceIDs copies the stable IDs of the price list onto the services
+// of a wash, matching them by name
+func StampServiceIDs(event *models.WashEvent, items []models.PriceListItem) {
+	ids := serviceIDsByName(items)
+	if id, ok := ids[event.Services.Main.ServiceName]; ok && event.Services.Main.ServiceID == "" {
+		event.Services.Main.ServiceID = id
+	}
+	for i := range event.Services.Additional {
+		service := &event.Services.Additional[i]
+		if id, ok := ids[service.ServiceName]; ok && service.ServiceID == "" {
+			service.ServiceID = id
+		}
+	}
+}
+
+// LinkRateServiceIDs returns the rates with the stable IDs of the price list
+// services they are named after
+func LinkRateServiceIDs(rates []models.SalaryRate, items []models.PriceListItem) []models.SalaryRate {
+	ids := serviceIDsByName(items)
+	result := make([]models.SalaryRate, len(rates))
+	for i, rate := range rates {
+		if id, ok := ids[rate.ServiceName]; ok && rate.ServiceID == "" {
+			rate.ServiceID = id
+		}
+		result[i] = rate
+	}
+	return result
+}
+
+func serviceIDsByName(items []models.PriceListItem) map[string]string {
+	ids := make(map[string]string, len(items))
+	for _, item := range items {
+		if item.ServiceID != "" {
+			ids[item.ServiceName] = item.ServiceID
+		}
+	}
+	return ids
+}
+
+// DetectSchemeDrift compares the rates of a scheme with its rate source price
+// list before and after a save. Services that kept their stable ID under a new
+// name are renames; ok is false when the scheme is not affected.
+func DetectSchemeDrift(scheme *models.SalaryScheme, before, after []models.PriceListItem) (drift models.SalarySchemeDrift, ok bool) {
+	drift = models.SalarySchemeDrift{SchemeID: scheme.ID, SchemeName: scheme.Name}
+
+	beforeByID := make(map[string]string, len(before))
+	beforeNames := make(map[string]bool, len(before))
+	for _, item := range before {
+		beforeNames[item.ServiceName] = true
+		if item.ServiceID != "" {
+			beforeByID[item.ServiceID] = item.ServiceName
+		}
+	}
+
+	afterByID := make(map[string]string, len(after))
+	afterNames := make(map[string]bool, len(after))
+	for _, item := range after {
+		afterNames[item.ServiceName] = true
+		if item.ServiceID != "" {
+			afterByID[item.ServiceID] = item.ServiceName
+		}
+	}
+
+	rated := make(map[string]bool, len(scheme.Rates))
+	for _, rate := range scheme.Rates {
+		rated[rate.ServiceName] = true
+		if rate.ServiceID != "" {
+			rated[rate.ServiceID] = true
+		}
+	}
+
+	renamedFrom := make(map[string]bool)
+	for _, item := range after {
+		oldName, existed := beforeByID[item.ServiceID]
+		if !existed || item.ServiceID == "" || oldName == item.ServiceName {
+			continue
+		}
+		if rated[oldName] || rated[item.ServiceID] {
+			drift.Renamed = append(drift.Renamed, models.ServiceRename{
+				ServiceID: item.ServiceID,
+				From:      oldName,
+				To:        item.ServiceName,
+			})
+			renamedFrom[oldName] = true
+		}
+	}
+
+	for _, rate := range scheme.Rates {
+		if renamedFrom[rate.ServiceName] || afterNames[rate.ServiceName] {
+			continue
+		}
+		if _, ok := afterByID[rate.ServiceID]; ok && rate.ServiceID != "" {
+			continue
+		}
+		drift.Missing = append(drift.Missing, rate.ServiceName)
+	}
+
+	for _, item := range after {
+		if beforeNames[item.ServiceName] || rated[item.ServiceName] || (item.ServiceID != "" && rated[item.ServiceID]) {
+			continue
+		}
+		if _, renamed := beforeByID[item.ServiceID]; renamed && item.ServiceID != "" {
+			continue
+		}
+		drift.Added = append(drift.Added, item.ServiceName)
+	}
+
+	ok = len(drift.Renamed) > 0 || len(drift.Missing) > 0 || len(drift.Added) > 0
+	return drift, ok
+}
+
+// PropagateRenames returns the rates with renamed services following their
+// new names and linked to the stable service IDs
+func PropagateRenames(rates []models.SalaryRate, renames []models.ServiceRename) []models.SalaryRate {
+	result := make([]models.SalaryRate, len(rates))
+	for i, rate := range rates {
+		for _, r := range renames {
+			if rate.ServiceID == r.ServiceID || (rate.ServiceID == "" && rate.ServiceName == r.From) {
+				rate.ServiceName = r.To
+				rate.ServiceID = r.ServiceID
+				break
+			}
+		}
+		result[i] = rate
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// washCountForms are the plural forms of "wash" for document lines
+var washCountForms = [3]string{"мойка", "мойки", "моек"}
+
+// BuildReconciliation fills the balances and entries of a reconciliation
+// statement. Washes are debited to the client a line per day, payments are
+// credited a line each. Everything before the period goes into the opening
+// balance. Balances are the client's account, as in the client statement.
+func BuildReconciliation(rec *models.Reconciliation, washEvents []models.WashEvent, transactions []models.ClientTransaction, period Period) {
+	rec.OpeningBalance = 0
+	rec.Entries = []models.ReconciliationEntry{}
+
+	type day struct {
+		count  int64
+		amount models.Money
+	}
+	days := make(map[string]*day)
+	for _, event := range washEvents {
+		if !isClientWash(&event, rec.ClientType, rec.ClientID) {
+			continue
+		}
+		if period.IsBeforeTimestamp(event.Timestamp) {
+			rec.OpeningBalance -= event.TotalAmount
+			continue
+		}
+		if !period.ContainsTimestamp(event.Timestamp) {
+			continue
+		}
+		t, _ := ParseTimestamp(event.Timestamp)
+		date := LocalDate(t)
+		if days[date] == nil {
+			days[date] = &day{}
+		}
+		days[date].count++
+		days[date].amount += event.TotalAmount
+	}
+
+	for date, d := range days {
+		rec.Entries = append(rec.Entries, models.ReconciliationEntry{
+			Date:     date,
+			Document: fmt.Sprintf("Оказание услуг (%d %s)", d.count, plural(d.count, washCountForms)),
+			Debit:    d.amount,
+		})
+	}
+
+	for _, trans := range transactions {
+		if period.IsBeforeTimestamp(trans.Date) {
+			rec.OpeningBalance += trans.Amount
+			continue
+		}
+		if !period.ContainsTimestamp(trans.Date) {
+			continue
+		}
+		document := "Оплата"
+		if trans.Description != "" {
+			document += " (" + trans.Description + ")"
+		}
+		t, _ := ParseTimestamp(trans.Date)
+		rec.Entries = append(rec.Entries, models.ReconciliationEntry{
+			Date:     LocalDate(t),
+			Document: document,
+			Credit:   trans.Amount,
+		})
+	}
+
+	// Services of a day come before the payments made that day
+	sort.SliceStable(rec.Entries, func(i, j int) bool {
+		a, b := rec.Entries[i], rec.Entries[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.Debit > 0 && b.Debit == 0
+	})
+
+	rec.DebitTotal, rec.CreditTotal = 0, 0
+	for _, entry := range rec.Entries {
+		rec.DebitTotal += entry.Debit
+		rec.CreditTotal += entry.Credit
+	}
+	rec.ClosingBalance = rec.OpeningBalance - rec.DebitTotal + rec.CreditTotal
+}
+
+// QuarterDates returns the first and last day of a quarter given as
+// YYYY-Qn, e.g. 2025-Q4
+func QuarterDates(quarter string) (from, to string, err error) {
+	var year, q int
+	if _, err := fmt.Sscanf(quarter, "%d-Q%d", &year, &q); err != nil || q < 1 || q > 4 {
+		return "", "", fmt.Errorf("quarter must be YYYY-Qn")
+	}
+	start := time.Date(year, time.Month(3*(q-1)+1), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 3, -1)
+	return start.Format("2006-01-02"), end.Format("2006-01-02"), nil
+}
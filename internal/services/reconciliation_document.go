@@ -0,0 +1,232 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"time"
+
+	"backend-go/internal/models"
+	"backend-go/internal/pdf"
+)
+
+// reconciliationView is the formatted reconciliation statement
+type reconciliationView struct {
+	Period       string
+	Seller       partyView
+	Customer     partyView
+	Rows         []reconciliationRowView
+	Opening      [2]string // debit, credit
+	Turnover     [2]string
+	Closing      [2]string
+	Conclusion   string
+	BalanceWords string
+}
+
+type reconciliationRowView struct {
+	No       int
+	Date     string
+	Document string
+	Debit    string
+	Credit   string
+}
+
+// partyView is a party of a document with its requisites line by line
+type partyView struct {
+	Name       string
+	Requisites []string
+}
+
+func party(c *models.CounterAgentCompany) partyView {
+	p := partyView{Name: companyName(c)}
+	for _, line := range []string{
+		withLabel("ИНН", c.INN),
+		withLabel("КПП", c.KPP),
+		withLabel("ОГРН", c.OGRNNumber),
+		withLabel("Адрес:", c.LegalAddress),
+		withLabel("Банк:", c.BankName),
+		withLabel("Р/с", c.SettlementAccount),
+		withLabel("К/с", c.CorrespondentAccount),
+		withLabel("БИК", c.BIK),
+	} {
+		if line != "" {
+			p.Requisites = append(p.Requisites, line)
+		}
+	}
+	return p
+}
+
+// balanceColumns puts a balance in the debit column when the client owes,
+// the balance being negative, and in the credit column when they paid in
+// advance
+func balanceColumns(balance models.Money) [2]string {
+	switch {
+	case balance < 0:
+		return [2]string{FormatRubles(-balance), ""}
+	case balance > 0:
+		return [2]string{"", FormatRubles(balance)}
+	default:
+		return [2]string{FormatRubles(0), ""}
+	}
+}
+
+func documentDate(date string) string {
+	if t, err := time.Parse("2006-01-02", date); err == nil {
+		return t.Format("02.01.2006")
+	}
+	return date
+}
+
+func reconciliationDocument(rec *models.Reconciliation) reconciliationView {
+	v := reconciliationView{
+		Period:   fmt.Sprintf("с %s по %s", documentDate(rec.From), documentDate(rec.To)),
+		Seller:   party(&rec.Seller),
+		Customer: party(&rec.Customer),
+		Opening:  balanceColumns(rec.OpeningBalance),
+		Turnover: [2]string{FormatRubles(rec.DebitTotal), FormatRubles(rec.CreditTotal)},
+		Closing:  balanceColumns(rec.ClosingBalance),
+	}
+
+	for i, entry := range rec.Entries {
+		row := reconciliationRowView{No: i + 1, Date: documentDate(entry.Date), Document: entry.Document}
+		if entry.Debit != 0 {
+			row.Debit = FormatRubles(entry.Debit)
+		}
+		if entry.Credit != 0 {
+			row.Credit = FormatRubles(entry.Credit)
+		}
+		v.Rows = append(v.Rows, row)
+	}
+
+	on := documentDate(rec.To)
+	switch {
+	case rec.ClosingBalance < 0:
+		v.Conclusion = fmt.Sprintf("На %s задолженность в пользу %s составляет %s руб.", on, v.Seller.Name, FormatRubles(-rec.ClosingBalance))
+		v.BalanceWords = AmountInWords(-rec.ClosingBalance)
+	case rec.ClosingBalance > 0:
+		v.Conclusion = fmt.Sprintf("На %s задолженность в пользу %s составляет %s руб.", on, v.Customer.Name, FormatRubles(rec.ClosingBalance))
+		v.BalanceWords = AmountInWords(rec.ClosingBalance)
+	default:
+		v.Conclusion = fmt.Sprintf("На %s задолженность отсутствует.", on)
+	}
+
+	return v
+}
+
+var reconciliationTemplate = template.Must(template.Must(invoiceTemplates.Clone()).New("reconciliation").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Акт сверки {{.Period}}</title>
+{{template "style"}}
+</head>
+<body>
+<h2>Акт сверки взаимных расчётов<br>за период {{.Period}}<br>между {{.Seller.Name}} и {{.Customer.Name}}</h2>
+<table class="lines">
+<tr><th>№</th><th>Дата</th><th>Документ</th><th>Дебет</th><th>Кредит</th></tr>
+<tr><td colspan="3"><b>Сальдо начальное</b></td><td class="num"><b>{{index .Opening 0}}</b></td><td class="num"><b>{{index .Opening 1}}</b></td></tr>
+{{range .Rows}}<tr><td class="center">{{.No}}</td><td class="center">{{.Date}}</td><td>{{.Document}}</td><td class="num">{{.Debit}}</td><td class="num">{{.Credit}}</td></tr>
+{{end}}<tr><td colspan="3"><b>Обороты за период</b></td><td class="num"><b>{{index .Turnover 0}}</b></td><td class="num"><b>{{index .Turnover 1}}</b></td></tr>
+<tr><td colspan="3"><b>Сальдо конечное</b></td><td class="num"><b>{{index .Closing 0}}</b></td><td class="num"><b>{{index .Closing 1}}</b></td></tr>
+</table>
+<p><b>{{.Conclusion}}</b>{{if .BalanceWords}}<br>{{.BalanceWords}}{{end}}</p>
+<div class="signatures">
+<div><b>Исполнитель</b>
+<p><b>{{.Seller.Name}}</b>{{range .Seller.Requisites}}<br>{{.}}{{end}}</p>
+<div class="sign-line"></div><p class="hint">(подпись)</p><p class="hint">М.П.</p></div>
+<div><b>Заказчик</b>
+<p><b>{{.Customer.Name}}</b>{{range .Customer.Requisites}}<br>{{.}}{{end}}</p>
+<div class="sign-line"></div><p class="hint">(подпись)</p><p class="hint">М.П.</p></div>
+</div>
+</body>
+</html>
+`))
+
+// RenderReconciliationHTML renders a reconciliation statement as a printable
+// HTML page with both parties' requisites
+func RenderReconciliationHTML(rec *models.Reconciliation) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := reconciliationTemplate.ExecuteTemplate(&buf, "reconciliation", reconciliationDocument(rec)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// reconciliationColumns are the x positions of the statement table columns:
+// number, date, document, debit, credit and the right edge
+var reconciliationColumns = []float64{invoiceMargin, invoiceMargin + 22, invoiceMargin + 90, invoiceMargin + 333, invoiceMargin + 414, pdf.PageWidth - invoiceMargin}
+
+var reconciliationAligns = []pdf.Align{pdf.AlignCenter, pdf.AlignCenter, pdf.AlignLeft, pdf.AlignRight, pdf.AlignRight}
+
+// parties draws the requisites of both parties side by side with signature
+// lines below
+func (w *invoicePDF) parties(left, right partyView) {
+	half := (pdf.PageWidth - 2*invoiceMargin) / 2
+	width := half - 20
+	wrap := func(p partyView) []string {
+		lines := pdf.Wrap(p.Name, width, invoiceFontSize, true)
+		for _, r := range p.Requisites {
+			lines = append(lines, pdf.Wrap(r, width, invoiceFontSize, false)...)
+		}
+		return lines
+	}
+	leftLines, rightLines := wrap(left), wrap(right)
+	rows := len(leftLines)
+	if len(rightLines) > rows {
+		rows = len(rightLines)
+	}
+
+	w.ensure(invoiceLineHeight * float64(rows+6))
+	w.y += invoiceLineHeight
+	w.doc.Text(invoiceMargin, w.y, invoiceFontSize, true, pdf.AlignLeft, "Исполнитель")
+	w.doc.Text(invoiceMargin+half+20, w.y, invoiceFontSize, true, pdf.AlignLeft, "Заказчик")
+	top := w.y
+	for i, line := range leftLines {
+		w.doc.Text(invoiceMargin, top+invoiceLineHeight*float64(i+1), invoiceFontSize, i == 0, pdf.AlignLeft, line)
+	}
+	for i, line := range rightLines {
+		w.doc.Text(invoiceMargin+half+20, top+invoiceLineHeight*float64(i+1), invoiceFontSize, i == 0, pdf.AlignLeft, line)
+	}
+	w.y = top + invoiceLineHeight*float64(rows)
+	w.signatures("", "")
+}
+
+// RenderReconciliationPDF renders a reconciliation statement as an A4 PDF
+// document
+func RenderReconciliationPDF(rec *models.Reconciliation) []byte {
+	v := reconciliationDocument(rec)
+	w := &invoicePDF{doc: pdf.New("Акт сверки " + v.Period)}
+	x := invoiceMargin
+
+	w.newPage()
+	w.y += 10
+	w.doc.Text(pdf.PageWidth/2, w.y, 13, true, pdf.AlignCenter, "Акт сверки взаимных расчётов")
+	w.y += 16
+	w.doc.Text(pdf.PageWidth/2, w.y, invoiceFontSize+1, false, pdf.AlignCenter, "за период "+v.Period)
+	w.y += 4
+	for _, line := range pdf.Wrap(fmt.Sprintf("между %s и %s", v.Seller.Name, v.Customer.Name), pdf.PageWidth-2*invoiceMargin, invoiceFontSize+1, false) {
+		w.y += invoiceLineHeight
+		w.doc.Text(pdf.PageWidth/2, w.y, invoiceFontSize+1, false, pdf.AlignCenter, line)
+	}
+	w.y += 6
+
+	rows := [][]string{{"", "", "Сальдо начальное", v.Opening[0], v.Opening[1]}}
+	for _, row := range v.Rows {
+		rows = append(rows, []string{fmt.Sprint(row.No), row.Date, row.Document, row.Debit, row.Credit})
+	}
+	rows = append(rows,
+		[]string{"", "", "Обороты за период", v.Turnover[0], v.Turnover[1]},
+		[]string{"", "", "Сальдо конечное", v.Closing[0], v.Closing[1]},
+	)
+	w.table(reconciliationColumns, reconciliationAligns, []string{"№", "Дата", "Документ", "Дебет", "Кредит"}, rows)
+
+	w.y += 6
+	w.paragraph(x, "", v.Conclusion, invoiceFontSize, true)
+	if v.BalanceWords != "" {
+		w.paragraph(x, "", v.BalanceWords, invoiceFontSize, false)
+	}
+	w.y += 6
+	w.parties(v.Seller, v.Customer)
+
+	return w.doc.Bytes()
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+
+	"backend-go/internal/models"
+)
+
+var kppPattern = regexp.MustCompile(`^\d{4}[\dA-Z]{2}\d{3}$`)
+
+func isDigits(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// weightedSum multiplies the leading digits of value by the weights
+func weightedSum(value string, weights []int) int {
+	sum := 0
+	for i, w := range weights {
+		sum += int(value[i]-'0') * w
+	}
+	return sum
+}
+
+// innCheckDigit computes an INN control digit over the digits the weights
+// cover
+func innCheckDigit(value string, weights []int) byte {
+	return byte(weightedSum(value, weights)%11%10) + '0'
+}
+
+// ValidINN checks a 10-digit INN of a company or a 12-digit INN of an
+// individual against its control digits
+func ValidINN(inn string) bool {
+	switch {
+	case isDigits(inn, 10):
+		return inn[9] == innCheckDigit(inn, []int{2, 4, 10, 3, 5, 9, 4, 6, 8})
+	case isDigits(inn, 12):
+		return inn[10] == innCheckDigit(inn, []int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) &&
+			inn[11] == innCheckDigit(inn, []int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8})
+	}
+	return false
+}
+
+// ValidKPP checks the KPP format: a 4-digit tax office code, a 2-character
+// reason code and a 3-digit number
+func ValidKPP(kpp string) bool {
+	return kppPattern.MatchString(kpp)
+}
+
+// ValidOGRN checks a 13-digit OGRN of a company or a 15-digit OGRNIP of an
+// individual entrepreneur against its control digit
+func ValidOGRN(ogrn string) bool {
+	var divisor uint64
+	switch {
+	case isDigits(ogrn, 13):
+		divisor = 11
+	case isDigits(ogrn, 15):
+		divisor = 13
+	default:
+		return false
+	}
+	last := len(ogrn) - 1
+	n, err := strconv.ParseUint(ogrn[:last], 10, 64)
+	if err != nil {
+		return false
+	}
+	return byte(n%divisor%10)+'0' == ogrn[last]
+}
+
+// ValidBIK checks the BIK format
+func ValidBIK(bik string) bool {
+	return isDigits(bik, 9)
+}
+
+// accountKeyWeights repeat over the 3 BIK digits and 20 account digits
+var accountKeyWeights = []int{7, 1, 3, 7, 1, 3, 7, 1, 3, 7, 1, 3, 7, 1, 3, 7, 1, 3, 7, 1, 3, 7, 1}
+
+// ValidSettlementAccount checks the key of a settlement account opened in the
+// bank with the given BIK
+func ValidSettlementAccount(account, bik string) bool {
+	if !isDigits(account, 20) || !ValidBIK(bik) {
+		return false
+	}
+	return weightedSum(bik[6:]+account, accountKeyWeights)%10 == 0
+}
+
+// ValidCorrespondentAccount checks the key of the correspondent account of
+// the bank with the given BIK
+func ValidCorrespondentAccount(account, bik string) bool {
+	if !isDigits(account, 20) || !ValidBIK(bik) {
+		return false
+	}
+	return weightedSum("0"+bik[4:6]+account, accountKeyWeights)%10 == 0
+}
+
+// CompanyRequisiteErrors checks the requisites filled in on a company and
+// returns an error message per invalid field, keyed by its JSON name. Empty
+// fields are not checked.
+func CompanyRequisiteErrors(company *models.CounterAgentCompany) map[string]string {
+	errs := make(map[string]string)
+
+	if company.INN != "" && !ValidINN(company.INN) {
+		errs["inn"] = "INN must be 10 digits for a company or 12 for an individual, with valid control digits"
+	}
+	if company.KPP != "" {
+		switch {
+		case !ValidKPP(company.KPP):
+			errs["kpp"] = "KPP must be 9 characters: 4 digits, 2 digits or capital letters, 3 digits"
+		case len(company.INN) == 12:
+			errs["kpp"] = "KPP is only assigned to companies, not to individual entrepreneurs"
+		}
+	}
+	if company.OGRNNumber != "" {
+		switch {
+		case !ValidOGRN(company.OGRNNumber):
+			errs["ogrnNumber"] = "OGRN must be 13 digits (OGRNIP 15) with a valid control digit"
+		case len(company.INN) == 10 && len(company.OGRNNumber) != 13:
+			errs["ogrnNumber"] = "a company with a 10-digit INN has a 13-digit OGRN"
+		case len(company.INN) == 12 && len(company.OGRNNumber) != 15:
+			errs["ogrnNumber"] = "an individual entrepreneur with a 12-digit INN has a 15-digit OGRNIP"
+		}
+	}
+
+	bikValid := company.BIK != "" && ValidBIK(company.BIK)
+	if company.BIK != "" && !bikValid {
+		errs["bik"] = "BIK must be 9 digits"
+	}
+	if company.BIK == "" && (company.SettlementAccount != "" || company.CorrespondentAccount != "") {
+		errs["bik"] = "BIK is required to check the accounts"
+	}
+	if company.SettlementAccount != "" {
+		switch {
+		case !isDigits(company.SettlementAccount, 20):
+			errs["settlementAccount"] = "settlement account must be 20 digits"
+		case bikValid && !ValidSettlementAccount(company.SettlementAccount, company.BIK):
+			errs["settlementAccount"] = "settlement account key does not match the BIK"
+		}
+	}
+	if company.CorrespondentAccount != "" {
+		switch {
+		case !isDigits(company.CorrespondentAccount, 20):
+			errs["correspondentAccount"] = "correspondent account must be 20 digits"
+		case bikValid && !ValidCorrespondentAccount(company.CorrespondentAccount, company.BIK):
+			errs["correspondentAccount"] = "correspondent account key does not match the BIK"
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
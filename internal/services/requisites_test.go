@@ -0,0 +1,79 @@
+package services
+
+import "testing"
+
+func TestValidINN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "7707083893", want: true},
+		{in: "7736207543", want: true},
+		{in: "7707083894", want: false},
+		{in: "7707083983", want: false},
+		{in: "500100732259", want: true},
+		{in: "500100732258", want: false},
+		{in: "500100732269", want: false},
+		{in: "600100732259", want: false},
+		{in: "770708389", want: false},
+		{in: "77070838930", want: false},
+		{in: "77070838a3", want: false},
+		{in: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := ValidINN(tt.in); got != tt.want {
+			t.Errorf("ValidINN(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidOGRN(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{in: "1027700132195", want: true},
+		{in: "1027700132196", want: false},
+		{in: "1027700132295", want: false},
+		{in: "304500116000157", want: true},
+		{in: "304500116000158", want: false},
+		{in: "304500116001157", want: false},
+		{in: "10277001321950", want: false},
+		{in: "102770013219", want: false},
+		{in: "", want: false},
+	}
+	for _, tt := range tests {
+		if got := ValidOGRN(tt.in); got != tt.want {
+			t.Errorf("ValidOGRN(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidAccounts(t *testing.T) {
+	tests := []struct {
+		account       string
+		bik           string
+		settlement    bool
+		correspondent bool
+	}{
+		{account: "40702810938000000001", bik: "044525225", settlement: true},
+		{account: "40702810138000000001", bik: "044525225"},
+		{account: "40702810938000000002", bik: "044525225"},
+		{account: "40702810938000000001", bik: "044525226"},
+		{account: "40817810000000000001", bik: "044525974", settlement: true},
+		{account: "30101810400000000225", bik: "044525225", correspondent: true},
+		{account: "30101810400000000226", bik: "044525225"},
+		{account: "30101810400000000225", bik: "044535225"},
+		{account: "30101810145250000974", bik: "044525974", correspondent: true},
+		{account: "3010181040000000022", bik: "044525225"},
+		{account: "30101810400000000225", bik: "04452522"},
+	}
+	for _, tt := range tests {
+		if got := ValidSettlementAccount(tt.account, tt.bik); got != tt.settlement {
+			t.Errorf("ValidSettlementAccount(%q, %q) = %v, want %v", tt.account, tt.bik, got, tt.settlement)
+		}
+		if got := ValidCorrespondentAccount(tt.account, tt.bik); got != tt.correspondent {
+			t.Errorf("ValidCorrespondentAccount(%q, %q) = %v, want %v", tt.account, tt.bik, got, tt.correspondent)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// LocalDate returns the YYYY-MM-DD date of t in server local time
+func LocalDate(t time.Time) string {
+	return t.In(time.Local).Format("2006-01-02")
+}
+
+// SchemeIDAt returns the scheme assigned to the employee on the given date.
+// Employees without an assignment history are paid by their current scheme.
+func SchemeIDAt(emp *models.Employee, date string) string {
+	if len(emp.SalarySchemeAssignments) == 0 {
+		return emp.SalarySchemeID
+	}
+
+	for _, a := range emp.SalarySchemeAssignments {
+		if a.ValidFrom != "" && date < a.ValidFrom {
+			continue
+		}
+		if a.ValidTo != "" && date > a.ValidTo {
+			continue
+		}
+		return a.SchemeID
+	}
+	return ""
+}
+
+// SchemeVersionAt returns the version of the scheme in effect on the given date.
+// Dates before the first version fall back to the oldest version.
+func SchemeVersionAt(scheme *models.SalaryScheme, date string) *models.SalaryScheme {
+	if len(scheme.PreviousVersions) == 0 || scheme.EffectiveFrom == "" || date >= scheme.EffectiveFrom {
+		return scheme
+	}
+
+	var best *models.SalaryScheme
+	for i := range scheme.PreviousVersions {
+		v := &scheme.PreviousVersions[i]
+		if v.EffectiveFrom != "" && date < v.EffectiveFrom {
+			continue
+		}
+		if best == nil || v.EffectiveFrom > best.EffectiveFrom {
+			best = v
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	oldest := &scheme.PreviousVersions[0]
+	for i := range scheme.PreviousVersions {
+		if scheme.PreviousVersions[i].EffectiveFrom < oldest.EffectiveFrom {
+			oldest = &scheme.PreviousVersions[i]
+		}
+	}
+	return oldest
+}
+
+// NewSchemeVersion makes updates the current version of the scheme effective
+// from the given date. Versions starting on or after that date are replaced;
+// the rest are kept as previous versions.
+func NewSchemeVersion(existing *models.SalaryScheme, updates models.SalaryScheme, effectiveFrom string) models.SalaryScheme {
+	all := append([]models.SalaryScheme{}, existing.PreviousVersions...)
+	current := *existing
+	current.PreviousVersions = nil
+	all = append(all, current)
+
+	maxVersion := 0
+	var kept []models.SalaryScheme
+	for _, v := range all {
+		// Schemes saved before versioning count as version 1
+		if v.Version == 0 {
+			v.Version = 1
+		}
+		if v.Version > maxVersion {
+			maxVersion = v.Version
+		}
+		if v.EffectiveFrom != "" && v.EffectiveFrom >= effectiveFrom {
+			continue
+		}
+		kept = append(kept, v)
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].EffectiveFrom < kept[j].EffectiveFrom
+	})
+
+	updates.ID = existing.ID
+	updates.Version = maxVersion + 1
+	updates.EffectiveFrom = effectiveFrom
+	updates.PreviousVersions = kept
+	if len(kept) == 0 {
+		// Nothing older survives, so the new version applies to all history
+		updates.EffectiveFrom = ""
+	}
+	return updates
+}
+
+// ReassignScheme closes the employee's open assignment the day before
+// effectiveFrom and assigns the new scheme from that date
+func ReassignScheme(emp *models.Employee, schemeID string, effectiveFrom string) error {
+	from, err := time.ParseInLocation("2006-01-02", effectiveFrom, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid effective date: %s", effectiveFrom)
+	}
+	dayBefore := from.AddDate(0, 0, -1).Format("2006-01-02")
+
+	var assignments []models.SalarySchemeAssignment
+	if len(emp.SalarySchemeAssignments) == 0 && emp.SalarySchemeID != "" {
+		// Record the scheme the employee has been on so far
+		assignments = append(assignments, models.SalarySchemeAssignment{
+			SchemeID: emp.SalarySchemeID,
+			ValidTo:  dayBefore,
+		})
+	}
+
+	for _, a := range emp.SalarySchemeAssignments {
+		if a.ValidFrom != "" && a.ValidFrom >= effectiveFrom {
+			continue
+		}
+		if a.ValidTo == "" || a.ValidTo >= effectiveFrom {
+			a.ValidTo = dayBefore
+		}
+		assignments = append(assignments, a)
+	}
+
+	if schemeID != "" {
+		assignments = append(assignments, models.SalarySchemeAssignment{
+			SchemeID:  schemeID,
+			ValidFrom: effectiveFrom,
+		})
+	}
+
+	emp.SalarySchemeAssignments = assignments
+	emp.SalarySchemeID = schemeID
+	return nil
+}
+
+// ValidateAssignments checks dates and that assignments do not overlap
+func ValidateAssignments(assignments []models.SalarySchemeAssignment) error {
+	sorted := append([]models.SalarySchemeAssignment{}, assignments...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ValidFrom < sorted[j].ValidFrom
+	})
+
+	for i, a := range sorted {
+		if a.SchemeID == "" {
+			return fmt.Errorf("assignment without schemeId")
+		}
+		for _, d := range []string{a.ValidFrom, a.ValidTo} {
+			if d == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				return fmt.Errorf("invalid assignment date: %s", d)
+			}
+		}
+		if a.ValidFrom != "" && a.ValidTo != "" && a.ValidTo < a.ValidFrom {
+			return fmt.Errorf("assignment to %s ends before it starts", a.SchemeID)
+		}
+		if i > 0 {
+			prev := sorted[i-1]
+			if prev.ValidTo == "" || (a.ValidFrom == "" || a.ValidFrom <= prev.ValidTo) {
+				return fmt.Errorf("assignments to %s and %s overlap", prev.SchemeID, a.SchemeID)
+			}
+		}
+	}
+	return nil
+}
+
+// SchemeIDsInPeriod returns the schemes assigned to the employee at any time
+// within the inclusive date range
+func SchemeIDsInPeriod(emp *models.Employee, from, to string) []string {
+	if len(emp.SalarySchemeAssignments) == 0 {
+		if emp.SalarySchemeID == "" {
+			return nil
+		}
+		return []string{emp.SalarySchemeID}
+	}
+
+	var ids []string
+	for _, a := range emp.SalarySchemeAssignments {
+		if a.ValidFrom != "" && a.ValidFrom > to {
+			continue
+		}
+		if a.ValidTo != "" && a.ValidTo < from {
+			continue
+		}
+		ids = append(ids, a.SchemeID)
+	}
+	return ids
+}
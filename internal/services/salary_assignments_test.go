@@ -0,0 +1,175 @@
+package services
+
+import (
+	"testing"
+
+	"backend-go/internal/models"
+)
+
+func TestSchemeIDAt(t *testing.T) {
+	history := &models.Employee{
+		SalarySchemeID: "scheme_c",
+		SalarySchemeAssignments: []models.SalarySchemeAssignment{
+			{SchemeID: "scheme_a", ValidTo: "2024-02-29"},
+			{SchemeID: "scheme_b", ValidFrom: "2024-03-01", ValidTo: "2024-03-31"},
+			{SchemeID: "scheme_c", ValidFrom: "2024-04-05"},
+		},
+	}
+	tests := []struct {
+		name string
+		emp  *models.Employee
+		date string
+		want string
+	}{
+		{name: "no history", emp: &models.Employee{SalarySchemeID: "scheme_x"}, date: "2024-01-01", want: "scheme_x"},
+		{name: "open start", emp: history, date: "2020-01-01", want: "scheme_a"},
+		{name: "last day of an assignment", emp: history, date: "2024-02-29", want: "scheme_a"},
+		{name: "first day of an assignment", emp: history, date: "2024-03-01", want: "scheme_b"},
+		{name: "gap between assignments", emp: history, date: "2024-04-02", want: ""},
+		{name: "open end", emp: history, date: "2030-01-01", want: "scheme_c"},
+	}
+	for _, tt := range tests {
+		if got := SchemeIDAt(tt.emp, tt.date); got != tt.want {
+			t.Errorf("%s: SchemeIDAt(%s) = %q, want %q", tt.name, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestSchemeVersionAt(t *testing.T) {
+	scheme := &models.SalaryScheme{
+		ID:            "scheme",
+		Version:       3,
+		EffectiveFrom: "2024-04-01",
+		Percentage:    30,
+		PreviousVersions: []models.SalaryScheme{
+			{ID: "scheme", Version: 1, Percentage: 10},
+			{ID: "scheme", Version: 2, EffectiveFrom: "2024-03-01", Percentage: 20},
+		},
+	}
+	tests := []struct {
+		date string
+		want int
+	}{
+		{date: "2023-12-31", want: 1},
+		{date: "2024-02-29", want: 1},
+		{date: "2024-03-01", want: 2},
+		{date: "2024-03-31", want: 2},
+		{date: "2024-04-01", want: 3},
+		{date: "2025-01-01", want: 3},
+	}
+	for _, tt := range tests {
+		if got := SchemeVersionAt(scheme, tt.date); got.Version != tt.want {
+			t.Errorf("SchemeVersionAt(%s) = version %d, want %d", tt.date, got.Version, tt.want)
+		}
+	}
+
+	dated := &models.SalaryScheme{
+		Version:          2,
+		EffectiveFrom:    "2024-03-01",
+		PreviousVersions: []models.SalaryScheme{{Version: 1, EffectiveFrom: "2024-02-01"}},
+	}
+	if got := SchemeVersionAt(dated, "2024-01-15"); got.Version != 1 {
+		t.Errorf("date before all versions = version %d, want the oldest", got.Version)
+	}
+}
+
+func TestNewSchemeVersion(t *testing.T) {
+	existing := &models.SalaryScheme{ID: "scheme", Percentage: 10}
+
+	v2 := NewSchemeVersion(existing, models.SalaryScheme{Percentage: 20}, "2024-03-01")
+	if v2.Version != 2 || v2.EffectiveFrom != "2024-03-01" || len(v2.PreviousVersions) != 1 {
+		t.Fatalf("second version = %+v", v2)
+	}
+
+	// A version on the same day replaces the one saved before
+	v3 := NewSchemeVersion(&v2, models.SalaryScheme{Percentage: 25}, "2024-03-01")
+	if v3.Version != 3 || len(v3.PreviousVersions) != 1 || v3.PreviousVersions[0].Percentage != 10 {
+		t.Fatalf("same day version = %+v", v3)
+	}
+	if got := SchemeVersionAt(&v3, "2024-02-29").Percentage; got != 10 {
+		t.Errorf("percentage before the change = %v, want 10", got)
+	}
+	if got := SchemeVersionAt(&v3, "2024-03-01").Percentage; got != 25 {
+		t.Errorf("percentage from the change = %v, want 25", got)
+	}
+}
+
+func TestValidateAssignments(t *testing.T) {
+	tests := []struct {
+		name        string
+		assignments []models.SalarySchemeAssignment
+		wantErr     bool
+	}{
+		{name: "empty"},
+		{
+			name: "consecutive",
+			assignments: []models.SalarySchemeAssignment{
+				{SchemeID: "b", ValidFrom: "2024-03-01"},
+				{SchemeID: "a", ValidTo: "2024-02-29"},
+			},
+		},
+		{
+			name: "with a gap",
+			assignments: []models.SalarySchemeAssignment{
+				{SchemeID: "a", ValidTo: "2024-02-10"},
+				{SchemeID: "b", ValidFrom: "2024-03-01"},
+			},
+		},
+		{
+			name: "overlapping by a day",
+			assignments: []models.SalarySchemeAssignment{
+				{SchemeID: "a", ValidTo: "2024-03-01"},
+				{SchemeID: "b", ValidFrom: "2024-03-01"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "two open ends",
+			assignments: []models.SalarySchemeAssignment{
+				{SchemeID: "a", ValidFrom: "2024-01-01"},
+				{SchemeID: "b", ValidFrom: "2024-03-01"},
+			},
+			wantErr: true,
+		},
+		{
+			name:        "ends before it starts",
+			assignments: []models.SalarySchemeAssignment{{SchemeID: "a", ValidFrom: "2024-03-01", ValidTo: "2024-02-01"}},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid date",
+			assignments: []models.SalarySchemeAssignment{{SchemeID: "a", ValidFrom: "01.03.2024"}},
+			wantErr:     true,
+		},
+		{
+			name:        "no scheme",
+			assignments: []models.SalarySchemeAssignment{{ValidFrom: "2024-03-01"}},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		err := ValidateAssignments(tt.assignments)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateAssignments() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReassignScheme(t *testing.T) {
+	emp := &models.Employee{SalarySchemeID: "a"}
+	if err := ReassignScheme(emp, "b", "2024-03-01"); err != nil {
+		t.Fatal(err)
+	}
+	if emp.SalarySchemeID != "b" || len(emp.SalarySchemeAssignments) != 2 {
+		t.Fatalf("after reassigning = %+v", emp)
+	}
+	if err := ValidateAssignments(emp.SalarySchemeAssignments); err != nil {
+		t.Errorf("reassigned history is invalid: %v", err)
+	}
+	if got := SchemeIDAt(emp, "2024-02-29"); got != "a" {
+		t.Errorf("scheme the day before = %q, want a", got)
+	}
+	if got := SchemeIDAt(emp, "2024-03-01"); got != "b" {
+		t.Errorf("scheme from the change = %q, want b", got)
+	}
+}
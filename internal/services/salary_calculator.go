@@ -26,107 +26,197 @@ func getWashSourceType(event *models.WashEvent) string {
 	}
 }
 
-// calculateIndividualShare calculates the salary for a single employee for a specific wash event
+// washShare represents an employee's earnings for a single wash event
+type washShare struct {
+	earnings       models.Money
+	unpaidServices []string
+	components     []models.SalaryComponent
+	tier           string
+}
+
+func (w *washShare) add(component models.SalaryComponent) {
+	w.earnings += component.Amount
+	w.components = append(w.components, component)
+}
+
+// calculateIndividualShare calculates the salary for a single employee for a specific wash event.
+// slot is the employee's position among the employees on the wash; pools are
+// split so the shares of all slots add up exactly to the pool.
+// acc carries the employee's progress for tiered schemes and is advanced by
+// every wash the scheme applies to.
 func (s *SalaryCalculator) calculateIndividualShare(
 	scheme *models.SalaryScheme,
 	event *models.WashEvent,
 	numEmployeesOnWash int,
-) (earnings float64, unpaidServices []string) {
-	if numEmployeesOnWash <= 0 {
-		return 0, nil
+	slot int,
+	acc *tierAccumulator,
+) washShare {
+	var share washShare
+	if numEmployeesOnWash <= 0 || slot < 0 || slot >= numEmployeesOnWash {
+		return share
 	}
+	tiered := acc != nil && hasTiers(scheme)
 
 	// Percentage-based schemes
 	if scheme.Type == models.SalarySchemePercentage {
-		totalBaseAmount := event.TotalAmount
-		if event.NetAmount > 0 {
-			totalBaseAmount = event.NetAmount
+		if tiered {
+			components, tier := tieredShare(scheme, event, numEmployeesOnWash, slot, acc, true)
+			for _, c := range components {
+				share.add(c)
+			}
+			share.tier = tier
+			return share
 		}
 
-		totalAmountAfterDeduction := totalBaseAmount - scheme.FixedDeduction
-		totalSalaryPool := totalAmountAfterDeduction * (scheme.Percentage / 100)
-		earning := totalSalaryPool / float64(numEmployeesOnWash)
-
+		earning := percentageShare(scheme, event, numEmployeesOnWash, slot)
 		if earning > 0 {
-			return float64(int(earning*100)) / 100, nil // Round to 2 decimal places
+			share.add(models.SalaryComponent{Type: models.SalaryComponentPercentage, Amount: earning})
 		}
-		return 0, nil
+		return share
 	}
 
 	// Rate-based schemes
 	if scheme.Type == models.SalarySchemeRate {
-		schemeSource := scheme.RateSource
-
-		// Check if the scheme is applicable to this specific wash
-		if schemeSource != nil {
-			washSourceType := getWashSourceType(event)
-
-			if string(schemeSource.Type) != washSourceType {
-				return 0, nil
+		earning, unpaid, applicable := rateShare(scheme, event, numEmployeesOnWash, slot)
+		if !applicable {
+			return share
+		}
+		share.unpaidServices = unpaid
+		if earning > 0 {
+			share.add(models.SalaryComponent{Type: models.SalaryComponentRate, Amount: earning})
+		}
+		if tiered {
+			components, tier := tieredShare(scheme, event, numEmployeesOnWash, slot, acc, false)
+			for _, c := range components {
+				share.add(c)
 			}
+			share.tier = tier
+		}
+		return share
+	}
 
-			washSourceId := "retail"
-			if washSourceType != "retail" {
-				washSourceId = event.SourceID
+	// Hybrid schemes: percentage of revenue plus rates for listed services.
+	// The base wage is paid per day or shift, see applyDailyRules.
+	if scheme.Type == models.SalarySchemeHybrid {
+		if tiered {
+			components, tier := tieredShare(scheme, event, numEmployeesOnWash, slot, acc, true)
+			for _, c := range components {
+				share.add(c)
 			}
-
-			if schemeSource.ID != washSourceId {
-				return 0, nil
+			share.tier = tier
+		} else if scheme.Percentage > 0 {
+			if earning := percentageShare(scheme, event, numEmployeesOnWash, slot); earning > 0 {
+				share.add(models.SalaryComponent{Type: models.SalaryComponentPercentage, Amount: earning})
 			}
+		}
 
-			// Additional check for aggregator price list name
-			if washSourceType == "aggregator" && schemeSource.PriceListName != "" && event.PriceListName != schemeSource.PriceListName {
-				return 0, nil
+		if len(scheme.Rates) > 0 {
+			earning, unpaid, applicable := rateShare(scheme, event, numEmployeesOnWash, slot)
+			if applicable && earning > 0 {
+				share.add(models.SalaryComponent{Type: models.SalaryComponentRate, Amount: earning})
+			}
+			// Services without a rate are still paid through the percentage
+			if applicable && scheme.Percentage <= 0 && !tiered {
+				share.unpaidServices = unpaid
 			}
 		}
 
-		// Build rate map
-		rateMap := make(map[string]models.SalaryRate)
-		for _, r := range scheme.Rates {
-			rateMap[r.ServiceName] = r
+		return share
+	}
+
+	return share
+}
+
+// washBaseAmount returns the revenue of a wash salaries are calculated from
+func washBaseAmount(scheme *models.SalaryScheme, event *models.WashEvent) models.Money {
+	totalBaseAmount := event.TotalAmount
+	if event.NetAmount > 0 {
+		totalBaseAmount = event.NetAmount
+	}
+	return totalBaseAmount - scheme.FixedDeduction
+}
+
+// percentageShare calculates an employee's part of the percentage pool of a wash.
+// The pool is rounded half to even and split without losing kopecks.
+func percentageShare(scheme *models.SalaryScheme, event *models.WashEvent, numEmployeesOnWash int, slot int) models.Money {
+	totalSalaryPool := washBaseAmount(scheme, event).MulPercent(scheme.Percentage, models.RoundHalfEven)
+	return totalSalaryPool.Allocate(numEmployeesOnWash)[slot]
+}
+
+// rateShare calculates an employee's part of the per-service rates of a wash.
+// applicable is false when the scheme's rate source does not match the wash.
+func rateShare(scheme *models.SalaryScheme, event *models.WashEvent, numEmployeesOnWash int, slot int) (earning models.Money, unpaid []string, applicable bool) {
+	schemeSource := scheme.RateSource
+
+	// Check if the scheme is applicable to this specific wash
+	if schemeSource != nil {
+		washSourceType := getWashSourceType(event)
+
+		if string(schemeSource.Type) != washSourceType {
+			return 0, nil, false
+		}
+
+		washSourceId := "retail"
+		if washSourceType != "retail" {
+			washSourceId = event.SourceID
 		}
 
-		// Calculate total rate for all services
-		allServices := []models.PriceListItem{event.Services.Main}
-		allServices = append(allServices, event.Services.Additional...)
+		if schemeSource.ID != washSourceId {
+			return 0, nil, false
+		}
 
-		var totalRateForWash float64
-		var unpaid []string
+		// Additional check for aggregator price list name
+		if washSourceType == "aggregator" && schemeSource.PriceListName != "" && event.PriceListName != schemeSource.PriceListName {
+			return 0, nil, false
+		}
+	}
 
-		for _, service := range allServices {
-			if service.ServiceName == "" {
-				continue
-			}
+	// Build rate maps; the stable service ID wins over the name
+	rateMap := make(map[string]models.SalaryRate)
+	rateByID := make(map[string]models.SalaryRate)
+	for _, r := range scheme.Rates {
+		rateMap[r.ServiceName] = r
+		if r.ServiceID != "" {
+			rateByID[r.ServiceID] = r
+		}
+	}
 
-			rateItem, found := rateMap[service.ServiceName]
-			if found && rateItem.Rate > 0 {
-				earningForService := rateItem.Rate - rateItem.Deduction
-				if earningForService > 0 {
-					totalRateForWash += earningForService
-				}
-			} else {
-				// Check for duplicates
-				isDuplicate := false
-				for _, s := range unpaid {
-					if s == service.ServiceName {
-						isDuplicate = true
-						break
-					}
-				}
-				if !isDuplicate {
-					unpaid = append(unpaid, service.ServiceName)
-				}
-			}
+	// Calculate total rate for all services
+	allServices := []models.PriceListItem{event.Services.Main}
+	allServices = append(allServices, event.Services.Additional...)
+
+	var totalRateForWash models.Money
+
+	for _, service := range allServices {
+		if service.ServiceName == "" {
+			continue
 		}
 
-		earning := totalRateForWash / float64(numEmployeesOnWash)
-		if earning > 0 {
-			return float64(int(earning*100)) / 100, unpaid
+		rateItem, found := rateByID[service.ServiceID]
+		if !found || service.ServiceID == "" {
+			rateItem, found = rateMap[service.ServiceName]
+		}
+		if found && rateItem.Rate > 0 {
+			earningForService := rateItem.Rate - rateItem.Deduction
+			if earningForService > 0 {
+				totalRateForWash += earningForService
+			}
+		} else {
+			// Check for duplicates
+			isDuplicate := false
+			for _, s := range unpaid {
+				if s == service.ServiceName {
+					isDuplicate = true
+					break
+				}
+			}
+			if !isDuplicate {
+				unpaid = append(unpaid, service.ServiceName)
+			}
 		}
-		return 0, unpaid
 	}
 
-	return 0, nil
+	return totalRateForWash.Allocate(numEmployeesOnWash)[slot], unpaid, true
 }
 
 // GenerateSalaryReport generates salary report for all employees
@@ -158,8 +248,24 @@ func (s *SalaryCalculator) GenerateSalaryReport(
 		}
 	}
 
-	// Process each wash event
-	for _, event := range washEvents {
+	// Working days of employees whose schemes have daily rules
+	workDays := make(map[string]map[string]*workDay)
+
+	// Chemical consumption per day of employees whose schemes settle it
+	chemicalDays := make(map[string]map[string]*chemicalDay)
+
+	// Tier progress per employee and scheme
+	accumulators := make(map[string]*tierAccumulator)
+
+	// Process wash events in chronological order so tiers are reached in
+	// the order the washes happened
+	chronological := make([]models.WashEvent, len(washEvents))
+	copy(chronological, washEvents)
+	sort.SliceStable(chronological, func(i, j int) bool {
+		return chronological[i].Timestamp < chronological[j].Timestamp
+	})
+
+	for _, event := range chronological {
 		if len(event.EmployeeIDs) == 0 {
 			continue
 		}
@@ -176,36 +282,85 @@ func (s *SalaryCalculator) GenerateSalaryReport(
 			continue
 		}
 
+		// Schemes and scheme versions are picked as of the wash date
+		var washDate string
+		if t, ok := ParseTimestamp(event.Timestamp); ok {
+			washDate = LocalDate(t)
+		}
+
 		// Calculate for each employee
-		for _, emp := range employeesOnWash {
-			if emp.SalarySchemeID == "" {
+		for slot, emp := range employeesOnWash {
+			schemeID := SchemeIDAt(&emp, washDate)
+			if schemeID == "" {
 				continue
 			}
 
-			scheme, found := schemeMap[emp.SalarySchemeID]
+			current, found := schemeMap[schemeID]
 			if !found {
 				continue
 			}
+			scheme := SchemeVersionAt(&current, washDate)
 
-			earnings, unpaidServices := s.calculateIndividualShare(&scheme, &event, len(employeesOnWash))
+			var acc *tierAccumulator
+			if hasTiers(scheme) {
+				key := emp.ID + "|" + schemeID
+				if accumulators[key] == nil {
+					accumulators[key] = &tierAccumulator{}
+				}
+				acc = accumulators[key]
+			}
+
+			share := s.calculateIndividualShare(scheme, &event, len(employeesOnWash), slot, acc)
+
+			if hasDailyRules(scheme) {
+				trackWorkDay(workDays, emp.ID, &event, share.earnings, scheme)
+			}
+			if scheme.ChemicalRule != nil {
+				trackChemicalUsage(chemicalDays, emp.ID, &event, scheme)
+			}
 
 			// Add to breakdown if there are earnings OR unpaid services
-			if earnings > 0 || len(unpaidServices) > 0 {
-				salaryData[emp.ID].TotalEarnings += earnings
+			if share.earnings > 0 || len(share.unpaidServices) > 0 {
+				salaryData[emp.ID].TotalEarnings += share.earnings
 				salaryData[emp.ID].Breakdown = append(salaryData[emp.ID].Breakdown, models.SalaryBreakdownItem{
 					WashEventID:    event.ID,
 					Timestamp:      event.Timestamp,
 					VehicleNumber:  event.VehicleNumber,
-					Earnings:       earnings,
-					UnpaidServices: unpaidServices,
+					Earnings:       share.earnings,
+					UnpaidServices: share.unpaidServices,
+					Components:     share.components,
+					Tier:           share.tier,
 				})
 			}
 		}
 	}
 
+	// Apply base wage, guaranteed minimum and cap per working day
+	for empID, days := range workDays {
+		salaryData[empID].Adjustments = append(salaryData[empID].Adjustments, applyDailyRules(days)...)
+	}
+
+	// Settle chemical overuse and savings per working day
+	for empID, days := range chemicalDays {
+		salaryData[empID].Adjustments = append(salaryData[empID].Adjustments, applyChemicalRules(days)...)
+	}
+
+	for _, data := range salaryData {
+		for _, adj := range data.Adjustments {
+			data.TotalEarnings += adj.Amount
+		}
+		sort.SliceStable(data.Adjustments, func(i, j int) bool {
+			return data.Adjustments[i].Date < data.Adjustments[j].Date
+		})
+	}
+
 	// Convert to slice and sort by total earnings descending
 	var result []models.SalaryReportData
 	for _, data := range salaryData {
+		// Breakdown is listed newest first, like the wash log
+		sort.SliceStable(data.Breakdown, func(i, j int) bool {
+			return data.Breakdown[i].Timestamp > data.Breakdown[j].Timestamp
+		})
 		result = append(result, *data)
 	}
 
@@ -215,3 +370,110 @@ func (s *SalaryCalculator) GenerateSalaryReport(
 
 	return result
 }
+
+// GeneratePeriodReport generates salary report for the given period with
+// balances built from employee transactions. Earnings and transactions dated
+// before the period make up the opening balance.
+func (s *SalaryCalculator) GeneratePeriodReport(
+	washEvents []models.WashEvent,
+	employees []models.Employee,
+	salarySchemes []models.SalaryScheme,
+	transactions map[string][]models.EmployeeTransaction,
+	period Period,
+) []models.SalaryReportData {
+	report := s.GenerateSalaryReport(washEvents, employees, salarySchemes)
+
+	for i := range report {
+		data := &report[i]
+
+		var openingBalance models.Money
+		var periodEarnings models.Money
+		periodBreakdown := []models.SalaryBreakdownItem{}
+
+		for _, item := range data.Breakdown {
+			if period.IsBeforeTimestamp(item.Timestamp) {
+				openingBalance += item.Earnings
+				continue
+			}
+			if period.ContainsTimestamp(item.Timestamp) {
+				periodEarnings += item.Earnings
+				periodBreakdown = append(periodBreakdown, item)
+			}
+		}
+
+		var periodAdjustments []models.SalaryAdjustment
+		for _, adj := range data.Adjustments {
+			if period.IsBeforeTimestamp(adj.Timestamp) {
+				openingBalance += adj.Amount
+				continue
+			}
+			if period.ContainsTimestamp(adj.Timestamp) {
+				periodEarnings += adj.Amount
+				periodAdjustments = append(periodAdjustments, adj)
+			}
+		}
+
+		for _, trans := range transactions[data.EmployeeID] {
+			if period.IsBeforeTimestamp(trans.Date) {
+				openingBalance += transactionBalanceEffect(&trans)
+				continue
+			}
+			if !period.ContainsTimestamp(trans.Date) {
+				continue
+			}
+
+			switch trans.Type {
+			case models.EmpTransBonus:
+				data.Bonuses += trans.Amount
+			case models.EmpTransPayment:
+				data.Payouts += trans.Amount
+			case models.EmpTransLoan:
+				// Loans with a repayment plan are withheld by their repayments
+				if trans.Repayment == nil {
+					data.Loans += trans.Amount
+				}
+			case models.EmpTransLoanRepayment:
+				data.LoanRepayments += trans.Amount
+			case models.EmpTransPurchase:
+				data.Purchases += trans.Amount
+			case models.EmpTransFine:
+				data.Fines += trans.Amount
+			}
+			data.Transactions = append(data.Transactions, trans)
+		}
+
+		data.Breakdown = periodBreakdown
+		data.Adjustments = periodAdjustments
+		data.TotalEarnings = periodEarnings
+		data.OpeningBalance = openingBalance
+		data.ClosingBalance = data.OpeningBalance + data.TotalEarnings + data.Bonuses -
+			data.Payouts - data.Loans - data.Purchases - data.Fines - data.LoanRepayments
+
+		sort.Slice(data.Transactions, func(a, b int) bool {
+			return data.Transactions[a].Date < data.Transactions[b].Date
+		})
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].TotalEarnings > report[j].TotalEarnings
+	})
+
+	return report
+}
+
+// transactionBalanceEffect returns how a transaction changes the amount owed to the employee
+func transactionBalanceEffect(trans *models.EmployeeTransaction) models.Money {
+	switch trans.Type {
+	case models.EmpTransBonus:
+		return trans.Amount
+	case models.EmpTransLoan:
+		if trans.Repayment != nil {
+			return 0
+		}
+		return -trans.Amount
+	case models.EmpTransPayment, models.EmpTransPurchase, models.EmpTransFine, models.EmpTransLoanRepayment:
+		return -trans.Amount
+	default:
+		return 0
+	}
+}
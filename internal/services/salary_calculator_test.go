@@ -0,0 +1,234 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// noon returns the timestamp of midday of a date in server local time
+func noon(date string) string {
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(12 * time.Hour).Format(time.RFC3339)
+}
+
+// month returns the period of a calendar month in server local time
+func month(year int, m time.Month) Period {
+	from := time.Date(year, m, 1, 0, 0, 0, 0, time.Local)
+	return Period{From: from, To: from.AddDate(0, 1, 0)}
+}
+
+func testWash(id, date string, amount models.Money, employeeIDs ...string) models.WashEvent {
+	return models.WashEvent{
+		ID:            id,
+		Timestamp:     noon(date),
+		EmployeeIDs:   employeeIDs,
+		PaymentMethod: models.WashPaymentCash,
+		TotalAmount:   amount,
+	}
+}
+
+func reportOf(t *testing.T, report []models.SalaryReportData, employeeID string) models.SalaryReportData {
+	t.Helper()
+	for _, data := range report {
+		if data.EmployeeID == employeeID {
+			return data
+		}
+	}
+	t.Fatalf("no report for %s", employeeID)
+	return models.SalaryReportData{}
+}
+
+func washEarnings(data models.SalaryReportData) map[string]models.Money {
+	earnings := make(map[string]models.Money)
+	for _, item := range data.Breakdown {
+		earnings[item.WashEventID] = item.Earnings
+	}
+	return earnings
+}
+
+func TestSalaryTiers(t *testing.T) {
+	emp := models.Employee{ID: "emp", SalarySchemeID: "scheme"}
+	tests := []struct {
+		name       string
+		scheme     models.SalaryScheme
+		washes     []models.WashEvent
+		want       map[string]models.Money
+		components map[string]int
+	}{
+		{
+			name: "revenue tier crossed mid-period",
+			scheme: models.SalaryScheme{
+				ID: "scheme", Type: models.SalarySchemePercentage, Percentage: 10,
+				Tiers:     []models.SalaryTier{{From: 10000, Percentage: 20}},
+				TierBasis: models.SalaryTierByRevenue, TierPeriod: models.SalaryTierPeriodMonth,
+			},
+			// Listed out of order: tiers follow the order the washes happened
+			washes: []models.WashEvent{
+				testWash("w2", "2024-03-10", 400000, "emp"),
+				testWash("w1", "2024-03-05", 800000, "emp"),
+				testWash("w3", "2024-04-01", 500000, "emp"),
+			},
+			// w2 earns 10% of the 2 000 below the threshold and 20% of the 2 000 above;
+			// w3 starts a new month from the base level
+			want:       map[string]models.Money{"w1": 80000, "w2": 60000, "w3": 50000},
+			components: map[string]int{"w1": 1, "w2": 2, "w3": 1},
+		},
+		{
+			name: "wash count tier",
+			scheme: models.SalaryScheme{
+				ID: "scheme", Type: models.SalarySchemePercentage, Percentage: 10,
+				Tiers:     []models.SalaryTier{{From: 2, Percentage: 20, BonusPerWash: 5000}},
+				TierBasis: models.SalaryTierByWashCount, TierPeriod: models.SalaryTierPeriodDay,
+			},
+			washes: []models.WashEvent{
+				testWash("w1", "2024-03-05", 100000, "emp"),
+				testWash("w2", "2024-03-05", 100000, "emp"),
+				testWash("w3", "2024-03-05", 100000, "emp"),
+				testWash("w4", "2024-03-06", 100000, "emp"),
+			},
+			want:       map[string]models.Money{"w1": 10000, "w2": 10000, "w3": 25000, "w4": 10000},
+			components: map[string]int{"w1": 1, "w2": 1, "w3": 2, "w4": 1},
+		},
+	}
+	for _, tt := range tests {
+		report := NewSalaryCalculator().GenerateSalaryReport(tt.washes, []models.Employee{emp}, []models.SalaryScheme{tt.scheme})
+		data := reportOf(t, report, "emp")
+		got := washEarnings(data)
+		var total models.Money
+		for id, want := range tt.want {
+			total += want
+			if got[id] != want {
+				t.Errorf("%s: wash %s earned %v, want %v", tt.name, id, got[id], want)
+			}
+		}
+		for _, item := range data.Breakdown {
+			if len(item.Components) != tt.components[item.WashEventID] {
+				t.Errorf("%s: wash %s has %d components, want %d", tt.name, item.WashEventID, len(item.Components), tt.components[item.WashEventID])
+			}
+		}
+		if data.TotalEarnings != total {
+			t.Errorf("%s: total %v, want %v", tt.name, data.TotalEarnings, total)
+		}
+	}
+}
+
+func TestSalaryDailyRules(t *testing.T) {
+	emp := models.Employee{ID: "emp", SalarySchemeID: "scheme"}
+	scheme := models.SalaryScheme{
+		ID: "scheme", Type: models.SalarySchemePercentage, Percentage: 10,
+		GuaranteedDailyMinimum: 100000, DailyCap: 150000,
+	}
+	washes := []models.WashEvent{
+		testWash("w1", "2024-03-05", 500000, "emp"),
+		testWash("w2", "2024-03-06", 1000000, "emp"),
+		testWash("w3", "2024-03-06", 1000000, "emp"),
+		testWash("w4", "2024-03-07", 1200000, "emp"),
+	}
+	report := NewSalaryCalculator().GenerateSalaryReport(washes, []models.Employee{emp}, []models.SalaryScheme{scheme})
+	data := reportOf(t, report, "emp")
+
+	tests := []struct {
+		date string
+		typ  models.SalaryComponentType
+		want models.Money
+	}{
+		{date: "2024-03-05", typ: models.SalaryComponentMinimumTopUp, want: 50000},
+		{date: "2024-03-06", typ: models.SalaryComponentCap, want: -50000},
+	}
+	if len(data.Adjustments) != len(tests) {
+		t.Fatalf("adjustments = %+v, want %d", data.Adjustments, len(tests))
+	}
+	for i, tt := range tests {
+		got := data.Adjustments[i]
+		if got.Date != tt.date || got.Type != tt.typ || got.Amount != tt.want {
+			t.Errorf("adjustment %d = %s %s %v, want %s %s %v", i, got.Date, got.Type, got.Amount, tt.date, tt.typ, tt.want)
+		}
+	}
+	// A day between the minimum and the cap is left alone
+	if want := models.Money(100000 + 150000 + 120000); data.TotalEarnings != want {
+		t.Errorf("total %v, want %v", data.TotalEarnings, want)
+	}
+}
+
+func TestSchemeVersionOnFirstDayOfPeriod(t *testing.T) {
+	emp := models.Employee{ID: "emp", SalarySchemeID: "scheme"}
+	scheme := models.SalaryScheme{
+		ID: "scheme", Type: models.SalarySchemePercentage, Percentage: 30,
+		Version: 2, EffectiveFrom: "2024-04-01",
+		PreviousVersions: []models.SalaryScheme{
+			{ID: "scheme", Type: models.SalarySchemePercentage, Percentage: 10, Version: 1},
+		},
+	}
+	washes := []models.WashEvent{
+		testWash("w1", "2024-03-31", 100000, "emp"),
+		testWash("w2", "2024-04-01", 100000, "emp"),
+	}
+	report := NewSalaryCalculator().GeneratePeriodReport(washes, []models.Employee{emp}, []models.SalaryScheme{scheme}, nil, month(2024, time.April))
+	data := reportOf(t, report, "emp")
+
+	if data.OpeningBalance != 10000 {
+		t.Errorf("opening balance %v, want 100 at the old 10%%", data.OpeningBalance)
+	}
+	if data.TotalEarnings != 30000 {
+		t.Errorf("earnings %v, want 300 at the new 30%%", data.TotalEarnings)
+	}
+	if len(data.Breakdown) != 1 || data.Breakdown[0].WashEventID != "w2" {
+		t.Errorf("breakdown = %+v, want only w2", data.Breakdown)
+	}
+}
+
+func TestPeriodReportSplit(t *testing.T) {
+	emp := models.Employee{ID: "emp", SalarySchemeID: "scheme"}
+	scheme := models.SalaryScheme{
+		ID: "scheme", Type: models.SalarySchemePercentage, Percentage: 10,
+		GuaranteedDailyMinimum: 20000,
+	}
+	washes := []models.WashEvent{
+		testWash("w1", "2024-02-20", 300000, "emp"),
+		testWash("w2", "2024-03-01", 100000, "emp"),
+		testWash("w3", "2024-03-31", 500000, "emp"),
+		testWash("w4", "2024-04-01", 800000, "emp"),
+	}
+	transactions := map[string][]models.EmployeeTransaction{
+		"emp": {
+			{ID: "t1", Date: noon("2024-02-29"), Type: models.EmpTransPayment, Amount: 10000},
+			{ID: "t2", Date: noon("2024-03-15"), Type: models.EmpTransBonus, Amount: 50000},
+			{ID: "t3", Date: noon("2024-03-31"), Type: models.EmpTransFine, Amount: 5000},
+			{ID: "t4", Date: noon("2024-04-01"), Type: models.EmpTransPayment, Amount: 70000},
+		},
+	}
+	calc := NewSalaryCalculator()
+
+	tests := []struct {
+		period  Period
+		opening models.Money
+		closing models.Money
+	}{
+		{period: month(2024, time.February), opening: 0, closing: 30000 - 10000},
+		{period: month(2024, time.March), opening: 20000, closing: 20000 + 20000 + 50000 + 50000 - 5000},
+		{period: month(2024, time.April), opening: 135000, closing: 135000 + 80000 - 70000},
+	}
+	var previous *models.SalaryReportData
+	for _, tt := range tests {
+		data := reportOf(t, calc.GeneratePeriodReport(washes, []models.Employee{emp}, []models.SalaryScheme{scheme}, transactions, tt.period), "emp")
+		if data.OpeningBalance != tt.opening || data.ClosingBalance != tt.closing {
+			t.Errorf("%s: balances %v..%v, want %v..%v", tt.period.From.Format("2006-01"),
+				data.OpeningBalance, data.ClosingBalance, tt.opening, tt.closing)
+		}
+		if previous != nil && data.OpeningBalance != previous.ClosingBalance {
+			t.Errorf("%s: opening balance %v differs from the previous closing balance %v",
+				tt.period.From.Format("2006-01"), data.OpeningBalance, previous.ClosingBalance)
+		}
+		previous = &data
+	}
+
+	whole := reportOf(t, calc.GeneratePeriodReport(washes, []models.Employee{emp}, []models.SalaryScheme{scheme}, transactions, Period{}), "emp")
+	if whole.ClosingBalance != previous.ClosingBalance {
+		t.Errorf("unbounded closing balance %v, want %v", whole.ClosingBalance, previous.ClosingBalance)
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// workDay collects what an employee earned from washes on one calendar day
+type workDay struct {
+	date     time.Time
+	earnings models.Money
+	shiftIDs map[string]bool
+	scheme   *models.SalaryScheme // scheme version in effect on the day
+}
+
+// hasDailyRules reports whether a scheme pays or limits anything per working day
+func hasDailyRules(scheme *models.SalaryScheme) bool {
+	return scheme.BaseWage > 0 || scheme.GuaranteedDailyMinimum > 0 || scheme.DailyCap > 0
+}
+
+// trackWorkDay records a wash on the employee's working day in server local time
+func trackWorkDay(workDays map[string]map[string]*workDay, employeeID string, event *models.WashEvent, earnings models.Money, scheme *models.SalaryScheme) {
+	t, ok := ParseTimestamp(event.Timestamp)
+	if !ok {
+		return
+	}
+	t = t.In(time.Local)
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+	key := date.Format("2006-01-02")
+
+	if workDays[employeeID] == nil {
+		workDays[employeeID] = make(map[string]*workDay)
+	}
+	day, ok := workDays[employeeID][key]
+	if !ok {
+		day = &workDay{date: date, shiftIDs: make(map[string]bool), scheme: scheme}
+		workDays[employeeID][key] = day
+	}
+
+	day.earnings += earnings
+	if event.ShiftID != "" {
+		day.shiftIDs[event.ShiftID] = true
+	}
+}
+
+// applyDailyRules produces the base wage, minimum top-up and cap adjustments
+// for each working day. The minimum and cap apply to the day's total
+// including the base wage.
+func applyDailyRules(days map[string]*workDay) []models.SalaryAdjustment {
+	var adjustments []models.SalaryAdjustment
+
+	for key, day := range days {
+		scheme := day.scheme
+		timestamp := day.date.Format(time.RFC3339)
+		total := day.earnings
+
+		if scheme.BaseWage > 0 {
+			units := 1
+			if scheme.BaseWagePeriod == models.BaseWagePerShift && len(day.shiftIDs) > 1 {
+				units = len(day.shiftIDs)
+			}
+			base := scheme.BaseWage * models.Money(units)
+			total += base
+			adjustments = append(adjustments, models.SalaryAdjustment{
+				Type:        models.SalaryComponentBaseWage,
+				Timestamp:   timestamp,
+				Date:        key,
+				Amount:      base,
+				Description: baseWageDescription(scheme, units),
+			})
+		}
+
+		if scheme.GuaranteedDailyMinimum > 0 && total < scheme.GuaranteedDailyMinimum {
+			topUp := scheme.GuaranteedDailyMinimum - total
+			total += topUp
+			adjustments = append(adjustments, models.SalaryAdjustment{
+				Type:        models.SalaryComponentMinimumTopUp,
+				Timestamp:   timestamp,
+				Date:        key,
+				Amount:      topUp,
+				Description: "Доплата до гарантированного минимума",
+			})
+		}
+
+		if scheme.DailyCap > 0 && total > scheme.DailyCap {
+			excess := total - scheme.DailyCap
+			adjustments = append(adjustments, models.SalaryAdjustment{
+				Type:        models.SalaryComponentCap,
+				Timestamp:   timestamp,
+				Date:        key,
+				Amount:      -excess,
+				Description: "Ограничение дневного заработка",
+			})
+		}
+	}
+
+	sort.SliceStable(adjustments, func(i, j int) bool {
+		return adjustments[i].Date < adjustments[j].Date
+	})
+
+	return adjustments
+}
+
+func baseWageDescription(scheme *models.SalaryScheme, units int) string {
+	if scheme.BaseWagePeriod == models.BaseWagePerShift {
+		if units > 1 {
+			return "Оклад за смены"
+		}
+		return "Оклад за смену"
+	}
+	return "Оклад за день"
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"sort"
+
+	"backend-go/internal/models"
+)
+
+// leavesServicesUnpaid reports whether services without a rate go unpaid
+// under the scheme, mirroring calculateIndividualShare
+func leavesServicesUnpaid(scheme *models.SalaryScheme) bool {
+	switch scheme.Type {
+	case models.SalarySchemeRate:
+		return true
+	case models.SalarySchemeHybrid:
+		return len(scheme.Rates) > 0 && scheme.Percentage <= 0 && !hasTiers(scheme)
+	default:
+		return false
+	}
+}
+
+// FindRateGaps aggregates the services performed by employees on the scheme
+// during the period that the current version of the scheme has no rate for.
+// sourcePrices maps service names to prices of the scheme's rate source.
+func FindRateGaps(
+	scheme *models.SalaryScheme,
+	washEvents []models.WashEvent,
+	employees []models.Employee,
+	period Period,
+	sourcePrices map[string]models.Money,
+) []models.SalaryRateGap {
+	result := []models.SalaryRateGap{}
+	if !leavesServicesUnpaid(scheme) {
+		return result
+	}
+
+	employeeMap := make(map[string]*models.Employee, len(employees))
+	for i := range employees {
+		employeeMap[employees[i].ID] = &employees[i]
+	}
+
+	gaps := make(map[string]*models.SalaryRateGap)
+	for i := range washEvents {
+		event := &washEvents[i]
+		if !period.ContainsTimestamp(event.Timestamp) || !washOnScheme(event, employeeMap, scheme.ID) {
+			continue
+		}
+
+		_, unpaid, applicable := rateShare(scheme, event, 1, 0)
+		if !applicable || len(unpaid) == 0 {
+			continue
+		}
+
+		isUnpaid := make(map[string]bool, len(unpaid))
+		for _, name := range unpaid {
+			isUnpaid[name] = true
+		}
+
+		for _, service := range append([]models.PriceListItem{event.Services.Main}, event.Services.Additional...) {
+			if !isUnpaid[service.ServiceName] {
+				continue
+			}
+			gap, ok := gaps[service.ServiceName]
+			if !ok {
+				gap = &models.SalaryRateGap{
+					ServiceName: service.ServiceName,
+					SourcePrice: sourcePrices[service.ServiceName],
+					FirstSeen:   event.Timestamp,
+					LastSeen:    event.Timestamp,
+				}
+				gaps[service.ServiceName] = gap
+			}
+			gap.Count++
+			gap.Revenue += service.Price
+			if event.Timestamp < gap.FirstSeen {
+				gap.FirstSeen = event.Timestamp
+			}
+			if event.Timestamp > gap.LastSeen {
+				gap.LastSeen = event.Timestamp
+			}
+		}
+	}
+
+	for _, gap := range gaps {
+		gap.AveragePrice = gap.Revenue.Allocate(gap.Count)[gap.Count-1]
+		result = append(result, *gap)
+	}
+
+	// Most revenue left unpaid first
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Revenue != result[j].Revenue {
+			return result[i].Revenue > result[j].Revenue
+		}
+		return result[i].ServiceName < result[j].ServiceName
+	})
+
+	return result
+}
+
+// washOnScheme reports whether any employee on the wash was paid by the
+// scheme on the day of the wash
+func washOnScheme(event *models.WashEvent, employeeMap map[string]*models.Employee, schemeID string) bool {
+	var washDate string
+	if t, ok := ParseTimestamp(event.Timestamp); ok {
+		washDate = LocalDate(t)
+	}
+
+	for _, empID := range event.EmployeeIDs {
+		if emp, ok := employeeMap[empID]; ok && SchemeIDAt(emp, washDate) == schemeID {
+			return true
+		}
+	}
+	return false
+}
+
+// RateFromPrice suggests a rate for a service as a percentage of its price,
+// preferring the rate source price over the average price performed
+func RateFromPrice(gap *models.SalaryRateGap, percentage float64) models.Money {
+	price := gap.SourcePrice
+	if price <= 0 {
+		price = gap.AveragePrice
+	}
+	return price.MulPercent(percentage, models.RoundHalfEven)
+}
+
+// ServicePrices maps the services of a price list to their prices
+func ServicePrices(items []models.PriceListItem) map[string]models.Money {
+	prices := make(map[string]models.Money, len(items))
+	for _, item := range items {
+		prices[item.ServiceName] = item.Price
+	}
+	return prices
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"sort"
+
+	"backend-go/internal/models"
+)
+
+// SimulateScheme replays wash history through a proposed scheme and compares
+// each selected employee's earnings for the period with the current rules.
+//
+// The proposed rules apply to the whole history as a single version. When
+// the proposed scheme replaces an existing one, employees keep their
+// assignments; selected employees never assigned to it during the period are
+// moved to it for the whole history, which is how a draft scheme is tried.
+// Without a selection the employees assigned to the scheme during the period
+// are compared.
+func (s *SalaryCalculator) SimulateScheme(
+	washEvents []models.WashEvent,
+	employees []models.Employee,
+	salarySchemes []models.SalaryScheme,
+	proposed models.SalaryScheme,
+	employeeIDs []string,
+	period Period,
+) models.SalarySimulationResult {
+	proposed.EffectiveFrom = ""
+	proposed.PreviousVersions = nil
+
+	from, to := periodDates(period)
+
+	selected := make(map[string]bool, len(employeeIDs))
+	for _, id := range employeeIDs {
+		selected[id] = true
+	}
+	if len(selected) == 0 {
+		for i := range employees {
+			if assignedInPeriod(&employees[i], proposed.ID, from, to) {
+				selected[employees[i].ID] = true
+			}
+		}
+	}
+
+	proposedSchemes := make([]models.SalaryScheme, 0, len(salarySchemes)+1)
+	for _, scheme := range salarySchemes {
+		if scheme.ID != proposed.ID {
+			proposedSchemes = append(proposedSchemes, scheme)
+		}
+	}
+	proposedSchemes = append(proposedSchemes, proposed)
+
+	proposedEmployees := make([]models.Employee, len(employees))
+	for i, emp := range employees {
+		if selected[emp.ID] && !assignedInPeriod(&emp, proposed.ID, from, to) {
+			emp.SalarySchemeID = proposed.ID
+			emp.SalarySchemeAssignments = nil
+		}
+		proposedEmployees[i] = emp
+	}
+
+	noTransactions := map[string][]models.EmployeeTransaction{}
+	current := s.GeneratePeriodReport(washEvents, employees, salarySchemes, noTransactions, period)
+	simulated := s.GeneratePeriodReport(washEvents, proposedEmployees, proposedSchemes, noTransactions, period)
+
+	simulatedByID := make(map[string]*models.SalaryReportData, len(simulated))
+	for i := range simulated {
+		simulatedByID[simulated[i].EmployeeID] = &simulated[i]
+	}
+
+	result := models.SalarySimulationResult{
+		Scheme:    proposed,
+		From:      from,
+		To:        to,
+		Employees: []models.SalarySimulationItem{},
+	}
+
+	for _, data := range current {
+		if !selected[data.EmployeeID] {
+			continue
+		}
+
+		item := models.SalarySimulationItem{
+			EmployeeID:       data.EmployeeID,
+			EmployeeName:     data.EmployeeName,
+			CurrentEarnings:  data.TotalEarnings,
+			CurrentWashCount: len(data.Breakdown),
+		}
+		if sim, ok := simulatedByID[data.EmployeeID]; ok {
+			item.ProposedEarnings = sim.TotalEarnings
+			item.ProposedWashCount = len(sim.Breakdown)
+		}
+		item.Delta = item.ProposedEarnings - item.CurrentEarnings
+
+		result.CurrentTotal += item.CurrentEarnings
+		result.ProposedTotal += item.ProposedEarnings
+		result.Employees = append(result.Employees, item)
+	}
+	result.Delta = result.ProposedTotal - result.CurrentTotal
+
+	// Biggest changes first
+	sort.SliceStable(result.Employees, func(i, j int) bool {
+		return result.Employees[i].Delta.Abs() > result.Employees[j].Delta.Abs()
+	})
+
+	return result
+}
+
+// periodDates returns the inclusive YYYY-MM-DD bounds of a period;
+// unbounded sides are empty
+func periodDates(period Period) (from, to string) {
+	if !period.From.IsZero() {
+		from = LocalDate(period.From)
+	}
+	if !period.To.IsZero() {
+		// To is exclusive
+		to = LocalDate(period.To.Add(-1))
+	}
+	return from, to
+}
+
+// assignedInPeriod reports whether the employee is paid by the scheme at any
+// time within the inclusive date range
+func assignedInPeriod(emp *models.Employee, schemeID string, from, to string) bool {
+	if to == "" {
+		to = "9999-12-31"
+	}
+	for _, id := range SchemeIDsInPeriod(emp, from, to) {
+		if id == schemeID {
+			return true
+		}
+	}
+	return false
+}
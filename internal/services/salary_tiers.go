@@ -0,0 +1,181 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"backend-go/internal/models"
+)
+
+// tierAccumulator tracks an employee's progress within the current
+// accumulation period of a tiered scheme
+type tierAccumulator struct {
+	periodKey string
+	revenue   models.Money
+	washes    int
+}
+
+// tierLevel is a resolved tier with the percentage inherited from below
+type tierLevel struct {
+	label        string
+	from         float64
+	percentage   float64
+	bonusPerWash models.Money
+}
+
+// hasTiers reports whether a scheme uses progressive tiers
+func hasTiers(scheme *models.SalaryScheme) bool {
+	return len(scheme.Tiers) > 0
+}
+
+// tierPeriodKey returns the accumulation period a wash belongs to
+func tierPeriodKey(period models.SalaryTierPeriod, t time.Time) string {
+	t = t.In(time.Local)
+	switch period {
+	case models.SalaryTierPeriodDay:
+		return t.Format("2006-01-02")
+	case models.SalaryTierPeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// advance starts a new accumulation period when the wash falls outside the current one
+func (acc *tierAccumulator) advance(scheme *models.SalaryScheme, event *models.WashEvent) {
+	t, ok := ParseTimestamp(event.Timestamp)
+	if !ok {
+		return
+	}
+	key := tierPeriodKey(scheme.TierPeriod, t)
+	if key != acc.periodKey {
+		acc.periodKey = key
+		acc.revenue = 0
+		acc.washes = 0
+	}
+}
+
+// tierLevels returns the base level followed by the scheme's tiers in
+// ascending order
+func tierLevels(scheme *models.SalaryScheme) []tierLevel {
+	tiers := make([]models.SalaryTier, len(scheme.Tiers))
+	copy(tiers, scheme.Tiers)
+	sort.SliceStable(tiers, func(i, j int) bool {
+		return tiers[i].From < tiers[j].From
+	})
+
+	levels := []tierLevel{{label: "Базовый", percentage: scheme.Percentage}}
+	for _, tier := range tiers {
+		level := tierLevel{
+			label:        tier.Name,
+			from:         tier.From,
+			percentage:   levels[len(levels)-1].percentage,
+			bonusPerWash: tier.BonusPerWash,
+		}
+		if tier.Percentage > 0 {
+			level.percentage = tier.Percentage
+		}
+		if level.label == "" {
+			level.label = fmt.Sprintf("от %g", tier.From)
+		}
+		// A tier starting at zero replaces the base level
+		if tier.From <= 0 && len(levels) == 1 {
+			levels[0] = level
+			continue
+		}
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// levelAt returns the index of the level in effect for an accumulated value
+func levelAt(levels []tierLevel, value float64) int {
+	idx := 0
+	for i, level := range levels {
+		if value >= level.from {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// tieredShare calculates the percentage components and tier bonus of a wash
+// for a tiered scheme and advances the accumulator. With revenue tiers a wash
+// that crosses a threshold is split so only the part above it earns the
+// higher percentage.
+func tieredShare(
+	scheme *models.SalaryScheme,
+	event *models.WashEvent,
+	numEmployeesOnWash int,
+	slot int,
+	acc *tierAccumulator,
+	withPercentage bool,
+) (components []models.SalaryComponent, tier string) {
+	acc.advance(scheme, event)
+	levels := tierLevels(scheme)
+
+	share := washBaseAmount(scheme, event).Allocate(numEmployeesOnWash)[slot]
+
+	var endLevel int
+	if scheme.TierBasis == models.SalaryTierByWashCount {
+		endLevel = levelAt(levels, float64(acc.washes))
+		if withPercentage && share > 0 {
+			if amount := share.MulPercent(levels[endLevel].percentage, models.RoundHalfEven); amount > 0 {
+				components = append(components, models.SalaryComponent{
+					Type:   models.SalaryComponentPercentage,
+					Amount: amount,
+					Tier:   levels[endLevel].label,
+				})
+			}
+		}
+	} else {
+		start := acc.revenue
+		end := start
+		if share > 0 {
+			end = start + share
+		}
+		endLevel = levelAt(levels, end.Float64())
+
+		if withPercentage && share > 0 {
+			for i := levelAt(levels, start.Float64()); i <= endLevel; i++ {
+				segmentStart := start
+				if from := models.MoneyFromFloat(levels[i].from); from > segmentStart {
+					segmentStart = from
+				}
+				segmentEnd := end
+				if i+1 < len(levels) {
+					if next := models.MoneyFromFloat(levels[i+1].from); next < segmentEnd {
+						segmentEnd = next
+					}
+				}
+				if segmentEnd <= segmentStart {
+					continue
+				}
+				if amount := (segmentEnd - segmentStart).MulPercent(levels[i].percentage, models.RoundHalfEven); amount > 0 {
+					components = append(components, models.SalaryComponent{
+						Type:   models.SalaryComponentPercentage,
+						Amount: amount,
+						Tier:   levels[i].label,
+					})
+				}
+			}
+		}
+	}
+
+	if bonus := levels[endLevel].bonusPerWash; bonus > 0 {
+		components = append(components, models.SalaryComponent{
+			Type:   models.SalaryComponentTierBonus,
+			Amount: bonus,
+			Tier:   levels[endLevel].label,
+		})
+	}
+
+	if share > 0 {
+		acc.revenue += share
+	}
+	acc.washes++
+
+	return components, levels[endLevel].label
+}
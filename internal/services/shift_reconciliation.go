@@ -0,0 +1,57 @@
+package services
+
+import (
+	"backend-go/internal/models"
+)
+
+// CalculateShiftTotals calculates expected takings from the wash events of a shift
+func CalculateShiftTotals(events []models.WashEvent) models.ShiftTotals {
+	var totals models.ShiftTotals
+
+	for _, event := range events {
+		totals.WashCount++
+
+		switch event.PaymentMethod {
+		case models.WashPaymentCash:
+			totals.CashWashCount++
+			totals.ExpectedCash += event.TotalAmount
+		case models.WashPaymentCard:
+			totals.CardWashCount++
+			totals.ExpectedCardGross += event.TotalAmount
+			totals.AcquiringFees += event.AcquiringFee
+		case models.WashPaymentTransfer:
+			totals.TransferTotal += event.TotalAmount
+		default:
+			totals.NonCashTotal += event.TotalAmount
+		}
+	}
+
+	totals.ExpectedCardNet = totals.ExpectedCardGross - totals.AcquiringFees
+
+	return totals
+}
+
+// ReconcileShift fills totals and discrepancies of a shift being closed.
+// Cash in the drawer is expected to equal the opening float plus cash washes;
+// the counted card total is compared against the gross card takings as shown
+// by the terminal report.
+func ReconcileShift(shift *models.Shift, events []models.WashEvent, countedCash models.Money, countedCard *models.Money) {
+	totals := CalculateShiftTotals(events)
+
+	shift.WashEventIDs = make([]string, 0, len(events))
+	for _, event := range events {
+		shift.WashEventIDs = append(shift.WashEventIDs, event.ID)
+	}
+
+	shift.Totals = &totals
+	shift.CountedCash = countedCash
+	shift.CashDiscrepancy = countedCash - (shift.OpeningCash + totals.ExpectedCash)
+
+	shift.CountedCard = countedCard
+	shift.CardDiscrepancy = 0
+	if countedCard != nil {
+		shift.CardDiscrepancy = *countedCard - totals.ExpectedCardGross
+	}
+
+	shift.HasDiscrepancy = shift.CashDiscrepancy != 0 || shift.CardDiscrepancy != 0
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"sync"
+	"time"
 
 	"backend-go/internal/models"
 )
@@ -9,14 +10,16 @@ import (
 type Cache struct {
 	mu sync.RWMutex
 
-	employees         []models.Employee
-	employeesLoaded   bool
+	employees       []models.Employee
+	employeesLoaded bool
 
 	counterAgents       []models.CounterAgent
 	counterAgentsLoaded bool
+	counterAgentsDay    string
 
 	aggregators       []models.Aggregator
 	aggregatorsLoaded bool
+	aggregatorsDay    string
 
 	washEvents       []models.WashEvent
 	washEventsLoaded bool
@@ -29,6 +32,7 @@ type Cache struct {
 
 	retailPriceConfig       *models.RetailPriceConfig
 	retailPriceConfigLoaded bool
+	retailPriceConfigDay    string
 
 	inventory       *models.Inventory
 	inventoryLoaded bool
@@ -68,11 +72,18 @@ func (c *Cache) InvalidateEmployees() {
 	c.employees = nil
 }
 
+// cacheDay is the local date price lists were loaded on. The store shows
+// the price list version in effect on the day, so cached lists expire at
+// midnight when a scheduled version may start.
+func cacheDay() string {
+	return time.Now().Format("2006-01-02")
+}
+
 // Counter Agents cache
 func (c *Cache) GetCounterAgents() ([]models.CounterAgent, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.counterAgents, c.counterAgentsLoaded
+	return c.counterAgents, c.counterAgentsLoaded && c.counterAgentsDay == cacheDay()
 }
 
 func (c *Cache) SetCounterAgents(agents []models.CounterAgent) {
@@ -80,6 +91,7 @@ func (c *Cache) SetCounterAgents(agents []models.CounterAgent) {
 	defer c.mu.Unlock()
 	c.counterAgents = agents
 	c.counterAgentsLoaded = true
+	c.counterAgentsDay = cacheDay()
 }
 
 func (c *Cache) InvalidateCounterAgents() {
@@ -93,7 +105,7 @@ func (c *Cache) InvalidateCounterAgents() {
 func (c *Cache) GetAggregators() ([]models.Aggregator, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.aggregators, c.aggregatorsLoaded
+	return c.aggregators, c.aggregatorsLoaded && c.aggregatorsDay == cacheDay()
 }
 
 func (c *Cache) SetAggregators(aggregators []models.Aggregator) {
@@ -101,6 +113,7 @@ func (c *Cache) SetAggregators(aggregators []models.Aggregator) {
 	defer c.mu.Unlock()
 	c.aggregators = aggregators
 	c.aggregatorsLoaded = true
+	c.aggregatorsDay = cacheDay()
 }
 
 func (c *Cache) InvalidateAggregators() {
@@ -177,7 +190,7 @@ func (c *Cache) InvalidateSalarySchemes() {
 func (c *Cache) GetRetailPriceConfig() (*models.RetailPriceConfig, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.retailPriceConfig, c.retailPriceConfigLoaded
+	return c.retailPriceConfig, c.retailPriceConfigLoaded && c.retailPriceConfigDay == cacheDay()
 }
 
 func (c *Cache) SetRetailPriceConfig(config *models.RetailPriceConfig) {
@@ -185,6 +198,7 @@ func (c *Cache) SetRetailPriceConfig(config *models.RetailPriceConfig) {
 	defer c.mu.Unlock()
 	c.retailPriceConfig = config
 	c.retailPriceConfigLoaded = true
+	c.retailPriceConfigDay = cacheDay()
 }
 
 func (c *Cache) InvalidateRetailPriceConfig() {
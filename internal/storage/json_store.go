@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"backend-go/internal/models"
 )
@@ -83,6 +85,30 @@ func (s *JSONStore) readFromDirectory(dir string, pattern string) ([]string, err
 	return files, nil
 }
 
+// readTransactionsFile reads a transactions file written either as
+// {"transactions": [...]} or, as the Next.js backend did, as a bare array
+func (s *JSONStore) readTransactionsFile(filePath string, v interface{}) error {
+	var raw json.RawMessage
+	if err := s.readJSONFile(filePath, &raw); err != nil {
+		return err
+	}
+
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		return json.Unmarshal(raw, v)
+	}
+
+	var wrapper struct {
+		Transactions json.RawMessage `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return err
+	}
+	if len(wrapper.Transactions) == 0 {
+		return nil
+	}
+	return json.Unmarshal(wrapper.Transactions, v)
+}
+
 // ==================== EMPLOYEES ====================
 
 func (s *JSONStore) GetAllEmployees() ([]models.Employee, error) {
@@ -175,6 +201,7 @@ func (s *JSONStore) GetAllCounterAgents() ([]models.CounterAgent, error) {
 		if err := s.readJSONFile(file, &agent); err != nil {
 			continue
 		}
+		currentCounterAgentPrices(&agent, localDate())
 		agents = append(agents, agent)
 	}
 	return agents, nil
@@ -192,6 +219,7 @@ func (s *JSONStore) GetCounterAgentByID(id string) (*models.CounterAgent, error)
 			continue
 		}
 		if agent.ID == id {
+			currentCounterAgentPrices(&agent, localDate())
 			return &agent, nil
 		}
 	}
@@ -236,6 +264,7 @@ func (s *JSONStore) GetAllAggregators() ([]models.Aggregator, error) {
 		if err := s.readJSONFile(file, &agg); err != nil {
 			continue
 		}
+		currentAggregatorPrices(&agg, localDate())
 		aggregators = append(aggregators, agg)
 	}
 	return aggregators, nil
@@ -253,6 +282,7 @@ func (s *JSONStore) GetAggregatorByID(id string) (*models.Aggregator, error) {
 			continue
 		}
 		if agg.ID == id {
+			currentAggregatorPrices(&agg, localDate())
 			return &agg, nil
 		}
 	}
@@ -483,14 +513,14 @@ func (s *JSONStore) DeleteSalaryScheme(id string) error {
 func (s *JSONStore) GetEmployeeTransactions(employeeID string) ([]models.EmployeeTransaction, error) {
 	filePath := filepath.Join(s.dataPath, "employee-transactions", fmt.Sprintf("%s.json", employeeID))
 
-	var file models.EmployeeTransactionsFile
-	if err := s.readJSONFile(filePath, &file); err != nil {
+	var transactions []models.EmployeeTransaction
+	if err := s.readTransactionsFile(filePath, &transactions); err != nil {
 		if os.IsNotExist(err) {
 			return []models.EmployeeTransaction{}, nil
 		}
 		return nil, err
 	}
-	return file.Transactions, nil
+	return transactions, nil
 }
 
 func (s *JSONStore) SaveEmployeeTransactions(employeeID string, transactions []models.EmployeeTransaction) error {
@@ -504,14 +534,14 @@ func (s *JSONStore) SaveEmployeeTransactions(employeeID string, transactions []m
 func (s *JSONStore) GetClientTransactions(clientID string) ([]models.ClientTransaction, error) {
 	filePath := filepath.Join(s.dataPath, "client-transactions", fmt.Sprintf("%s.json", clientID))
 
-	var file models.ClientTransactionsFile
-	if err := s.readJSONFile(filePath, &file); err != nil {
+	var transactions []models.ClientTransaction
+	if err := s.readTransactionsFile(filePath, &transactions); err != nil {
 		if os.IsNotExist(err) {
 			return []models.ClientTransaction{}, nil
 		}
 		return nil, err
 	}
-	return file.Transactions, nil
+	return transactions, nil
 }
 
 func (s *JSONStore) SaveClientTransactions(clientID string, transactions []models.ClientTransaction) error {
@@ -535,6 +565,7 @@ func (s *JSONStore) GetRetailPriceConfig() (*models.RetailPriceConfig, error) {
 		}
 		return nil, err
 	}
+	currentRetailPrices(&config, localDate())
 	return &config, nil
 }
 
@@ -543,6 +574,38 @@ func (s *JSONStore) SaveRetailPriceConfig(config *models.RetailPriceConfig) erro
 	return s.writeJSONFile(filePath, config)
 }
 
+// ==================== PRICE LIST VERSIONS ====================
+
+// Price lists are read as the version in effect on the day, so a scheduled
+// version applies from its first day without being written back. The next
+// save of the list stores it.
+
+// localDate returns today's date in the server's time zone
+func localDate() string {
+	return time.Now().In(time.Local).Format("2006-01-02")
+}
+
+func currentRetailPrices(config *models.RetailPriceConfig, today string) {
+	if v, due := models.PriceListVersionDue(config.Versions, config.MainPriceList, config.AdditionalPriceList, today); due {
+		config.MainPriceList, config.AdditionalPriceList = v.Main, v.Additional
+	}
+}
+
+func currentCounterAgentPrices(agent *models.CounterAgent, today string) {
+	if v, due := models.PriceListVersionDue(agent.PriceListVersions, agent.PriceList, agent.AdditionalPriceList, today); due {
+		agent.PriceList, agent.AdditionalPriceList = v.Main, v.Additional
+	}
+}
+
+func currentAggregatorPrices(agg *models.Aggregator, today string) {
+	for i := range agg.PriceLists {
+		list := &agg.PriceLists[i]
+		if v, due := models.PriceListVersionDue(list.Versions, list.Services, nil, today); due {
+			list.Services = v.Main
+		}
+	}
+}
+
 // ==================== INVENTORY ====================
 
 func (s *JSONStore) GetInventory() (*models.Inventory, error) {
@@ -562,3 +625,391 @@ func (s *JSONStore) SaveInventory(inv *models.Inventory) error {
 	filePath := filepath.Join(s.dataPath, "inventory.json")
 	return s.writeJSONFile(filePath, inv)
 }
+
+// ==================== LOAN SETTINGS ====================
+
+func (s *JSONStore) GetLoanSettings() (*models.LoanSettings, error) {
+	filePath := filepath.Join(s.dataPath, "loan-settings.json")
+
+	var settings models.LoanSettings
+	if err := s.readJSONFile(filePath, &settings); err != nil {
+		if os.IsNotExist(err) {
+			return &models.LoanSettings{}, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s *JSONStore) SaveLoanSettings(settings *models.LoanSettings) error {
+	filePath := filepath.Join(s.dataPath, "loan-settings.json")
+	return s.writeJSONFile(filePath, settings)
+}
+
+// ==================== COMPANY DETAILS ====================
+
+// GetCompanyDetails returns the requisites of the car wash itself
+func (s *JSONStore) GetCompanyDetails() (*models.CounterAgentCompany, error) {
+	filePath := filepath.Join(s.dataPath, "company-details.json")
+
+	var details models.CounterAgentCompany
+	if err := s.readJSONFile(filePath, &details); err != nil {
+		if os.IsNotExist(err) {
+			return &models.CounterAgentCompany{}, nil
+		}
+		return nil, err
+	}
+	return &details, nil
+}
+
+func (s *JSONStore) SaveCompanyDetails(details *models.CounterAgentCompany) error {
+	filePath := filepath.Join(s.dataPath, "company-details.json")
+	return s.writeJSONFile(filePath, details)
+}
+
+// ==================== SHIFTS ====================
+
+func (s *JSONStore) GetAllShifts() ([]models.Shift, error) {
+	files, err := s.readFromDirectory("shifts", "shift_")
+	if err != nil {
+		return nil, err
+	}
+
+	var shifts []models.Shift
+	for _, file := range files {
+		var shift models.Shift
+		if err := s.readJSONFile(file, &shift); err != nil {
+			continue
+		}
+		shifts = append(shifts, shift)
+	}
+
+	// Sort by opening time descending
+	sort.Slice(shifts, func(i, j int) bool {
+		return shifts[i].OpenedAt > shifts[j].OpenedAt
+	})
+
+	return shifts, nil
+}
+
+func (s *JSONStore) GetShiftByID(id string) (*models.Shift, error) {
+	shifts, err := s.GetAllShifts()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shift := range shifts {
+		if shift.ID == id {
+			return &shift, nil
+		}
+	}
+	return nil, fmt.Errorf("shift not found: %s", id)
+}
+
+// GetOpenShift returns the currently open shift, if any
+func (s *JSONStore) GetOpenShift() (*models.Shift, error) {
+	shifts, err := s.GetAllShifts()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, shift := range shifts {
+		if shift.Status == models.ShiftOpen {
+			return &shift, nil
+		}
+	}
+	return nil, fmt.Errorf("no open shift")
+}
+
+func (s *JSONStore) SaveShift(shift *models.Shift) error {
+	filename := fmt.Sprintf("%s.json", shift.ID)
+	filePath := filepath.Join(s.dataPath, "shifts", filename)
+	return s.writeJSONFile(filePath, shift)
+}
+
+// ==================== INVOICES ====================
+
+func (s *JSONStore) GetAllInvoices() ([]models.Invoice, error) {
+	files, err := s.readFromDirectory("invoices", "inv_")
+	if err != nil {
+		return nil, err
+	}
+
+	var invoices []models.Invoice
+	for _, file := range files {
+		var invoice models.Invoice
+		if err := s.readJSONFile(file, &invoice); err != nil {
+			continue
+		}
+		invoices = append(invoices, invoice)
+	}
+
+	// Sort by date descending
+	sort.Slice(invoices, func(i, j int) bool {
+		if invoices[i].Date != invoices[j].Date {
+			return invoices[i].Date > invoices[j].Date
+		}
+		return invoices[i].CreatedAt > invoices[j].CreatedAt
+	})
+
+	return invoices, nil
+}
+
+func (s *JSONStore) GetInvoiceByID(id string) (*models.Invoice, error) {
+	filePath := filepath.Join(s.dataPath, "invoices", fmt.Sprintf("%s.json", id))
+
+	var invoice models.Invoice
+	if err := s.readJSONFile(filePath, &invoice); err != nil {
+		return nil, fmt.Errorf("invoice not found: %s", id)
+	}
+	return &invoice, nil
+}
+
+func (s *JSONStore) SaveInvoice(invoice *models.Invoice) error {
+	filename := fmt.Sprintf("%s.json", invoice.ID)
+	filePath := filepath.Join(s.dataPath, "invoices", filename)
+	return s.writeJSONFile(filePath, invoice)
+}
+
+func (s *JSONStore) DeleteInvoice(id string) error {
+	filePath := filepath.Join(s.dataPath, "invoices", fmt.Sprintf("%s.json", id))
+	if err := s.deleteFile(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("invoice not found: %s", id)
+		}
+		return err
+	}
+	return nil
+}
+
+// ==================== BANK STATEMENT IMPORTS ====================
+
+func (s *JSONStore) GetAllBankStatementImports() ([]models.BankStatementImport, error) {
+	files, err := s.readFromDirectory("bank-imports", "bimp_")
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []models.BankStatementImport
+	for _, file := range files {
+		var imp models.BankStatementImport
+		if err := s.readJSONFile(file, &imp); err != nil {
+			continue
+		}
+		imports = append(imports, imp)
+	}
+
+	// Sort by import time descending
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].ImportedAt > imports[j].ImportedAt
+	})
+
+	return imports, nil
+}
+
+func (s *JSONStore) GetBankStatementImportByID(id string) (*models.BankStatementImport, error) {
+	filePath := filepath.Join(s.dataPath, "bank-imports", fmt.Sprintf("%s.json", id))
+
+	var imp models.BankStatementImport
+	if err := s.readJSONFile(filePath, &imp); err != nil {
+		return nil, fmt.Errorf("bank statement import not found: %s", id)
+	}
+	return &imp, nil
+}
+
+func (s *JSONStore) SaveBankStatementImport(imp *models.BankStatementImport) error {
+	filename := fmt.Sprintf("%s.json", imp.ID)
+	filePath := filepath.Join(s.dataPath, "bank-imports", filename)
+	return s.writeJSONFile(filePath, imp)
+}
+
+// ==================== AGGREGATOR REGISTERS ====================
+
+func (s *JSONStore) GetAllAggregatorRegisters() ([]models.AggregatorRegister, error) {
+	files, err := s.readFromDirectory("aggregator-registers", "areg_")
+	if err != nil {
+		return nil, err
+	}
+
+	var registers []models.AggregatorRegister
+	for _, file := range files {
+		var register models.AggregatorRegister
+		if err := s.readJSONFile(file, &register); err != nil {
+			continue
+		}
+		registers = append(registers, register)
+	}
+
+	// Sort by import time descending
+	sort.Slice(registers, func(i, j int) bool {
+		return registers[i].ImportedAt > registers[j].ImportedAt
+	})
+
+	return registers, nil
+}
+
+func (s *JSONStore) GetAggregatorRegisterByID(id string) (*models.AggregatorRegister, error) {
+	filePath := filepath.Join(s.dataPath, "aggregator-registers", fmt.Sprintf("%s.json", id))
+
+	var register models.AggregatorRegister
+	if err := s.readJSONFile(filePath, &register); err != nil {
+		return nil, fmt.Errorf("aggregator register not found: %s", id)
+	}
+	return &register, nil
+}
+
+func (s *JSONStore) SaveAggregatorRegister(register *models.AggregatorRegister) error {
+	filename := fmt.Sprintf("%s.json", register.ID)
+	filePath := filepath.Join(s.dataPath, "aggregator-registers", filename)
+	return s.writeJSONFile(filePath, register)
+}
+
+// ==================== PRICE LIST IMPORTS ====================
+
+func (s *JSONStore) GetAllPriceListImports() ([]models.PriceListImport, error) {
+	files, err := s.readFromDirectory("price-list-imports", "plimp_")
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []models.PriceListImport
+	for _, file := range files {
+		var imp models.PriceListImport
+		if err := s.readJSONFile(file, &imp); err != nil {
+			continue
+		}
+		imports = append(imports, imp)
+	}
+
+	// Sort by import time descending
+	sort.Slice(imports, func(i, j int) bool {
+		return imports[i].ImportedAt > imports[j].ImportedAt
+	})
+
+	return imports, nil
+}
+
+func (s *JSONStore) GetPriceListImportByID(id string) (*models.PriceListImport, error) {
+	filePath := filepath.Join(s.dataPath, "price-list-imports", fmt.Sprintf("%s.json", id))
+
+	var imp models.PriceListImport
+	if err := s.readJSONFile(filePath, &imp); err != nil {
+		return nil, fmt.Errorf("price list import not found: %s", id)
+	}
+	return &imp, nil
+}
+
+func (s *JSONStore) SavePriceListImport(imp *models.PriceListImport) error {
+	filename := fmt.Sprintf("%s.json", imp.ID)
+	filePath := filepath.Join(s.dataPath, "price-list-imports", filename)
+	return s.writeJSONFile(filePath, imp)
+}
+
+// ==================== PAYROLL PERIODS ====================
+
+func (s *JSONStore) GetAllPayrollPeriods() ([]models.PayrollPeriod, error) {
+	files, err := s.readFromDirectory("payroll-periods", "payroll_")
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []models.PayrollPeriod
+	for _, file := range files {
+		var period models.PayrollPeriod
+		if err := s.readJSONFile(file, &period); err != nil {
+			continue
+		}
+		periods = append(periods, period)
+	}
+
+	// Sort by start date descending
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].From > periods[j].From
+	})
+
+	return periods, nil
+}
+
+func (s *JSONStore) GetPayrollPeriodByID(id string) (*models.PayrollPeriod, error) {
+	periods, err := s.GetAllPayrollPeriods()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, period := range periods {
+		if period.ID == id {
+			return &period, nil
+		}
+	}
+	return nil, fmt.Errorf("payroll period not found: %s", id)
+}
+
+func (s *JSONStore) SavePayrollPeriod(period *models.PayrollPeriod) error {
+	filename := fmt.Sprintf("%s.json", period.ID)
+	filePath := filepath.Join(s.dataPath, "payroll-periods", filename)
+	return s.writeJSONFile(filePath, period)
+}
+
+func (s *JSONStore) DeletePayrollPeriod(id string) error {
+	filePath := filepath.Join(s.dataPath, "payroll-periods", fmt.Sprintf("%s.json", id))
+	if err := s.deleteFile(filePath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("payroll period not found: %s", id)
+		}
+		return err
+	}
+	return nil
+}
+
+// ==================== AUDIT LOG ====================
+
+func (s *JSONStore) GetAuditLog() ([]models.AuditLogEntry, error) {
+	filePath := filepath.Join(s.dataPath, "audit-log.json")
+
+	var file models.AuditLogFile
+	if err := s.readJSONFile(filePath, &file); err != nil {
+		if os.IsNotExist(err) {
+			return []models.AuditLogEntry{}, nil
+		}
+		return nil, err
+	}
+	return file.Entries, nil
+}
+
+func (s *JSONStore) AppendAuditLogEntry(entry models.AuditLogEntry) error {
+	entries, err := s.GetAuditLog()
+	if err != nil {
+		return err
+	}
+
+	filePath := filepath.Join(s.dataPath, "audit-log.json")
+	file := models.AuditLogFile{Entries: append(entries, entry)}
+	return s.writeJSONFile(filePath, file)
+}
+
+// ==================== SESSION SECRET ====================
+
+// GetSessionSecret returns the key session cookies are signed with. The key
+// is generated on first use and kept with the data so sessions survive
+// restarts.
+func (s *JSONStore) GetSessionSecret() ([]byte, error) {
+	filePath := filepath.Join(s.dataPath, "session-secret.json")
+
+	var file models.SessionSecretFile
+	err := s.readJSONFile(filePath, &file)
+	if err == nil && len(file.Key) > 0 {
+		return file.Key, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	file.Key = make([]byte, 32)
+	if _, err := rand.Read(file.Key); err != nil {
+		return nil, err
+	}
+	if err := s.writeJSONFile(filePath, file); err != nil {
+		return nil, err
+	}
+	return file.Key, nil
+}
@@ -62,8 +62,8 @@ export function CounterAgentForm({ initialData, agentId }: CounterAgentFormProps
         name: initialData.name || "",
         companies: initialData.companies && initialData.companies.length > 0 ? initialData.companies.map(c => ({ ...baseCompany, ...c })) : [],
         cars: initialData.cars.map(c => c.licensePlate).join('\n'),
-        priceList: initialData.priceList?.map(p => ({ serviceName: p.serviceName || "", price: p.price ?? 0, chemicalConsumption: p.chemicalConsumption ?? 0 })) || [],
-        additionalPriceList: initialData.additionalPriceList?.map(p => ({ serviceName: p.serviceName || "", price: p.price ?? 0, chemicalConsumption: p.chemicalConsumption ?? 0 })) || [],
+        priceList: initialData.priceList?.map(p => ({ serviceName: p.serviceName || "", price: p.price ?? 0, chemicalConsumption: p.chemicalConsumption ?? 0, serviceId: p.serviceId })) || [],
+        additionalPriceList: initialData.additionalPriceList?.map(p => ({ serviceName: p.serviceName || "", price: p.price ?? 0, chemicalConsumption: p.chemicalConsumption ?? 0, serviceId: p.serviceId })) || [],
         allowCustomServices: initialData.allowCustomServices ?? true,
         balance: initialData.balance ?? 0,
       };
@@ -13,6 +13,7 @@ export const priceListItemSchema = z.object({
   serviceName: z.string().min(1, "Название услуги не может быть пустым."),
   price: z.coerce.number().min(0, "Цена должна быть положительным числом или нулем."),
   chemicalConsumption: z.coerce.number().min(0, "Расход должен быть положительным числом или нулем.").optional(),
+  serviceId: z.string().optional(),
 });
 
 interface PriceListEditorProps<T> {
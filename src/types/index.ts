@@ -58,6 +58,7 @@ export interface PriceListItem {
   isCustom?: boolean;
   chemicalConsumption?: number; // Norma per service, in grams
   employeeConsumptions?: EmployeeConsumption[]; // Actual consumption per employee
+  serviceId?: string; // Stable ID of the service in its price list, kept across renames
 }
 
 export interface RetailPriceConfig {